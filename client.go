@@ -1,13 +1,16 @@
 package gateway
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/discordpkg/gateway/encoding"
 	"io"
 	"runtime"
+	"time"
 
 	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/event/opcode"
 	"github.com/discordpkg/gateway/intent"
 	"github.com/discordpkg/gateway/internal/util"
 )
@@ -16,11 +19,69 @@ var ErrOutOfSync = errors.New("sequence number was out of sync")
 var ErrNotConnectedYet = errors.New("client is not in a connected state")
 
 func NewClient(options ...Option) (*Client, error) {
+	client, err := configure(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// the shard information must be known before resuming a snapshot
+	if client.snapshot != nil && client.ctx.state == nil {
+		client.resumeSnapshot(client.snapshot)
+	}
+
+	if client.ctx.state == nil {
+		client.ctx.SetState(&HelloState{
+			ctx: client.ctx,
+			Identity: &Identify{
+				BotToken:       client.botToken,
+				Properties:     &client.connectionProperties,
+				Compress:       false,
+				LargeThreshold: 0,
+				Shard:          [2]int{int(client.id), client.totalNumberOfShards},
+				Presence:       nil,
+				Intents:        client.intents,
+			},
+		})
+	}
+	return client, nil
+}
+
+// Settings holds the configuration of a client that is needed before connecting, such as the codec which decides the
+// encoding of the dial url.
+type Settings struct {
+	ShardID    ShardID
+	ShardCount int
+	Codec      encoding.Codec
+	Metrics    Metrics
+}
+
+// ReadSettings applies the options without creating a client, such that nothing is reported to the metrics. The
+// options are validated as by NewClient.
+func ReadSettings(options ...Option) (*Settings, error) {
+	client, err := configure(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Settings{
+		ShardID:    client.id,
+		ShardCount: client.totalNumberOfShards,
+		Codec:      client.codec,
+		Metrics:    client.metrics,
+	}, nil
+}
+
+// configure applies and validates the options, the client has no state until NewClient sets it
+func configure(options []Option) (*Client, error) {
 	client := &Client{
 		allowlist: util.Set[event.Type]{},
 		logger:    &nopLogger{},
+		metrics:   &nopMetrics{},
+		codec:     encoding.JSONCodec{},
+
+		reorderWindow: defaultReorderWindow,
 	}
-	client.ctx = &StateCtx{client: client}
+	client.ctx = &StateCtx{client: client, logger: client.logger}
 
 	for i := range options {
 		if err := options[i](client); err != nil {
@@ -66,21 +127,6 @@ func NewClient(options ...Option) (*Client, error) {
 	if int(client.id) > client.totalNumberOfShards {
 		return nil, errors.New("shard id is higher than shard count")
 	}
-
-	if client.ctx.state == nil {
-		client.ctx.SetState(&HelloState{
-			ctx: client.ctx,
-			Identity: &Identify{
-				BotToken:       client.botToken,
-				Properties:     &client.connectionProperties,
-				Compress:       false,
-				LargeThreshold: 0,
-				Shard:          [2]int{int(client.id), client.totalNumberOfShards},
-				Presence:       nil,
-				Intents:        client.intents,
-			},
-		})
-	}
 	return client, nil
 }
 
@@ -95,15 +141,32 @@ type Client struct {
 	intents              intent.Type
 
 	allowlist    util.Set[event.Type]
-	eventHandler Handler
+	eventHandler HandlerV2
+	tracer       Tracer
+
+	recoverPanics bool
+	panicHandler  PanicHandler
+
+	guildsReadyEnabled bool
+	guildsReadyTimeout time.Duration
+
+	// reordered buffers the events that arrived ahead of the expected sequence number, see SequenceReorder
+	sequencePolicy SequencePolicy
+	reorderWindow  int
+	reordered      map[int64]*Payload
 
 	commandRateLimiter  RateLimiter
 	identifyRateLimiter RateLimiter
 
 	heartbeatHandler HeartbeatHandler
 
-	ctx    *StateCtx
-	logger Logger
+	// snapshot is resumed once every option is applied, see WithSessionSnapshot
+	snapshot *Session
+
+	ctx     *StateCtx
+	logger  Logger
+	metrics Metrics
+	codec   encoding.Codec
 }
 
 func (c *Client) String() string {
@@ -127,6 +190,45 @@ func (c *Client) ResumeURL() string {
 	return ""
 }
 
+// ShardID returns the id of the shard, see WithShardInfo.
+func (c *Client) ShardID() ShardID {
+	return c.id
+}
+
+// ShardCount returns the total number of shards, see WithShardInfo.
+func (c *Client) ShardCount() int {
+	return c.totalNumberOfShards
+}
+
+// Metrics returns the metrics the client reports to, see WithMetrics.
+func (c *Client) Metrics() Metrics {
+	return c.metrics
+}
+
+// Latency returns the round trip time of the last acknowledged heartbeat, or 0 if no heartbeat has been
+// acknowledged yet.
+func (c *Client) Latency() time.Duration {
+	return time.Duration(c.ctx.latency.Load())
+}
+
+// AverageLatency returns the moving average of the heartbeat round trip times, where recent heartbeats weigh more.
+// Returns 0 if no heartbeat has been acknowledged yet.
+func (c *Client) AverageLatency() time.Duration {
+	return time.Duration(c.ctx.averageLatency.Load())
+}
+
+// Ready returns the Ready payload of the current session, which holds the user of the bot, the application and the
+// guilds at the time of connecting. Resumed sessions keep the payload of the client given to WithExistingSession.
+// Returns nil until the Ready event is received, and for sessions resumed from a snapshot.
+func (c *Client) Ready() *Ready {
+	return c.ctx.ready.Load()
+}
+
+// Codec returns the codec used to encode and decode payloads, see WithCodec.
+func (c *Client) Codec() encoding.Codec {
+	return c.codec
+}
+
 func (c *Client) Close(closeWriter io.Writer) error {
 	return c.ctx.Close(closeWriter)
 }
@@ -138,41 +240,45 @@ func (c *Client) read(client io.Reader) (*Payload, int, error) {
 	}
 
 	packet := &Payload{}
-	if err = encoding.Unmarshal(data, packet); err != nil {
+	if err = c.codec.Unmarshal(data, packet); err != nil {
 		return nil, 0, fmt.Errorf("failed to unmarshal packet. %w", err)
 	}
 
 	return packet, len(data), nil
 }
 
-func (c *Client) process(payload *Payload, pipe io.Writer) (err error) {
-	// we consider 0 to be either the first message or messages without sequence numbers such as heartbeat ack
-	if c.ctx.sequenceNumber.Load() == 0 || c.ctx.sequenceNumber.CompareAndSwap(payload.Seq-1, payload.Seq) {
-		return c.ctx.Process(payload, pipe)
-	} else if c.ctx.sequenceNumber.Load() >= payload.Seq {
-		// already handled
-		return nil
-	}
-
-	c.ctx.SetState(&ClosedState{})
-	return ErrOutOfSync
-}
-
 // ProcessNext processes the next Discord message and update state accordingly. On error, you are expected to call
 // Client.Close to notify Discord about any issues accumulated in the Client.
 func (c *Client) ProcessNext(reader io.Reader, writer io.Writer) (*Payload, error) {
-	payload, _, err := c.read(reader)
+	return c.ProcessNextContext(context.Background(), reader, writer)
+}
+
+// ProcessNextContext is the same as ProcessNext, where the context is passed on to the event handler, see HandlerV2.
+func (c *Client) ProcessNextContext(ctx context.Context, reader io.Reader, writer io.Writer) (*Payload, error) {
+	receivedAt := time.Now()
+	payload, size, err := c.read(reader)
 	if err != nil {
 		c.ctx.SetState(&ClosedState{})
 		return nil, err
 	}
 
+	c.metrics.BytesRead(c.id, size)
+	if payload.Op == opcode.Dispatch && payload.CloseCode == 0 {
+		c.metrics.EventReceived(c.id, payload.EventName)
+	}
+
 	c.logger.Debug("processing payload: %s", payload)
+
+	// the dispatch context only lives while the payload is processed
+	c.ctx.dispatchCtx, c.ctx.receivedAt = ctx, receivedAt
+	defer func() {
+		c.ctx.dispatchCtx = nil
+	}()
 	return payload, c.process(payload, writer)
 }
 
 func (c *Client) Write(pipe io.Writer, evt event.Type, payload encoding.RawMessage) error {
-	if _, ok := c.ctx.state.(*ConnectedState); !ok {
+	if !c.ctx.connected.Load() {
 		return ErrNotConnectedYet
 	}
 
@@ -2,9 +2,15 @@ package gateway
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/discordpkg/gateway/closecode"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/event/opcode"
 	"strings"
 	"testing"
 	"time"
@@ -126,3 +132,272 @@ func TestCloseFrameTransitions(t *testing.T) {
 		}
 	})
 }
+
+func TestStateCtx_WriteHeartbeat(t *testing.T) {
+	for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			client := NewClientMust(t, append(commonOptions, WithCodec(codec))...)
+
+			buffer := &bytes.Buffer{}
+			if err := client.ctx.WriteHeartbeat(buffer, 1234567); err != nil {
+				t.Fatal(err)
+			}
+
+			var payload Payload
+			if err := codec.Unmarshal(buffer.Bytes(), &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.Op != opcode.Heartbeat {
+				t.Errorf("incorrect op code. Got %d", payload.Op)
+			}
+
+			var seq int64
+			if err := codec.Unmarshal(payload.Data, &seq); err != nil {
+				t.Fatal(err)
+			}
+			if seq != 1234567 {
+				t.Errorf("incorrect sequence number. Got %d", seq)
+			}
+		})
+	}
+}
+
+func TestClient_Latency(t *testing.T) {
+	client := NewClientMust(t, commonOptions...)
+	client.ctx.SetState(&ConnectedState{client.ctx})
+
+	if client.Latency() != 0 || client.AverageLatency() != 0 {
+		t.Fatal("expected no latency before the first heartbeat")
+	}
+
+	heartbeat := func(delay time.Duration) {
+		buffer := &bytes.Buffer{}
+		if err := client.ctx.WriteHeartbeat(buffer, 0); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(delay)
+		if _, err := client.ProcessNext(strings.NewReader(`{"op":11}`), buffer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	heartbeat(20 * time.Millisecond)
+	first := client.Latency()
+	if first < 20*time.Millisecond {
+		t.Errorf("expected a latency of at least 20ms, got %s", first)
+	}
+	if client.AverageLatency() != first {
+		t.Errorf("expected the first sample to be the average, got %s", client.AverageLatency())
+	}
+
+	// acknowledgements without a heartbeat are ignored
+	if _, err := client.ProcessNext(strings.NewReader(`{"op":11}`), &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if client.Latency() != first {
+		t.Errorf("latency should not change without a heartbeat, got %s", client.Latency())
+	}
+
+	heartbeat(0)
+	if client.Latency() >= first {
+		t.Errorf("expected a lower latency, got %s", client.Latency())
+	}
+	if average := client.AverageLatency(); average >= first || average <= client.Latency() {
+		t.Errorf("expected the average to be between the samples, got %s", average)
+	}
+}
+
+type errorLogger struct {
+	nopLogger
+	errors []string
+}
+
+func (l *errorLogger) Error(format string, args ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+type failureMetrics struct {
+	nopMetrics
+	failed []event.Type
+}
+
+func (m *failureMetrics) HandlerFailed(_ ShardID, evt event.Type) {
+	m.failed = append(m.failed, evt)
+}
+
+func TestClient_ProcessNextContext(t *testing.T) {
+	type key struct{}
+	logger, metrics := &errorLogger{}, &failureMetrics{}
+
+	var received []*Event
+	client := NewClientMust(t, append(commonOptions,
+		WithShardInfo(1, 2),
+		WithLogger(logger),
+		WithMetrics(metrics),
+		WithGuildEvents(event.MessageCreate),
+		WithEventHandlerV2(func(ctx context.Context, evt *Event) error {
+			if ctx.Value(key{}) != "value" {
+				t.Error("expected the context to be derived from the process context")
+			}
+			received = append(received, evt)
+			return errors.New("handler failed")
+		}),
+	)...)
+	client.ctx.SetState(&ConnectedState{client.ctx})
+
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	before := time.Now()
+	data := `{"op":0,"s":1,"t":"MESSAGE_CREATE","d":{"content":"hello"}}`
+	if _, err := client.ProcessNextContext(ctx, strings.NewReader(data), &bytes.Buffer{}); err != nil {
+		t.Fatalf("handler errors should not affect the client, got %v", err)
+	}
+
+	if len(received) != 1 {
+		t.Fatalf("expected one event, got %d", len(received))
+	}
+	evt := received[0]
+	if evt.ShardID != 1 || evt.Type != event.MessageCreate || evt.Seq != 1 || string(evt.Data) != `{"content":"hello"}` {
+		t.Errorf("incorrect event. Got %+v", evt)
+	}
+	if evt.ReceivedAt.Before(before) || evt.ReceivedAt.After(time.Now()) {
+		t.Errorf("incorrect receive time. Got %s", evt.ReceivedAt)
+	}
+
+	if len(logger.errors) != 1 || !strings.Contains(logger.errors[0], "handler failed") {
+		t.Errorf("expected the handler error to be logged. Got %v", logger.errors)
+	}
+	if len(metrics.failed) != 1 || metrics.failed[0] != event.MessageCreate {
+		t.Errorf("expected the handler error to be reported. Got %v", metrics.failed)
+	}
+}
+
+func TestClient_PanicRecovery(t *testing.T) {
+	newClient := func(options ...Option) *Client {
+		client := NewClientMust(t, append(append(commonOptions,
+			WithGuildEvents(event.MessageCreate),
+			WithEventHandler(func(_ ShardID, _ event.Type, _ encoding.RawMessage) {
+				panic("oops")
+			}),
+		), options...)...)
+		client.ctx.SetState(&ConnectedState{client.ctx})
+		return client
+	}
+	data := `{"op":0,"s":7,"t":"MESSAGE_CREATE","d":{}}`
+
+	t.Run("recovered", func(t *testing.T) {
+		logger, metrics := &errorLogger{}, &failureMetrics{}
+		var recovered []interface{}
+		client := newClient(WithLogger(logger), WithMetrics(metrics), WithPanicRecovery(func(evt *Event, value interface{}, stack []byte) {
+			if evt.Seq != 7 || len(stack) == 0 {
+				t.Errorf("incorrect panic details. Got event %+v", evt)
+			}
+			recovered = append(recovered, value)
+		}))
+
+		if _, err := client.ProcessNext(strings.NewReader(data), &bytes.Buffer{}); err != nil {
+			t.Fatalf("recovered panics should not affect the client, got %v", err)
+		}
+		if _, ok := client.ctx.state.(*ConnectedState); !ok || client.ctx.sequenceNumber.Load() != 7 {
+			t.Errorf("expected the client to stay connected. Got state %s", client.ctx.state)
+		}
+		if len(recovered) != 1 || recovered[0] != "oops" {
+			t.Errorf("expected the panic handler to be called. Got %v", recovered)
+		}
+		if len(logger.errors) != 1 || !strings.Contains(logger.errors[0], "MESSAGE_CREATE event with sequence number 7: oops") {
+			t.Errorf("expected the panic to be logged. Got %v", logger.errors)
+		}
+		if len(metrics.failed) != 1 {
+			t.Errorf("expected the panic to be reported. Got %v", metrics.failed)
+		}
+	})
+
+	t.Run("not recovered", func(t *testing.T) {
+		client := newClient()
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to continue")
+			}
+			if !client.ctx.closed.Load() {
+				t.Error("expected the client to be closed")
+			}
+		}()
+		_, _ = client.ProcessNext(strings.NewReader(data), &bytes.Buffer{})
+	})
+}
+
+func TestClient_Session(t *testing.T) {
+	options := append(commonOptions, WithShardInfo(1, 2))
+	client := NewClientMust(t, options...)
+	if _, err := client.Session(); !errors.Is(err, ErrSessionNotResumable) {
+		t.Errorf("expected a new client to not be resumable, got %v", err)
+	}
+
+	client.ctx.SessionID, client.ctx.ResumeGatewayURL = "session", "wss://resume.discord.gg"
+	client.ctx.sequenceNumber.Store(42)
+	client.ctx.SetState(&ConnectedState{client.ctx})
+	if err := client.Close(&bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := client.Session()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot *Session
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	wants := Session{ID: "session", ResumeGatewayURL: "wss://resume.discord.gg", Sequence: 42, ShardID: 1, ShardCount: 2}
+	if *snapshot != wants {
+		t.Errorf("incorrect snapshot. Got %+v, wants %+v", *snapshot, wants)
+	}
+
+	t.Run("resume", func(t *testing.T) {
+		// the shard info is applied after the snapshot option
+		resumed := NewClientMust(t, append(commonOptions, WithSessionSnapshot(snapshot), WithShardInfo(1, 2))...)
+
+		buffer := &bytes.Buffer{}
+		hello := `{"op":10,"d":{"heartbeat_interval":45000}}`
+		if _, err := resumed.ProcessNext(strings.NewReader(hello), buffer); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buffer.String(), `"session_id":"session","seq":42`) {
+			t.Errorf("expected a resume command, got %s", buffer.String())
+		}
+	})
+
+	t.Run("different shard", func(t *testing.T) {
+		identified := NewClientMust(t, append(commonOptions, WithSessionSnapshot(snapshot), WithShardInfo(1, 4))...)
+		if _, ok := identified.ctx.state.(*HelloState); !ok {
+			t.Errorf("expected the snapshot of a different shard to be ignored. Got state %s", identified.ctx.state)
+		}
+	})
+
+	t.Run("incomplete", func(t *testing.T) {
+		if _, err := NewClient(append(options, WithSessionSnapshot(&Session{ID: "session"}))...); err == nil {
+			t.Error("expected an incomplete snapshot to be rejected")
+		}
+	})
+}
+
+func TestReadSettings(t *testing.T) {
+	metrics := &recordedMetrics{}
+	settings, err := ReadSettings(append(commonOptions, WithShardInfo(1, 2), WithMetrics(metrics))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.ShardID != 1 || settings.ShardCount != 2 || settings.Codec.Name() != "json" || settings.Metrics != metrics {
+		t.Errorf("incorrect settings. Got %+v", settings)
+	}
+	if len(metrics.states) != 0 {
+		t.Errorf("expected no state changes to be reported, got %v", metrics.states)
+	}
+
+	if _, err = ReadSettings(WithBotToken("token")); err == nil {
+		t.Error("expected the options to be validated")
+	}
+}
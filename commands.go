@@ -0,0 +1,259 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/intent"
+)
+
+// ErrInvalidCommand is returned when a send command would be rejected by Discord, which would otherwise close the
+// connection with a DecodeError.
+var ErrInvalidCommand = errors.New("invalid gateway command")
+
+func invalidCommand(evt event.Type, reason string) error {
+	return fmt.Errorf("%w: %s %s", ErrInvalidCommand, evt, reason)
+}
+
+// RequestGuildMembers requests the members of a guild, which are sent back as one or more GUILD_MEMBERS_CHUNK events.
+// Either a Query or UserIDs must be specified. An empty Query combined with a zero Limit requests every member of the
+// guild.
+//
+// See https://discord.com/developers/docs/topics/gateway-events#request-guild-members
+type RequestGuildMembers struct {
+	GuildID   event.Snowflake
+	Query     string
+	Limit     int
+	Presences bool
+	UserIDs   []event.Snowflake
+	Nonce     string
+}
+
+// MaxRequestGuildMembersLimit is the maximum number of members or user ids in a single request
+const MaxRequestGuildMembersLimit = 100
+
+// MaxNonceLength is the maximum number of bytes in a request guild members nonce
+const MaxNonceLength = 32
+
+func (r *RequestGuildMembers) Validate() error {
+	switch {
+	case r.GuildID == "":
+		return invalidCommand(event.RequestGuildMembers, "is missing a guild id")
+	case r.Query != "" && len(r.UserIDs) > 0:
+		return invalidCommand(event.RequestGuildMembers, "can not specify both a query and user ids")
+	case len(r.UserIDs) > MaxRequestGuildMembersLimit:
+		return invalidCommand(event.RequestGuildMembers, fmt.Sprintf("can not request more than %d user ids", MaxRequestGuildMembersLimit))
+	case r.Limit < 0 || r.Limit > MaxRequestGuildMembersLimit:
+		return invalidCommand(event.RequestGuildMembers, fmt.Sprintf("limit must be between 0 and %d", MaxRequestGuildMembersLimit))
+	case r.Query != "" && r.Limit == 0:
+		return invalidCommand(event.RequestGuildMembers, "limit must be above 0 when specifying a query")
+	case len(r.Nonce) > MaxNonceLength:
+		return invalidCommand(event.RequestGuildMembers, fmt.Sprintf("nonce can not be longer than %d bytes", MaxNonceLength))
+	}
+	return nil
+}
+
+// requiredIntents returns the intents Discord requires for the request
+func (r *RequestGuildMembers) requiredIntents() intent.Type {
+	var intents intent.Type
+	if r.Presences {
+		intents |= intent.GuildPresences
+	}
+	if r.Query == "" && len(r.UserIDs) == 0 {
+		intents |= intent.GuildMembers
+	}
+	return intents
+}
+
+// payload returns the wire format, where query and limit are only sent when user ids are not
+func (r *RequestGuildMembers) payload() interface{} {
+	type request struct {
+		GuildID   event.Snowflake   `json:"guild_id"`
+		Query     *string           `json:"query,omitempty"`
+		Limit     *int              `json:"limit,omitempty"`
+		Presences bool              `json:"presences,omitempty"`
+		UserIDs   []event.Snowflake `json:"user_ids,omitempty"`
+		Nonce     string            `json:"nonce,omitempty"`
+	}
+
+	p := &request{
+		GuildID:   r.GuildID,
+		Presences: r.Presences,
+		UserIDs:   r.UserIDs,
+		Nonce:     r.Nonce,
+	}
+	if len(r.UserIDs) == 0 {
+		query, limit := r.Query, r.Limit
+		p.Query, p.Limit = &query, &limit
+	}
+	return p
+}
+
+// StatusType is the status of a presence
+type StatusType string
+
+const (
+	StatusOnline    StatusType = "online"
+	StatusDND       StatusType = "dnd"
+	StatusIdle      StatusType = "idle"
+	StatusInvisible StatusType = "invisible"
+	StatusOffline   StatusType = "offline"
+)
+
+// ActivityType decides how an activity is displayed, such as "Playing {name}"
+type ActivityType int
+
+const (
+	ActivityGame ActivityType = iota
+	ActivityStreaming
+	ActivityListening
+	ActivityWatching
+	ActivityCustom
+	ActivityCompeting
+)
+
+// Activity is the subset of the activity object that bots are allowed to send.
+//
+// See https://discord.com/developers/docs/topics/gateway-events#activity-object
+type Activity struct {
+	Name  string       `json:"name"`
+	Type  ActivityType `json:"type"`
+	URL   string       `json:"url,omitempty"`
+	State string       `json:"state,omitempty"`
+}
+
+// UpdatePresence updates the presence of the bot. A zero Since is sent as null, meaning the client is not idle.
+//
+// See https://discord.com/developers/docs/topics/gateway-events#update-presence
+type UpdatePresence struct {
+	Since      time.Time
+	Activities []Activity
+	Status     StatusType
+	AFK        bool
+}
+
+func (u *UpdatePresence) Validate() error {
+	switch u.Status {
+	case StatusOnline, StatusDND, StatusIdle, StatusInvisible, StatusOffline:
+	default:
+		return invalidCommand(event.UpdatePresence, fmt.Sprintf("has an unknown status '%s'", u.Status))
+	}
+
+	for i := range u.Activities {
+		activity := &u.Activities[i]
+		switch {
+		case activity.Name == "":
+			return invalidCommand(event.UpdatePresence, "has an activity without a name")
+		case activity.Type < ActivityGame || activity.Type > ActivityCompeting:
+			return invalidCommand(event.UpdatePresence, fmt.Sprintf("has an unknown activity type %d", activity.Type))
+		case activity.URL != "" && activity.Type != ActivityStreaming:
+			return invalidCommand(event.UpdatePresence, "can only specify an activity url when streaming")
+		}
+	}
+	return nil
+}
+
+// payload returns the wire format, where since is a unix timestamp in milliseconds and activities are always sent
+func (u *UpdatePresence) payload() interface{} {
+	type presence struct {
+		Since      *int64     `json:"since"`
+		Activities []Activity `json:"activities"`
+		Status     StatusType `json:"status"`
+		AFK        bool       `json:"afk"`
+	}
+
+	p := &presence{
+		Activities: u.Activities,
+		Status:     u.Status,
+		AFK:        u.AFK,
+	}
+	if p.Activities == nil {
+		p.Activities = []Activity{}
+	}
+	if !u.Since.IsZero() {
+		since := u.Since.UnixMilli()
+		p.Since = &since
+	}
+	return p
+}
+
+// UpdateVoiceState joins, moves between or leaves voice channels. An empty ChannelID disconnects from voice.
+//
+// See https://discord.com/developers/docs/topics/gateway-events#update-voice-state
+type UpdateVoiceState struct {
+	GuildID   event.Snowflake
+	ChannelID event.Snowflake
+	SelfMute  bool
+	SelfDeaf  bool
+}
+
+func (u *UpdateVoiceState) Validate() error {
+	if u.GuildID == "" {
+		return invalidCommand(event.UpdateVoiceState, "is missing a guild id")
+	}
+	return nil
+}
+
+// payload returns the wire format, where an empty channel id is sent as null
+func (u *UpdateVoiceState) payload() interface{} {
+	type voiceState struct {
+		GuildID   event.Snowflake  `json:"guild_id"`
+		ChannelID *event.Snowflake `json:"channel_id"`
+		SelfMute  bool             `json:"self_mute"`
+		SelfDeaf  bool             `json:"self_deaf"`
+	}
+
+	p := &voiceState{
+		GuildID:  u.GuildID,
+		SelfMute: u.SelfMute,
+		SelfDeaf: u.SelfDeaf,
+	}
+	if u.ChannelID != "" {
+		channelID := u.ChannelID
+		p.ChannelID = &channelID
+	}
+	return p
+}
+
+// RequestGuildMembers validates and sends a request guild members command. The client must have the intents Discord
+// requires for the request, such as GuildPresences when requesting presences.
+func (c *Client) RequestGuildMembers(pipe io.Writer, request *RequestGuildMembers) error {
+	if err := request.Validate(); err != nil {
+		return err
+	}
+	if required := request.requiredIntents(); c.intents&required != required {
+		return invalidCommand(event.RequestGuildMembers, "requires intents the client did not specify")
+	}
+
+	return c.writeCommand(pipe, event.RequestGuildMembers, request.payload())
+}
+
+// UpdatePresence validates and sends an update presence command.
+func (c *Client) UpdatePresence(pipe io.Writer, presence *UpdatePresence) error {
+	if err := presence.Validate(); err != nil {
+		return err
+	}
+
+	return c.writeCommand(pipe, event.UpdatePresence, presence.payload())
+}
+
+// UpdateVoiceState validates and sends an update voice state command.
+func (c *Client) UpdateVoiceState(pipe io.Writer, voiceState *UpdateVoiceState) error {
+	if err := voiceState.Validate(); err != nil {
+		return err
+	}
+
+	return c.writeCommand(pipe, event.UpdateVoiceState, voiceState.payload())
+}
+
+func (c *Client) writeCommand(pipe io.Writer, evt event.Type, payload interface{}) error {
+	data, err := c.codec.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s command; %w", evt, err)
+	}
+
+	return c.Write(pipe, evt, data)
+}
@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/event/opcode"
+	"github.com/discordpkg/gateway/intent"
+)
+
+type exhaustedRateLimiter struct{}
+
+func (rl *exhaustedRateLimiter) Try(_ ShardID) (bool, time.Duration) {
+	return false, time.Minute
+}
+
+func TestCommands_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		command interface{ Validate() error }
+		valid   bool
+	}{
+		{"members by query", &RequestGuildMembers{GuildID: "1", Query: "go", Limit: 10}, true},
+		{"all members", &RequestGuildMembers{GuildID: "1"}, true},
+		{"members by user ids", &RequestGuildMembers{GuildID: "1", UserIDs: []event.Snowflake{"2"}}, true},
+		{"members without guild", &RequestGuildMembers{Query: "go", Limit: 1}, false},
+		{"members by query and user ids", &RequestGuildMembers{GuildID: "1", Query: "go", Limit: 1, UserIDs: []event.Snowflake{"2"}}, false},
+		{"members with query and no limit", &RequestGuildMembers{GuildID: "1", Query: "go"}, false},
+		{"members limit too high", &RequestGuildMembers{GuildID: "1", Limit: 101}, false},
+		{"members negative limit", &RequestGuildMembers{GuildID: "1", Limit: -1}, false},
+		{"members too many user ids", &RequestGuildMembers{GuildID: "1", UserIDs: make([]event.Snowflake, 101)}, false},
+		{"members nonce too long", &RequestGuildMembers{GuildID: "1", Nonce: strings.Repeat("n", 33)}, false},
+		{"presence", &UpdatePresence{Status: StatusIdle, Activities: []Activity{{Name: "go", Type: ActivityStreaming, URL: "https://twitch.tv/go"}}}, true},
+		{"presence unknown status", &UpdatePresence{Status: "away"}, false},
+		{"presence activity without name", &UpdatePresence{Status: StatusOnline, Activities: []Activity{{}}}, false},
+		{"presence unknown activity type", &UpdatePresence{Status: StatusOnline, Activities: []Activity{{Name: "go", Type: 9}}}, false},
+		{"presence url without streaming", &UpdatePresence{Status: StatusOnline, Activities: []Activity{{Name: "go", URL: "https://go.dev"}}}, false},
+		{"voice state", &UpdateVoiceState{GuildID: "1", ChannelID: "2"}, true},
+		{"voice state without guild", &UpdateVoiceState{ChannelID: "2"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.command.Validate()
+			if tc.valid && err != nil {
+				t.Errorf("expected command to be valid. Got %s", err)
+			}
+			if !tc.valid && !errors.Is(err, ErrInvalidCommand) {
+				t.Errorf("expected ErrInvalidCommand. Got %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_Commands(t *testing.T) {
+	for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			client := NewClientMust(t, append(commonOptions, WithCodec(codec), WithGuildEvents(event.GuildMembersChunk))...)
+			client.ctx.SetState(&ConnectedState{client.ctx})
+
+			read := func(buffer *bytes.Buffer, op opcode.Type) map[string]interface{} {
+				var payload Payload
+				if err := codec.Unmarshal(buffer.Bytes(), &payload); err != nil {
+					t.Fatal(err)
+				}
+				if payload.Op != op {
+					t.Errorf("incorrect op code. Got %d, wants %d", payload.Op, op)
+				}
+
+				var data map[string]interface{}
+				if err := codec.Unmarshal(payload.Data, &data); err != nil {
+					t.Fatal(err)
+				}
+				return data
+			}
+
+			buffer := &bytes.Buffer{}
+			err := client.RequestGuildMembers(buffer, &RequestGuildMembers{GuildID: "1", UserIDs: []event.Snowflake{"2"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			members := read(buffer, opcode.RequestGuildMembers)
+			if _, ok := members["query"]; ok {
+				t.Error("query should not be sent along with user ids")
+			}
+			if members["guild_id"] != "1" {
+				t.Errorf("incorrect guild id. Got %v", members["guild_id"])
+			}
+
+			buffer.Reset()
+			if err = client.UpdatePresence(buffer, &UpdatePresence{Status: StatusDND}); err != nil {
+				t.Fatal(err)
+			}
+			presence := read(buffer, opcode.PresenceUpdate)
+			if presence["since"] != nil || presence["status"] != "dnd" {
+				t.Errorf("incorrect presence. Got %v", presence)
+			}
+			if activities, ok := presence["activities"].([]interface{}); !ok || len(activities) != 0 {
+				t.Errorf("activities should be an empty list. Got %v", presence["activities"])
+			}
+
+			buffer.Reset()
+			if err = client.UpdateVoiceState(buffer, &UpdateVoiceState{GuildID: "1"}); err != nil {
+				t.Fatal(err)
+			}
+			voiceState := read(buffer, opcode.VoiceStateUpdate)
+			if channelID, ok := voiceState["channel_id"]; !ok || channelID != nil {
+				t.Errorf("channel id should be null to disconnect. Got %v", voiceState)
+			}
+		})
+	}
+
+	t.Run("missing intents", func(t *testing.T) {
+		client := NewClientMust(t, append(commonOptions, WithGuildEvents(event.MessageCreate))...)
+		client.ctx.SetState(&ConnectedState{client.ctx})
+
+		err := client.RequestGuildMembers(&bytes.Buffer{}, &RequestGuildMembers{GuildID: "1"})
+		if !errors.Is(err, ErrInvalidCommand) {
+			t.Errorf("expected ErrInvalidCommand. Got %v", err)
+		}
+		if client.intents&intent.GuildMembers != 0 {
+			t.Fatal("test requires a client without the guild members intent")
+		}
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		options := append(commonOptions, WithCommandRateLimiter(&exhaustedRateLimiter{}))
+		client := NewClientMust(t, options...)
+		client.ctx.SetState(&ConnectedState{client.ctx})
+
+		err := client.UpdatePresence(&bytes.Buffer{}, &UpdatePresence{Status: StatusOnline})
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("expected ErrRateLimited. Got %v", err)
+		}
+	})
+
+	t.Run("not connected", func(t *testing.T) {
+		client := NewClientMust(t, commonOptions...)
+
+		err := client.UpdateVoiceState(&bytes.Buffer{}, &UpdateVoiceState{GuildID: "1"})
+		if !errors.Is(err, ErrNotConnectedYet) {
+			t.Errorf("expected ErrNotConnectedYet. Got %v", err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package encoding
+
+// FrameType is the websocket frame type used to send and receive payloads
+type FrameType int
+
+const (
+	TextFrame FrameType = iota
+	BinaryFrame
+)
+
+// Codec encodes and decodes the gateway payloads of a client.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+
+	// Name is the value of the "encoding" query parameter of the dial url, such as "json" or "etf".
+	Name() string
+
+	// FrameType of the websocket messages carrying the payloads.
+	FrameType() FrameType
+}
+
+// JSONCodec encodes payloads as json. The zero value uses the package level Marshal and Unmarshal functions, while
+// NewJSONCodec allows a different json implementation to be used for specific clients.
+type JSONCodec struct {
+	marshal   MarshalFunc
+	unmarshal UnmarshalFunc
+}
+
+var _ Codec = JSONCodec{}
+
+type (
+	MarshalFunc   func(v interface{}) ([]byte, error)
+	UnmarshalFunc func(data []byte, v interface{}) error
+)
+
+// NewJSONCodec creates a json codec using the given implementation, such as jsoniter:
+//
+//	var j = jsoniter.ConfigCompatibleWithStandardLibrary
+//	codec := encoding.NewJSONCodec(j.Marshal, j.Unmarshal)
+func NewJSONCodec(marshal MarshalFunc, unmarshal UnmarshalFunc) JSONCodec {
+	return JSONCodec{
+		marshal:   marshal,
+		unmarshal: unmarshal,
+	}
+}
+
+func (c JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	if c.marshal == nil {
+		return Marshal(v)
+	}
+	return c.marshal(v)
+}
+
+func (c JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	if c.unmarshal == nil {
+		return Unmarshal(data, v)
+	}
+	return c.unmarshal(data, v)
+}
+
+func (JSONCodec) Name() string {
+	return "json"
+}
+
+func (JSONCodec) FrameType() FrameType {
+	return TextFrame
+}
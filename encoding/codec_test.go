@@ -0,0 +1,41 @@
+package encoding
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONCodec(t *testing.T) {
+	var marshalled, unmarshalled bool
+	codec := NewJSONCodec(func(v interface{}) ([]byte, error) {
+		marshalled = true
+		return json.Marshal(v)
+	}, func(data []byte, v interface{}) error {
+		unmarshalled = true
+		return json.Unmarshal(data, v)
+	})
+
+	data, err := codec.Marshal(map[string]int{"op": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v map[string]int
+	if err = codec.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if !marshalled || !unmarshalled {
+		t.Error("the given json implementation was not used")
+	}
+	if v["op"] != 1 {
+		t.Errorf("incorrect value. Got %+v", v)
+	}
+
+	if codec.Name() != "json" || codec.FrameType() != TextFrame {
+		t.Error("json must be sent as text frames with the json encoding")
+	}
+	if (JSONCodec{}).Name() != "json" {
+		t.Error("zero value must be a json codec")
+	}
+}
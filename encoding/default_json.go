@@ -2,6 +2,10 @@ package encoding
 
 import "encoding/json"
 
+// Marshal and Unmarshal are used by the zero value JSONCodec, which is the default codec of a client.
+//
+// Deprecated: overwriting these affects every client in the process. Use NewJSONCodec along with gateway.WithCodec
+// to change the json implementation of a client instead.
 var (
 	Marshal   = json.Marshal
 	Unmarshal = json.Unmarshal
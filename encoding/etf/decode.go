@@ -0,0 +1,733 @@
+package etf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal decodes the ETF data into the value pointed to by v.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("etf: Unmarshal requires a non-nil pointer")
+	}
+
+	if len(data) == 0 || data[0] != version {
+		return &SyntaxError{Offset: 0, msg: "missing version byte"}
+	}
+
+	d := &decoder{data: data, pos: 1}
+	if len(data) > 1 && data[1] == tagCompressed {
+		if err := d.decompress(); err != nil {
+			return err
+		}
+	}
+
+	if err := d.value(rv.Elem()); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return &SyntaxError{Offset: d.pos, msg: "unexpected data after term"}
+	}
+	return nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// integer holds any integer term. Big integers that do not fit in 64 bits are stored in big.
+type integer struct {
+	negative  bool
+	magnitude uint64
+	big       *big.Int
+}
+
+func (i integer) String() string {
+	if i.big != nil {
+		return i.big.String()
+	}
+	if i.negative {
+		return "-" + strconv.FormatUint(i.magnitude, 10)
+	}
+	return strconv.FormatUint(i.magnitude, 10)
+}
+
+func (i integer) int64() (int64, bool) {
+	if i.big != nil {
+		return 0, false
+	}
+	if i.negative {
+		if i.magnitude > 1<<63 {
+			return 0, false
+		}
+		return int64(-i.magnitude), true
+	}
+	if i.magnitude > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(i.magnitude), true
+}
+
+func (i integer) float64() float64 {
+	if i.big != nil {
+		f, _ := new(big.Float).SetInt(i.big).Float64()
+		return f
+	}
+	if i.negative {
+		return -float64(i.magnitude)
+	}
+	return float64(i.magnitude)
+}
+
+func (d *decoder) syntaxError(msg string) error {
+	return &SyntaxError{Offset: d.pos, msg: msg}
+}
+
+func (d *decoder) typeError(term string, t reflect.Type, offset int) error {
+	return &UnmarshalTypeError{Term: term, Type: t, Offset: offset}
+}
+
+func (d *decoder) decompress() error {
+	d.pos++ // tag
+	size, err := d.uint32()
+	if err != nil {
+		return err
+	}
+
+	if size < 0 || size > maxDecompressedSize {
+		return d.syntaxError("compressed term exceeds the maximum size")
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(d.data[d.pos:]))
+	if err != nil {
+		return d.syntaxError("invalid compressed term")
+	}
+	defer reader.Close()
+
+	// the buffer grows with the decompressed data, rather than trusting the size of the header
+	buffer := bytes.NewBuffer([]byte{version})
+	if _, err = io.Copy(buffer, io.LimitReader(reader, int64(size))); err != nil || buffer.Len() != size+1 {
+		return d.syntaxError("invalid compressed term")
+	}
+
+	d.data, d.pos = buffer.Bytes(), 1
+	return nil
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, d.syntaxError("unexpected end of data")
+	}
+
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) byte() (byte, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *decoder) uint16() (int, error) {
+	b, err := d.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint16(b)), nil
+}
+
+func (d *decoder) uint32() (int, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint32(b)), nil
+}
+
+// peekNil reports whether the next term is the nil atom, which Discord uses for null values
+func (d *decoder) peekNil() bool {
+	start := d.pos
+	defer func() {
+		d.pos = start
+	}()
+
+	tag, err := d.byte()
+	if err != nil || !isAtom(tag) {
+		return false
+	}
+	name, err := d.atom(tag)
+	return err == nil && name == "nil"
+}
+
+func isAtom(tag byte) bool {
+	return tag == tagAtom || tag == tagSmallAtom || tag == tagAtomUTF8 || tag == tagSmallAtomUTF8
+}
+
+func (d *decoder) atom(tag byte) (string, error) {
+	var length int
+	var err error
+	if tag == tagSmallAtom || tag == tagSmallAtomUTF8 {
+		var b byte
+		b, err = d.byte()
+		length = int(b)
+	} else {
+		length, err = d.uint16()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	name, err := d.read(length)
+	if err != nil {
+		return "", err
+	}
+	return string(name), nil
+}
+
+func (d *decoder) integer(tag byte) (integer, error) {
+	switch tag {
+	case tagSmallInteger:
+		b, err := d.byte()
+		return integer{magnitude: uint64(b)}, err
+	case tagInteger:
+		b, err := d.read(4)
+		if err != nil {
+			return integer{}, err
+		}
+		i := int32(binary.BigEndian.Uint32(b))
+		if i < 0 {
+			return integer{negative: true, magnitude: uint64(-int64(i))}, nil
+		}
+		return integer{magnitude: uint64(i)}, nil
+	}
+
+	var n int
+	var err error
+	if tag == tagSmallBig {
+		var b byte
+		b, err = d.byte()
+		n = int(b)
+	} else {
+		n, err = d.uint32()
+	}
+	if err != nil {
+		return integer{}, err
+	}
+
+	sign, err := d.byte()
+	if err != nil {
+		return integer{}, err
+	}
+	digits, err := d.read(n)
+	if err != nil {
+		return integer{}, err
+	}
+
+	// digits are stored little endian
+	if n <= 8 {
+		var magnitude uint64
+		for i := n - 1; i >= 0; i-- {
+			magnitude = magnitude<<8 | uint64(digits[i])
+		}
+		return integer{negative: sign != 0, magnitude: magnitude}, nil
+	}
+
+	bigEndian := make([]byte, n)
+	for i := range digits {
+		bigEndian[n-1-i] = digits[i]
+	}
+	value := new(big.Int).SetBytes(bigEndian)
+	if sign != 0 {
+		value.Neg(value)
+	}
+	return integer{negative: sign != 0, big: value}, nil
+}
+
+func (d *decoder) float(tag byte) (float64, error) {
+	if tag == tagNewFloat {
+		b, err := d.read(8)
+		if err != nil {
+			return 0, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	}
+
+	b, err := d.read(31)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(strings.TrimRight(string(b), "\x00"), 64)
+	if err != nil {
+		return 0, d.syntaxError("invalid float")
+	}
+	return f, nil
+}
+
+// bytes reads the content of a binary or string term
+func (d *decoder) bytes(tag byte) ([]byte, error) {
+	var length int
+	var err error
+	if tag == tagBinary {
+		length, err = d.uint32()
+	} else {
+		length, err = d.uint16()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.read(length)
+}
+
+// length reads the number of elements of a list, tuple or map term
+func (d *decoder) length(tag byte) (int, error) {
+	switch tag {
+	case tagNil:
+		return 0, nil
+	case tagSmallTuple:
+		b, err := d.byte()
+		return int(b), err
+	default:
+		n, err := d.uint32()
+		if err != nil {
+			return 0, err
+		}
+		// every element takes at least one byte, which prevents corrupt lengths from allocating huge slices and maps
+		if n < 0 || n > len(d.data)-d.pos {
+			return 0, d.syntaxError("length exceeds the remaining data")
+		}
+		return n, nil
+	}
+}
+
+// tail consumes the tail of a list, which is the empty list for proper lists
+func (d *decoder) tail(tag byte) error {
+	if tag != tagList {
+		return nil
+	}
+	return d.skip()
+}
+
+// skip advances past the next term
+func (d *decoder) skip() error {
+	tag, err := d.byte()
+	if err != nil {
+		return err
+	}
+
+	switch tag {
+	case tagSmallInteger:
+		_, err = d.read(1)
+	case tagInteger:
+		_, err = d.read(4)
+	case tagNewFloat:
+		_, err = d.read(8)
+	case tagFloat:
+		_, err = d.read(31)
+	case tagAtom, tagSmallAtom, tagAtomUTF8, tagSmallAtomUTF8:
+		_, err = d.atom(tag)
+	case tagSmallBig, tagLargeBig:
+		_, err = d.integer(tag)
+	case tagBinary, tagString:
+		_, err = d.bytes(tag)
+	case tagNil, tagSmallTuple, tagLargeTuple, tagList, tagMap:
+		var n int
+		if n, err = d.length(tag); err != nil {
+			return err
+		}
+		if tag == tagMap {
+			n *= 2
+		}
+		for i := 0; i < n && err == nil; i++ {
+			err = d.skip()
+		}
+		if err == nil {
+			err = d.tail(tag)
+		}
+	default:
+		return &SyntaxError{Offset: d.pos - 1, msg: "unsupported term tag " + strconv.Itoa(int(tag))}
+	}
+	return err
+}
+
+// any decodes the next term into a generic Go value, similar to decoding json into an interface{}
+func (d *decoder) any() (interface{}, error) {
+	tag, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case tagSmallInteger, tagInteger, tagSmallBig, tagLargeBig:
+		i, err := d.integer(tag)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := i.int64(); ok {
+			return v, nil
+		}
+		if i.big != nil {
+			return i.big, nil
+		}
+		return new(big.Int).SetUint64(i.magnitude), nil
+	case tagNewFloat, tagFloat:
+		return d.float(tag)
+	case tagAtom, tagSmallAtom, tagAtomUTF8, tagSmallAtomUTF8:
+		name, err := d.atom(tag)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "nil":
+			return nil, nil
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return name, nil
+	case tagBinary:
+		b, err := d.bytes(tag)
+		return string(b), err
+	case tagString:
+		// erlang encodes lists of small integers as strings
+		b, err := d.bytes(tag)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, len(b))
+		for i := range b {
+			list[i] = int64(b[i])
+		}
+		return list, nil
+	case tagNil, tagSmallTuple, tagLargeTuple, tagList:
+		n, err := d.length(tag)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = d.any(); err != nil {
+				return nil, err
+			}
+		}
+		return list, d.tail(tag)
+	case tagMap:
+		n, err := d.length(tag)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key, err := d.key()
+			if err != nil {
+				return nil, err
+			}
+			if m[key], err = d.any(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+
+	return nil, &SyntaxError{Offset: d.pos - 1, msg: "unsupported term tag " + strconv.Itoa(int(tag))}
+}
+
+// key decodes a map key as a string
+func (d *decoder) key() (string, error) {
+	start := d.pos
+	tag, err := d.byte()
+	if err != nil {
+		return "", err
+	}
+
+	switch tag {
+	case tagAtom, tagSmallAtom, tagAtomUTF8, tagSmallAtomUTF8:
+		return d.atom(tag)
+	case tagBinary, tagString:
+		b, err := d.bytes(tag)
+		return string(b), err
+	case tagSmallInteger, tagInteger, tagSmallBig, tagLargeBig:
+		i, err := d.integer(tag)
+		return i.String(), err
+	}
+
+	d.pos = start
+	return "", d.syntaxError("unsupported map key")
+}
+
+// value decodes the next term into v
+func (d *decoder) value(v reflect.Value) error {
+	if v.Type() == rawMessageType {
+		start := d.pos
+		if err := d.skip(); err != nil {
+			return err
+		}
+
+		raw := make([]byte, 0, d.pos-start+1)
+		raw = append(raw, version)
+		raw = append(raw, d.data[start:d.pos]...)
+		v.SetBytes(raw)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if d.peekNil() {
+			v.Set(reflect.Zero(v.Type()))
+			return d.skip()
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.value(v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return d.typeError("term", v.Type(), d.pos)
+		}
+
+		x, err := d.any()
+		if err != nil {
+			return err
+		}
+		if x == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(x))
+		}
+		return nil
+	}
+
+	start := d.pos
+	tag, err := d.byte()
+	if err != nil {
+		return err
+	}
+
+	switch tag {
+	case tagSmallInteger, tagInteger, tagSmallBig, tagLargeBig:
+		i, err := d.integer(tag)
+		if err != nil {
+			return err
+		}
+		return d.storeInteger(v, i, start)
+	case tagNewFloat, tagFloat:
+		f, err := d.float(tag)
+		if err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(f)
+			return nil
+		}
+		return d.typeError("float", v.Type(), start)
+	case tagAtom, tagSmallAtom, tagAtomUTF8, tagSmallAtomUTF8:
+		name, err := d.atom(tag)
+		if err != nil {
+			return err
+		}
+		return d.storeAtom(v, name, start)
+	case tagBinary, tagString:
+		b, err := d.bytes(tag)
+		if err != nil {
+			return err
+		}
+		return d.storeBytes(v, b, tag, start)
+	case tagNil, tagSmallTuple, tagLargeTuple, tagList:
+		n, err := d.length(tag)
+		if err != nil {
+			return err
+		}
+		if err = d.storeList(v, n, start); err != nil {
+			return err
+		}
+		return d.tail(tag)
+	case tagMap:
+		n, err := d.length(tag)
+		if err != nil {
+			return err
+		}
+		return d.storeMap(v, n, start)
+	}
+
+	return &SyntaxError{Offset: start, msg: "unsupported term tag " + strconv.Itoa(int(tag))}
+}
+
+func (d *decoder) storeInteger(v reflect.Value, i integer, start int) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := i.int64()
+		if !ok || v.OverflowInt(n) {
+			return d.typeError("integer "+i.String(), v.Type(), start)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i.negative || i.big != nil || v.OverflowUint(i.magnitude) {
+			return d.typeError("integer "+i.String(), v.Type(), start)
+		}
+		v.SetUint(i.magnitude)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(i.float64())
+	case reflect.String:
+		// snowflakes are sent as integers
+		v.SetString(i.String())
+	default:
+		return d.typeError("integer", v.Type(), start)
+	}
+	return nil
+}
+
+func (d *decoder) storeAtom(v reflect.Value, name string, start int) error {
+	if name == "nil" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if name != "true" && name != "false" {
+			return d.typeError("atom "+name, v.Type(), start)
+		}
+		v.SetBool(name == "true")
+	case reflect.String:
+		v.SetString(name)
+	default:
+		return d.typeError("atom "+name, v.Type(), start)
+	}
+	return nil
+}
+
+func (d *decoder) storeBytes(v reflect.Value, b []byte, tag byte, start int) error {
+	// timestamps are sent as binaries
+	if tag == tagBinary && v.CanAddr() {
+		if unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if err := unmarshaler.UnmarshalText(b); err != nil {
+				return d.typeError("binary", v.Type(), start)
+			}
+			return nil
+		}
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		if v.Type() == reflect.TypeOf(json.Number("")) {
+			if _, err := strconv.ParseFloat(string(b), 64); err != nil {
+				return d.typeError("binary", v.Type(), start)
+			}
+		}
+		v.SetString(string(b))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(append([]byte{}, b...))
+	case tag == tagString && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+		// a list of small integers
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(b), len(b)))
+		}
+		for i := 0; i < len(b) && i < v.Len(); i++ {
+			if err := d.storeInteger(v.Index(i), integer{magnitude: uint64(b[i])}, start); err != nil {
+				return err
+			}
+		}
+	default:
+		return d.typeError("binary", v.Type(), start)
+	}
+	return nil
+}
+
+func (d *decoder) storeList(v reflect.Value, n, start int) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+	case reflect.Array:
+	case reflect.String:
+		if n == 0 {
+			// erlang has no empty string, an empty list is used instead
+			v.SetString("")
+			return nil
+		}
+		return d.typeError("list", v.Type(), start)
+	default:
+		return d.typeError("list", v.Type(), start)
+	}
+
+	for i := 0; i < n; i++ {
+		if i >= v.Len() {
+			if err := d.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.value(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) storeMap(v reflect.Value, n, start int) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := cachedFields(v.Type())
+		for i := 0; i < n; i++ {
+			key, err := d.key()
+			if err != nil {
+				return err
+			}
+
+			f := lookupField(fields, key)
+			if f == nil {
+				if err = d.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err = d.value(v.FieldByIndex(f.index)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), n))
+		}
+
+		keyType, elemType := v.Type().Key(), v.Type().Elem()
+		for i := 0; i < n; i++ {
+			key := reflect.New(keyType).Elem()
+			if keyType.Kind() == reflect.String {
+				name, err := d.key()
+				if err != nil {
+					return err
+				}
+				key.SetString(name)
+			} else if err := d.value(key); err != nil {
+				return err
+			}
+
+			elem := reflect.New(elemType).Elem()
+			if err := d.value(elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+		return nil
+	}
+
+	return d.typeError("map", v.Type(), start)
+}
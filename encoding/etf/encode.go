@@ -0,0 +1,363 @@
+package etf
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+var (
+	bigIntType        = reflect.TypeOf(big.Int{})
+	jsonNumberType    = reflect.TypeOf(json.Number(""))
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Marshal returns the ETF encoding of v, including the version byte.
+func Marshal(v interface{}) ([]byte, error) {
+	e := &encoder{}
+	e.buffer.WriteByte(version)
+	if err := e.value(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buffer.Bytes(), nil
+}
+
+type encoder struct {
+	buffer bytes.Buffer
+}
+
+func (e *encoder) uint16(n int) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(n))
+	e.buffer.Write(b[:])
+}
+
+func (e *encoder) uint32(n int) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(n))
+	e.buffer.Write(b[:])
+}
+
+func (e *encoder) atom(name string) {
+	e.buffer.WriteByte(tagSmallAtomUTF8)
+	e.buffer.WriteByte(byte(len(name)))
+	e.buffer.WriteString(name)
+}
+
+func (e *encoder) nil() {
+	e.atom("nil")
+}
+
+func (e *encoder) bool(b bool) {
+	if b {
+		e.atom("true")
+	} else {
+		e.atom("false")
+	}
+}
+
+func (e *encoder) binary(b []byte) {
+	e.buffer.WriteByte(tagBinary)
+	e.uint32(len(b))
+	e.buffer.Write(b)
+}
+
+func (e *encoder) int(i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxUint8:
+		e.buffer.WriteByte(tagSmallInteger)
+		e.buffer.WriteByte(byte(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		e.buffer.WriteByte(tagInteger)
+		e.uint32(int(uint32(int32(i))))
+	case i < 0:
+		e.big(true, uint64(-i))
+	default:
+		e.big(false, uint64(i))
+	}
+}
+
+func (e *encoder) uint(i uint64) {
+	if i <= math.MaxInt32 {
+		e.int(int64(i))
+		return
+	}
+	e.big(false, i)
+}
+
+// big writes a SMALL_BIG_EXT, where the digits are stored little endian
+func (e *encoder) big(negative bool, magnitude uint64) {
+	var digits []byte
+	for ; magnitude > 0; magnitude >>= 8 {
+		digits = append(digits, byte(magnitude))
+	}
+
+	e.buffer.WriteByte(tagSmallBig)
+	e.buffer.WriteByte(byte(len(digits)))
+	if negative {
+		e.buffer.WriteByte(1)
+	} else {
+		e.buffer.WriteByte(0)
+	}
+	e.buffer.Write(digits)
+}
+
+func (e *encoder) bigInt(i *big.Int) error {
+	if i.IsInt64() {
+		e.int(i.Int64())
+		return nil
+	}
+
+	bigEndian := new(big.Int).Abs(i).Bytes()
+	n := len(bigEndian)
+	if n <= math.MaxUint8 {
+		e.buffer.WriteByte(tagSmallBig)
+		e.buffer.WriteByte(byte(n))
+	} else {
+		e.buffer.WriteByte(tagLargeBig)
+		e.uint32(n)
+	}
+	if i.Sign() < 0 {
+		e.buffer.WriteByte(1)
+	} else {
+		e.buffer.WriteByte(0)
+	}
+	for j := n - 1; j >= 0; j-- {
+		e.buffer.WriteByte(bigEndian[j])
+	}
+	return nil
+}
+
+func (e *encoder) float(f float64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(f))
+	e.buffer.WriteByte(tagNewFloat)
+	e.buffer.Write(b[:])
+}
+
+// number writes a json.Number as an integer when possible, and otherwise as a float
+func (e *encoder) number(n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		e.int(i)
+		return nil
+	}
+	if i, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		e.uint(i)
+		return nil
+	}
+	if i, ok := new(big.Int).SetString(string(n), 10); ok {
+		return e.bigInt(i)
+	}
+
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return &UnsupportedTypeError{Type: jsonNumberType}
+	}
+	e.float(f)
+	return nil
+}
+
+func (e *encoder) value(v reflect.Value) error {
+	if !v.IsValid() {
+		e.nil()
+		return nil
+	}
+
+	switch v.Type() {
+	case rawMessageType:
+		return e.rawMessage(v.Bytes())
+	case jsonNumberType:
+		return e.number(json.Number(v.String()))
+	case bigIntType:
+		i := v.Interface().(big.Int)
+		return e.bigInt(&i)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.nil()
+			return nil
+		}
+		return e.value(v.Elem())
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.binary(text)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		e.bool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.uint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.float(v.Float())
+	case reflect.String:
+		e.binary([]byte(v.String()))
+	case reflect.Slice:
+		if v.IsNil() {
+			e.nil()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.binary(v.Bytes())
+			return nil
+		}
+		return e.list(v)
+	case reflect.Array:
+		return e.list(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.nil()
+			return nil
+		}
+		return e.mapValue(v)
+	case reflect.Struct:
+		return e.structValue(v)
+	default:
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+	return nil
+}
+
+func (e *encoder) list(v reflect.Value) error {
+	if v.Len() == 0 {
+		e.buffer.WriteByte(tagNil)
+		return nil
+	}
+
+	e.buffer.WriteByte(tagList)
+	e.uint32(v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if err := e.value(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	e.buffer.WriteByte(tagNil)
+	return nil
+}
+
+func (e *encoder) mapValue(v reflect.Value) error {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: key, value: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	e.buffer.WriteByte(tagMap)
+	e.uint32(len(entries))
+	for _, entry := range entries {
+		e.binary([]byte(entry.key))
+		if err := e.value(entry.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapKey converts a map key to a string, following the rules of encoding/json
+func mapKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if key.Type().Implements(textMarshalerType) {
+		text, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", &UnsupportedTypeError{Type: key.Type()}
+}
+
+func (e *encoder) structValue(v reflect.Value) error {
+	fields := cachedFields(v.Type())
+
+	values := make([]reflect.Value, 0, len(fields))
+	names := make([]string, 0, len(fields))
+	for i := range fields {
+		fv, ok := fieldByIndex(v, fields[i].index)
+		if !ok || (fields[i].omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		values = append(values, fv)
+		names = append(names, fields[i].name)
+	}
+
+	e.buffer.WriteByte(tagMap)
+	e.uint32(len(values))
+	for i := range values {
+		e.binary([]byte(names[i]))
+		if err := e.value(values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex, except that it reports false instead of panicking on nil embedded
+// struct pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// rawMessage embeds an already encoded term. Raw JSON is converted to ETF, so payloads built as JSON can still be
+// sent over an ETF connection.
+func (e *encoder) rawMessage(raw []byte) error {
+	if len(raw) == 0 {
+		e.nil()
+		return nil
+	}
+	if raw[0] == version {
+		e.buffer.Write(raw[1:])
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return err
+	}
+	return e.value(reflect.ValueOf(v))
+}
@@ -0,0 +1,103 @@
+// Package etf implements the Erlang External Term Format used by the Discord gateway when dialing with
+// "encoding=etf".
+//
+// Terms are mapped to Go values much like encoding/json does, and struct fields are matched using their json tags:
+//
+//	maps                        <=> structs, maps
+//	lists, tuples               <=> slices, arrays
+//	binaries                    <=> strings, []byte
+//	small/large integers, bigs  <=> integers, floats, strings (Discord sends snowflakes as integers)
+//	atoms nil, true, false      <=> nil, bools
+//
+// A json.RawMessage is decoded into the raw ETF term. When encoding a json.RawMessage that holds JSON, the JSON is
+// converted to ETF, which allows existing code writing JSON payloads to keep working.
+//
+// See https://www.erlang.org/doc/apps/erts/erl_ext_dist.html
+package etf
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/discordpkg/gateway/encoding"
+)
+
+const version = 131
+
+// maxDecompressedSize is the largest size of a compressed term that is decoded, in bytes
+const maxDecompressedSize = 64 << 20
+
+const (
+	tagNewFloat      = 70
+	tagCompressed    = 80
+	tagSmallInteger  = 97
+	tagInteger       = 98
+	tagFloat         = 99
+	tagAtom          = 100
+	tagSmallTuple    = 104
+	tagLargeTuple    = 105
+	tagNil           = 106
+	tagString        = 107
+	tagList          = 108
+	tagBinary        = 109
+	tagSmallBig      = 110
+	tagLargeBig      = 111
+	tagSmallAtom     = 115
+	tagMap           = 116
+	tagAtomUTF8      = 118
+	tagSmallAtomUTF8 = 119
+)
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// SyntaxError describes malformed ETF data
+type SyntaxError struct {
+	Offset int
+	msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("etf: %s (offset %d)", e.msg, e.Offset)
+}
+
+// UnmarshalTypeError describes an ETF term that can not be stored in a Go value of the given type
+type UnmarshalTypeError struct {
+	Term   string
+	Type   reflect.Type
+	Offset int
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return fmt.Sprintf("etf: cannot unmarshal %s into Go value of type %s (offset %d)", e.Term, e.Type, e.Offset)
+}
+
+// UnsupportedTypeError is returned by Marshal when given a value that can not be encoded
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "etf: unsupported type: " + e.Type.String()
+}
+
+// Codec implements encoding.Codec for the gateway "etf" encoding.
+type Codec struct{}
+
+var _ encoding.Codec = Codec{}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return Marshal(v)
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	return Unmarshal(data, v)
+}
+
+func (Codec) Name() string {
+	return "etf"
+}
+
+func (Codec) FrameType() encoding.FrameType {
+	return encoding.BinaryFrame
+}
@@ -0,0 +1,346 @@
+package etf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// term helpers for handcrafting ETF data
+
+func smallAtom(name string) []byte {
+	return append([]byte{tagSmallAtomUTF8, byte(len(name))}, name...)
+}
+
+func atom(name string) []byte {
+	return append([]byte{tagAtom, 0, byte(len(name))}, name...)
+}
+
+func bin(s string) []byte {
+	b := []byte{tagBinary, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(len(s)))
+	return append(b, s...)
+}
+
+func mapTerm(pairs ...[]byte) []byte {
+	b := []byte{tagMap, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(len(pairs)/2))
+	for _, pair := range pairs {
+		b = append(b, pair...)
+	}
+	return b
+}
+
+func listTerm(elements ...[]byte) []byte {
+	b := []byte{tagList, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(len(elements)))
+	for _, element := range elements {
+		b = append(b, element...)
+	}
+	return append(b, tagNil)
+}
+
+func term(b []byte) []byte {
+	return append([]byte{version}, b...)
+}
+
+func TestUnmarshal(t *testing.T) {
+	type Author struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Bot      bool   `json:"bot"`
+	}
+	type Message struct {
+		ID        uint64   `json:"id"`
+		ChannelID string   `json:"channel_id"`
+		Content   string   `json:"content"`
+		Author    *Author  `json:"author"`
+		Mentions  []string `json:"mentions"`
+		Nonce     *string  `json:"nonce"`
+		Flags     int      `json:"flags"`
+		Score     float64  `json:"score"`
+	}
+
+	// snowflakes do not fit in 32 bits, so Discord sends them as small big integers
+	snowflake := []byte{tagSmallBig, 8, 0, 0x00, 0x40, 0x6a, 0xd6, 0x74, 0x73, 0x6f, 0x0f}
+	data := term(mapTerm(
+		smallAtom("id"), snowflake,
+		smallAtom("channel_id"), snowflake,
+		atom("content"), bin("hello"),
+		smallAtom("author"), mapTerm(
+			smallAtom("id"), snowflake,
+			smallAtom("username"), bin("gopher"),
+			smallAtom("bot"), smallAtom("true"),
+		),
+		smallAtom("mentions"), []byte{tagNil},
+		smallAtom("nonce"), smallAtom("nil"),
+		smallAtom("flags"), []byte{tagInteger, 0xff, 0xff, 0xff, 0xfe},
+		smallAtom("score"), []byte{tagNewFloat, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0},
+		smallAtom("unknown"), listTerm(bin("skipped"), mapTerm()),
+	))
+
+	var message Message
+	if err := Unmarshal(data, &message); err != nil {
+		t.Fatal(err)
+	}
+
+	const id = 1112234578634489856
+	wants := Message{
+		ID:        id,
+		ChannelID: "1112234578634489856",
+		Content:   "hello",
+		Author:    &Author{ID: "1112234578634489856", Username: "gopher", Bot: true},
+		Mentions:  []string{},
+		Flags:     -2,
+		Score:     1.5,
+	}
+	if !reflect.DeepEqual(message, wants) {
+		t.Errorf("incorrect message. Got %+v, wants %+v", message, wants)
+	}
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	data := term(mapTerm(
+		smallAtom("op"), []byte{tagSmallInteger, 10},
+		smallAtom("d"), mapTerm(
+			smallAtom("heartbeat_interval"), []byte{tagInteger, 0, 0, 0xa1, 0x22},
+			smallAtom("_trace"), listTerm(bin("gateway-prd")),
+		),
+		smallAtom("s"), smallAtom("nil"),
+		smallAtom("t"), smallAtom("nil"),
+		smallAtom("chars"), []byte{tagString, 0, 2, 1, 2},
+		smallAtom("big"), []byte{tagSmallBig, 9, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+	))
+
+	var v interface{}
+	if err := Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	bigValue, _ := new(big.Int).SetString("-18446744073709551616", 10)
+	wants := map[string]interface{}{
+		"op": int64(10),
+		"d": map[string]interface{}{
+			"heartbeat_interval": int64(41250),
+			"_trace":             []interface{}{"gateway-prd"},
+		},
+		"s":     nil,
+		"t":     nil,
+		"chars": []interface{}{int64(1), int64(2)},
+		"big":   bigValue,
+	}
+	if !reflect.DeepEqual(v, wants) {
+		t.Errorf("incorrect value. Got %+v, wants %+v", v, wants)
+	}
+}
+
+func TestUnmarshalRawMessage(t *testing.T) {
+	type Payload struct {
+		Op   int             `json:"op"`
+		Data json.RawMessage `json:"d"`
+	}
+
+	inner := mapTerm(smallAtom("heartbeat_interval"), []byte{tagInteger, 0, 0, 0xa1, 0x22})
+	data := term(mapTerm(smallAtom("op"), []byte{tagSmallInteger, 10}, smallAtom("d"), inner))
+
+	var payload Payload
+	if err := Unmarshal(data, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(payload.Data, term(inner)) {
+		t.Fatalf("raw message should hold the versioned term, got %v", []byte(payload.Data))
+	}
+
+	var hello struct {
+		HeartbeatInterval int `json:"heartbeat_interval"`
+	}
+	if err := Unmarshal(payload.Data, &hello); err != nil {
+		t.Fatal(err)
+	}
+	if hello.HeartbeatInterval != 41250 {
+		t.Errorf("incorrect heartbeat interval. Got %d", hello.HeartbeatInterval)
+	}
+}
+
+// compressed creates a compressed term, with the given uncompressed size in its header
+func compressed(b []byte, size int) []byte {
+	var buffer bytes.Buffer
+	writer := zlib.NewWriter(&buffer)
+	_, _ = writer.Write(b)
+	_ = writer.Close()
+
+	data := []byte{version, tagCompressed, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(data[2:], uint32(size))
+	return append(data, buffer.Bytes()...)
+}
+
+func TestUnmarshalCompressed(t *testing.T) {
+	uncompressed := bin("compressed")
+	data := compressed(uncompressed, len(uncompressed))
+
+	var s string
+	if err := Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s != "compressed" {
+		t.Errorf("incorrect value. Got '%s'", s)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		data []byte
+		v    interface{}
+		err  interface{}
+	}{
+		{"missing version", bin("x"), new(string), &SyntaxError{}},
+		{"truncated", term(bin("x"))[:5], new(string), &SyntaxError{}},
+		{"trailing data", append(term(bin("x")), tagNil), new(string), &SyntaxError{}},
+		{"unknown tag", term([]byte{1}), new(interface{}), &SyntaxError{}},
+		{"binary into int", term(bin("x")), new(int), &UnmarshalTypeError{}},
+		{"overflow", term([]byte{tagInteger, 0, 0, 1, 0}), new(uint8), &UnmarshalTypeError{}},
+		{"negative into uint", term([]byte{tagInteger, 0xff, 0xff, 0xff, 0xff}), new(uint), &UnmarshalTypeError{}},
+		{"atom into bool", term(smallAtom("maybe")), new(bool), &UnmarshalTypeError{}},
+		{"truncated list", term(listTerm(bin("x"), bin("y")))[:12], new([]string), &SyntaxError{}},
+		{"oversized list", term([]byte{tagList, 0xff, 0xff, 0xff, 0xff, tagNil}), new(interface{}), &SyntaxError{}},
+		{"oversized list into slice", term([]byte{tagList, 0xff, 0xff, 0xff, 0xff, tagNil}), new([]int), &SyntaxError{}},
+		{"oversized tuple", term([]byte{tagLargeTuple, 0x7f, 0xff, 0xff, 0xff}), new(interface{}), &SyntaxError{}},
+		{"oversized map", term([]byte{tagMap, 0xff, 0xff, 0xff, 0xff}), new(interface{}), &SyntaxError{}},
+		{"oversized map into map", term([]byte{tagMap, 0xff, 0xff, 0xff, 0xff}), new(map[string]int), &SyntaxError{}},
+		{"oversized compressed", []byte{version, tagCompressed, 0xff, 0xff, 0xff, 0xff}, new(interface{}), &SyntaxError{}},
+		{"truncated compressed", compressed(bin("compressed"), 100), new(string), &SyntaxError{}},
+		{"undersized compressed", compressed(bin("compressed"), 2), new(string), &SyntaxError{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Unmarshal(tc.data, tc.v)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			target := reflect.New(reflect.TypeOf(tc.err))
+			if !errors.As(err, target.Interface()) {
+				t.Errorf("expected %T, got %T: %s", tc.err, err, err)
+			}
+		})
+	}
+
+	var s string
+	if err := Unmarshal(term(bin("x")), s); err == nil {
+		t.Error("expected error for non-pointer value")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value interface{}
+		wants []byte
+	}{
+		{"nil", nil, smallAtom("nil")},
+		{"true", true, smallAtom("true")},
+		{"small integer", 200, []byte{tagSmallInteger, 200}},
+		{"integer", -2, []byte{tagInteger, 0xff, 0xff, 0xff, 0xfe}},
+		{"small big", uint64(1112234578634489856), []byte{tagSmallBig, 8, 0, 0x00, 0x40, 0x6a, 0xd6, 0x74, 0x73, 0x6f, 0x0f}},
+		{"negative small big", int64(-1 << 40), []byte{tagSmallBig, 6, 1, 0, 0, 0, 0, 0, 1}},
+		{"float", 1.5, []byte{tagNewFloat, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"string", "hi", bin("hi")},
+		{"bytes", []byte("hi"), bin("hi")},
+		{"empty list", []int{}, []byte{tagNil}},
+		{"list", []string{"a"}, listTerm(bin("a"))},
+		{"map", map[string]int{"b": 2, "a": 1}, mapTerm(bin("a"), []byte{tagSmallInteger, 1}, bin("b"), []byte{tagSmallInteger, 2})},
+		{"json number", json.Number("41250"), []byte{tagInteger, 0, 0, 0xa1, 0x22}},
+		{"raw json", json.RawMessage(`{"since":null,"afk":false}`), mapTerm(bin("afk"), smallAtom("false"), bin("since"), smallAtom("nil"))},
+		{"raw etf", json.RawMessage(term(bin("raw"))), bin("raw")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := Marshal(tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wants := term(tc.wants); !bytes.Equal(data, wants) {
+				t.Errorf("incorrect encoding.\ngot:   %v\nwants: %v", data, wants)
+			}
+		})
+	}
+
+	if _, err := Marshal(make(chan int)); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	type Embedded struct {
+		Shard [2]int `json:"shard"`
+	}
+	type Identify struct {
+		Embedded
+		Token      string            `json:"token"`
+		Properties map[string]string `json:"properties"`
+		Presence   interface{}       `json:"presence"`
+		Intents    uint64            `json:"intents"`
+		Compress   bool              `json:"compress,omitempty"`
+		Ignored    string            `json:"-"`
+		Threshold  *int              `json:"large_threshold,omitempty"`
+	}
+
+	identify := Identify{
+		Embedded:   Embedded{Shard: [2]int{1, 4}},
+		Token:      "token",
+		Properties: map[string]string{"os": "linux"},
+		Intents:    1<<32 | 1,
+		Ignored:    "ignored",
+	}
+
+	data, err := Marshal(&identify)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]interface{}
+	if err = Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"compress", "large_threshold", "Ignored", "Embedded"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("field '%s' should not be encoded", name)
+		}
+	}
+
+	var decoded Identify
+	if err = Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	identify.Ignored = ""
+	if !reflect.DeepEqual(decoded, identify) {
+		t.Errorf("round trip failed. Got %+v, wants %+v", decoded, identify)
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	f.Add(term(mapTerm(bin("op"), []byte{tagSmallInteger, 10}, bin("d"), listTerm(bin("x"), atom("nil")))))
+	f.Add(compressed(bin("compressed"), 15))
+	f.Add(term([]byte{tagList, 0xff, 0xff, 0xff, 0xff, tagNil}))
+	f.Add(term([]byte{tagMap, 0xff, 0xff, 0xff, 0xff}))
+	f.Add([]byte{version, tagCompressed, 0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v interface{}
+		_ = Unmarshal(data, &v)
+
+		var payload struct {
+			Op   int               `json:"op"`
+			Data []json.RawMessage `json:"d"`
+		}
+		_ = Unmarshal(data, &payload)
+	})
+}
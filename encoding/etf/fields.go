@@ -0,0 +1,110 @@
+package etf
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// cachedFields returns the exported fields of a struct type, named after their json tags. Fields of embedded
+// structs are promoted, as done by encoding/json.
+func cachedFields(t reflect.Type) []field {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]field)
+	}
+
+	fields, _ := fieldCache.LoadOrStore(t, dominantFields(typeFields(t, nil)))
+	return fields.([]field)
+}
+
+// dominantFields removes promoted fields that are shadowed by a field of the same name at a shallower depth
+func dominantFields(fields []field) []field {
+	depth := make(map[string]int, len(fields))
+	for _, f := range fields {
+		if d, ok := depth[f.name]; !ok || len(f.index) < d {
+			depth[f.name] = len(f.index)
+		}
+	}
+
+	dominant := fields[:0]
+	for _, f := range fields {
+		if len(f.index) == depth[f.name] {
+			dominant = append(dominant, f)
+			depth[f.name] = -1 // keep the first field when several share the same depth
+		}
+	}
+	return dominant
+}
+
+func typeFields(t reflect.Type, parent []int) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, typeFields(sf.Type, index)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{
+			name:      name,
+			index:     index,
+			omitEmpty: strings.Contains(options, "omitempty"),
+		})
+	}
+
+	return fields
+}
+
+func lookupField(fields []field, name string) *field {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
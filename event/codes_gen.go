@@ -8,24 +8,24 @@ import (
 
 func (t Type) OpCode() opcode.Type {
 	switch t {
-	case Resume:
-		return opcode.Resume
 	case Heartbeat:
 		return opcode.Heartbeat
-	case VoiceStateUpdate:
-		return opcode.VoiceStateUpdate
 	case Hello:
 		return opcode.Hello
 	case Identify:
 		return opcode.Identify
 	case InvalidSession:
 		return opcode.InvalidSession
-	case PresenceUpdate:
+	case UpdatePresence:
 		return opcode.PresenceUpdate
 	case Reconnect:
 		return opcode.Reconnect
 	case RequestGuildMembers:
 		return opcode.RequestGuildMembers
+	case Resume:
+		return opcode.Resume
+	case UpdateVoiceState:
+		return opcode.VoiceStateUpdate
 	default:
 		return opcode.Dispatch
 	}
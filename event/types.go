@@ -0,0 +1,55 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/discordpkg/gateway/encoding"
+)
+
+// ErrUnknownPayload is returned by Decode when the event has no generated payload struct
+var ErrUnknownPayload = errors.New("event has no payload struct")
+
+// Snowflake is a Discord ID. JSON sends snowflakes as strings, while ETF sends them as integers which are decoded
+// to their decimal representation.
+type Snowflake string
+
+// UnavailableGuild is a guild which is either not yet available after connecting, or has become unavailable due
+// to an outage. It is sent as the GUILD_DELETE payload and as part of READY.
+type UnavailableGuild struct {
+	ID          Snowflake `json:"id"`
+	Unavailable bool      `json:"unavailable"`
+}
+
+// GuildsReady is a synthetic event of this library, it is never sent by Discord. It is dispatched once every guild of
+// the Ready payload has been received, or the timeout was reached. See gateway.WithGuildsReady.
+const GuildsReady Type = "GUILDS_READY"
+
+// GuildsReadyEvent is the payload of the synthetic GuildsReady event.
+type GuildsReadyEvent struct {
+	// Available holds the guilds received through GUILD_CREATE
+	Available []Snowflake `json:"available"`
+	// Unavailable holds the guilds Discord reported as unavailable, due to an outage
+	Unavailable []Snowflake `json:"unavailable"`
+	// TimedOut holds the guilds that were not received before the timeout
+	TimedOut []Snowflake `json:"timed_out"`
+}
+
+// Decode unmarshals the payload of a dispatch event into its generated struct, such that handlers can switch on
+// the concrete type:
+//
+//	payload, err := event.Decode(client.Codec(), t, data)
+//	switch p := payload.(type) {
+//	case *event.MessageCreateEvent:
+//		fmt.Println(p.Content)
+//	}
+func Decode(codec encoding.Codec, t Type, data []byte) (interface{}, error) {
+	payload := NewPayload(t)
+	if payload == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPayload, t)
+	}
+	if err := codec.Unmarshal(data, payload); err != nil {
+		return nil, fmt.Errorf("unable to decode %s payload: %w", t, err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,1259 @@
+package event
+
+// The payload structs follow the rules of internal/generate/events/types.go, but this file has not been generated
+// from the discord-api-docs submodule yet. Run go generate to replace it with the generated code.
+
+import (
+	"time"
+
+	"github.com/discordpkg/gateway/encoding"
+)
+
+// ApplicationCommandPermissionsUpdateEvent is the payload of the ApplicationCommandPermissionsUpdate dispatch event
+type ApplicationCommandPermissionsUpdateEvent struct {
+	GuildApplicationCommandPermissions
+}
+
+// AutoModerationActionExecutionEvent is the payload of the AutoModerationActionExecution dispatch event
+type AutoModerationActionExecutionEvent struct {
+	GuildID              Snowflake            `json:"guild_id"`
+	Action               AutoModerationAction `json:"action"`
+	RuleID               Snowflake            `json:"rule_id"`
+	RuleTriggerType      int                  `json:"rule_trigger_type"`
+	UserID               Snowflake            `json:"user_id"`
+	ChannelID            Snowflake            `json:"channel_id,omitempty"`
+	MessageID            Snowflake            `json:"message_id,omitempty"`
+	AlertSystemMessageID Snowflake            `json:"alert_system_message_id,omitempty"`
+	Content              string               `json:"content"`
+	MatchedKeyword       *string              `json:"matched_keyword"`
+	MatchedContent       *string              `json:"matched_content,omitempty"`
+}
+
+// AutoModerationRuleCreateEvent is the payload of the AutoModerationRuleCreate dispatch event
+type AutoModerationRuleCreateEvent struct {
+	AutoModerationRule
+}
+
+// AutoModerationRuleDeleteEvent is the payload of the AutoModerationRuleDelete dispatch event
+type AutoModerationRuleDeleteEvent struct {
+	AutoModerationRule
+}
+
+// AutoModerationRuleUpdateEvent is the payload of the AutoModerationRuleUpdate dispatch event
+type AutoModerationRuleUpdateEvent struct {
+	AutoModerationRule
+}
+
+// ChannelCreateEvent is the payload of the ChannelCreate dispatch event
+type ChannelCreateEvent struct {
+	Channel
+}
+
+// ChannelDeleteEvent is the payload of the ChannelDelete dispatch event
+type ChannelDeleteEvent struct {
+	Channel
+}
+
+// ChannelPinsUpdateEvent is the payload of the ChannelPinsUpdate dispatch event
+type ChannelPinsUpdateEvent struct {
+	GuildID          Snowflake  `json:"guild_id,omitempty"`
+	ChannelID        Snowflake  `json:"channel_id"`
+	LastPinTimestamp *time.Time `json:"last_pin_timestamp,omitempty"`
+}
+
+// ChannelUpdateEvent is the payload of the ChannelUpdate dispatch event
+type ChannelUpdateEvent struct {
+	Channel
+}
+
+// GuildAuditLogEntryCreateEvent is the payload of the GuildAuditLogEntryCreate dispatch event
+type GuildAuditLogEntryCreateEvent struct {
+	AuditLogEntry
+	GuildID Snowflake `json:"guild_id"`
+}
+
+// GuildBanAddEvent is the payload of the GuildBanAdd dispatch event
+type GuildBanAddEvent struct {
+	GuildID Snowflake `json:"guild_id"`
+	User    User      `json:"user"`
+}
+
+// GuildBanRemoveEvent is the payload of the GuildBanRemove dispatch event
+type GuildBanRemoveEvent struct {
+	GuildID Snowflake `json:"guild_id"`
+	User    User      `json:"user"`
+}
+
+// GuildCreateEvent is the payload of the GuildCreate dispatch event
+type GuildCreateEvent struct {
+	Guild
+	JoinedAt             time.Time             `json:"joined_at"`
+	Large                bool                  `json:"large"`
+	Unavailable          bool                  `json:"unavailable,omitempty"`
+	MemberCount          int                   `json:"member_count"`
+	VoiceStates          []VoiceState          `json:"voice_states"`
+	Members              []GuildMember         `json:"members"`
+	Channels             []Channel             `json:"channels"`
+	Threads              []Channel             `json:"threads"`
+	Presences            []PresenceUpdateEvent `json:"presences"`
+	StageInstances       []StageInstance       `json:"stage_instances"`
+	GuildScheduledEvents []GuildScheduledEvent `json:"guild_scheduled_events"`
+}
+
+// GuildDeleteEvent is the payload of the GuildDelete dispatch event
+type GuildDeleteEvent struct {
+	UnavailableGuild
+}
+
+// GuildEmojisUpdateEvent is the payload of the GuildEmojisUpdate dispatch event
+type GuildEmojisUpdateEvent struct {
+	GuildID Snowflake `json:"guild_id"`
+	Emojis  []Emoji   `json:"emojis"`
+}
+
+// GuildIntegrationsUpdateEvent is the payload of the GuildIntegrationsUpdate dispatch event
+type GuildIntegrationsUpdateEvent struct {
+	GuildID Snowflake `json:"guild_id"`
+}
+
+// GuildMemberAddEvent is the payload of the GuildMemberAdd dispatch event
+type GuildMemberAddEvent struct {
+	GuildMember
+	GuildID Snowflake `json:"guild_id"`
+}
+
+// GuildMemberRemoveEvent is the payload of the GuildMemberRemove dispatch event
+type GuildMemberRemoveEvent struct {
+	GuildID Snowflake `json:"guild_id"`
+	User    User      `json:"user"`
+}
+
+// GuildMemberUpdateEvent is the payload of the GuildMemberUpdate dispatch event
+type GuildMemberUpdateEvent struct {
+	GuildID                    Snowflake   `json:"guild_id"`
+	Roles                      []Snowflake `json:"roles"`
+	User                       User        `json:"user"`
+	Nick                       *string     `json:"nick,omitempty"`
+	Avatar                     *string     `json:"avatar"`
+	JoinedAt                   *time.Time  `json:"joined_at"`
+	PremiumSince               *time.Time  `json:"premium_since,omitempty"`
+	Deaf                       bool        `json:"deaf,omitempty"`
+	Mute                       bool        `json:"mute,omitempty"`
+	Pending                    bool        `json:"pending,omitempty"`
+	CommunicationDisabledUntil *time.Time  `json:"communication_disabled_until,omitempty"`
+	Flags                      int         `json:"flags,omitempty"`
+}
+
+// GuildMembersChunkEvent is the payload of the GuildMembersChunk dispatch event
+type GuildMembersChunkEvent struct {
+	GuildID    Snowflake             `json:"guild_id"`
+	Members    []GuildMember         `json:"members"`
+	ChunkIndex int                   `json:"chunk_index"`
+	ChunkCount int                   `json:"chunk_count"`
+	NotFound   encoding.RawMessage   `json:"not_found,omitempty"`
+	Presences  []PresenceUpdateEvent `json:"presences,omitempty"`
+	Nonce      string                `json:"nonce,omitempty"`
+}
+
+// GuildRoleCreateEvent is the payload of the GuildRoleCreate dispatch event
+type GuildRoleCreateEvent struct {
+	GuildID Snowflake `json:"guild_id"`
+	Role    Role      `json:"role"`
+}
+
+// GuildRoleDeleteEvent is the payload of the GuildRoleDelete dispatch event
+type GuildRoleDeleteEvent struct {
+	GuildID Snowflake `json:"guild_id"`
+	RoleID  Snowflake `json:"role_id"`
+}
+
+// GuildRoleUpdateEvent is the payload of the GuildRoleUpdate dispatch event
+type GuildRoleUpdateEvent struct {
+	GuildID Snowflake `json:"guild_id"`
+	Role    Role      `json:"role"`
+}
+
+// GuildScheduledEventCreateEvent is the payload of the GuildScheduledEventCreate dispatch event
+type GuildScheduledEventCreateEvent struct {
+	GuildScheduledEvent
+}
+
+// GuildScheduledEventDeleteEvent is the payload of the GuildScheduledEventDelete dispatch event
+type GuildScheduledEventDeleteEvent struct {
+	GuildScheduledEvent
+}
+
+// GuildScheduledEventUpdateEvent is the payload of the GuildScheduledEventUpdate dispatch event
+type GuildScheduledEventUpdateEvent struct {
+	GuildScheduledEvent
+}
+
+// GuildScheduledEventUserAddEvent is the payload of the GuildScheduledEventUserAdd dispatch event
+type GuildScheduledEventUserAddEvent struct {
+	GuildScheduledEventID Snowflake `json:"guild_scheduled_event_id"`
+	UserID                Snowflake `json:"user_id"`
+	GuildID               Snowflake `json:"guild_id"`
+}
+
+// GuildScheduledEventUserRemoveEvent is the payload of the GuildScheduledEventUserRemove dispatch event
+type GuildScheduledEventUserRemoveEvent struct {
+	GuildScheduledEventID Snowflake `json:"guild_scheduled_event_id"`
+	UserID                Snowflake `json:"user_id"`
+	GuildID               Snowflake `json:"guild_id"`
+}
+
+// GuildStickersUpdateEvent is the payload of the GuildStickersUpdate dispatch event
+type GuildStickersUpdateEvent struct {
+	GuildID  Snowflake `json:"guild_id"`
+	Stickers []Sticker `json:"stickers"`
+}
+
+// GuildUpdateEvent is the payload of the GuildUpdate dispatch event
+type GuildUpdateEvent struct {
+	Guild
+}
+
+// IntegrationCreateEvent is the payload of the IntegrationCreate dispatch event
+type IntegrationCreateEvent struct {
+	Integration
+	GuildID Snowflake `json:"guild_id"`
+}
+
+// IntegrationDeleteEvent is the payload of the IntegrationDelete dispatch event
+type IntegrationDeleteEvent struct {
+	ID            Snowflake `json:"id"`
+	GuildID       Snowflake `json:"guild_id"`
+	ApplicationID Snowflake `json:"application_id,omitempty"`
+}
+
+// IntegrationUpdateEvent is the payload of the IntegrationUpdate dispatch event
+type IntegrationUpdateEvent struct {
+	Integration
+	GuildID Snowflake `json:"guild_id"`
+}
+
+// InteractionCreateEvent is the payload of the InteractionCreate dispatch event
+type InteractionCreateEvent struct {
+	Interaction
+}
+
+// InviteCreateEvent is the payload of the InviteCreate dispatch event
+type InviteCreateEvent struct {
+	ChannelID         Snowflake    `json:"channel_id"`
+	Code              string       `json:"code"`
+	CreatedAt         time.Time    `json:"created_at"`
+	GuildID           Snowflake    `json:"guild_id,omitempty"`
+	Inviter           *User        `json:"inviter,omitempty"`
+	MaxAge            int          `json:"max_age"`
+	MaxUses           int          `json:"max_uses"`
+	TargetType        int          `json:"target_type,omitempty"`
+	TargetUser        *User        `json:"target_user,omitempty"`
+	TargetApplication *Application `json:"target_application,omitempty"`
+	Temporary         bool         `json:"temporary"`
+	Uses              int          `json:"uses"`
+}
+
+// InviteDeleteEvent is the payload of the InviteDelete dispatch event
+type InviteDeleteEvent struct {
+	ChannelID Snowflake `json:"channel_id"`
+	GuildID   Snowflake `json:"guild_id,omitempty"`
+	Code      string    `json:"code"`
+}
+
+// MessageCreateEvent is the payload of the MessageCreate dispatch event
+type MessageCreateEvent struct {
+	Message
+	GuildID  Snowflake    `json:"guild_id,omitempty"`
+	Member   *GuildMember `json:"member,omitempty"`
+	Mentions []User       `json:"mentions"`
+}
+
+// MessageDeleteBulkEvent is the payload of the MessageDeleteBulk dispatch event
+type MessageDeleteBulkEvent struct {
+	IDs       []Snowflake `json:"ids"`
+	ChannelID Snowflake   `json:"channel_id"`
+	GuildID   Snowflake   `json:"guild_id,omitempty"`
+}
+
+// MessageDeleteEvent is the payload of the MessageDelete dispatch event
+type MessageDeleteEvent struct {
+	ID        Snowflake `json:"id"`
+	ChannelID Snowflake `json:"channel_id"`
+	GuildID   Snowflake `json:"guild_id,omitempty"`
+}
+
+// MessageReactionAddEvent is the payload of the MessageReactionAdd dispatch event
+type MessageReactionAddEvent struct {
+	UserID    Snowflake    `json:"user_id"`
+	ChannelID Snowflake    `json:"channel_id"`
+	MessageID Snowflake    `json:"message_id"`
+	GuildID   Snowflake    `json:"guild_id,omitempty"`
+	Member    *GuildMember `json:"member,omitempty"`
+	Emoji     Emoji        `json:"emoji"`
+}
+
+// MessageReactionRemoveAllEvent is the payload of the MessageReactionRemoveAll dispatch event
+type MessageReactionRemoveAllEvent struct {
+	ChannelID Snowflake `json:"channel_id"`
+	MessageID Snowflake `json:"message_id"`
+	GuildID   Snowflake `json:"guild_id,omitempty"`
+}
+
+// MessageReactionRemoveEmojiEvent is the payload of the MessageReactionRemoveEmoji dispatch event
+type MessageReactionRemoveEmojiEvent struct {
+	ChannelID Snowflake `json:"channel_id"`
+	GuildID   Snowflake `json:"guild_id,omitempty"`
+	MessageID Snowflake `json:"message_id"`
+	Emoji     Emoji     `json:"emoji"`
+}
+
+// MessageReactionRemoveEvent is the payload of the MessageReactionRemove dispatch event
+type MessageReactionRemoveEvent struct {
+	UserID    Snowflake `json:"user_id"`
+	ChannelID Snowflake `json:"channel_id"`
+	MessageID Snowflake `json:"message_id"`
+	GuildID   Snowflake `json:"guild_id,omitempty"`
+	Emoji     Emoji     `json:"emoji"`
+}
+
+// MessageUpdateEvent is the payload of the MessageUpdate dispatch event
+type MessageUpdateEvent struct {
+	Message
+}
+
+// PresenceUpdateEvent is the payload of the PresenceUpdate dispatch event
+type PresenceUpdateEvent struct {
+	User         User         `json:"user"`
+	GuildID      Snowflake    `json:"guild_id"`
+	Status       string       `json:"status"`
+	Activities   []Activity   `json:"activities"`
+	ClientStatus ClientStatus `json:"client_status"`
+}
+
+// ReadyEvent is the payload of the Ready dispatch event
+type ReadyEvent struct {
+	V                int                `json:"v"`
+	User             User               `json:"user"`
+	Guilds           []UnavailableGuild `json:"guilds"`
+	SessionID        string             `json:"session_id"`
+	ResumeGatewayURL string             `json:"resume_gateway_url"`
+	Shard            [2]int             `json:"shard,omitempty"`
+	Application      Application        `json:"application"`
+}
+
+// ResumedEvent is the payload of the Resumed dispatch event
+type ResumedEvent struct {
+}
+
+// StageInstanceCreateEvent is the payload of the StageInstanceCreate dispatch event
+type StageInstanceCreateEvent struct {
+	StageInstance
+}
+
+// StageInstanceDeleteEvent is the payload of the StageInstanceDelete dispatch event
+type StageInstanceDeleteEvent struct {
+	StageInstance
+}
+
+// StageInstanceUpdateEvent is the payload of the StageInstanceUpdate dispatch event
+type StageInstanceUpdateEvent struct {
+	StageInstance
+}
+
+// ThreadCreateEvent is the payload of the ThreadCreate dispatch event
+type ThreadCreateEvent struct {
+	Channel
+}
+
+// ThreadDeleteEvent is the payload of the ThreadDelete dispatch event
+type ThreadDeleteEvent struct {
+	Channel
+}
+
+// ThreadListSyncEvent is the payload of the ThreadListSync dispatch event
+type ThreadListSyncEvent struct {
+	GuildID    Snowflake      `json:"guild_id"`
+	ChannelIDs []Snowflake    `json:"channel_ids,omitempty"`
+	Threads    []Channel      `json:"threads"`
+	Members    []ThreadMember `json:"members"`
+}
+
+// ThreadMemberUpdateEvent is the payload of the ThreadMemberUpdate dispatch event
+type ThreadMemberUpdateEvent struct {
+	ThreadMember
+	GuildID Snowflake `json:"guild_id"`
+}
+
+// ThreadMembersUpdateEvent is the payload of the ThreadMembersUpdate dispatch event
+type ThreadMembersUpdateEvent struct {
+	ID               Snowflake      `json:"id"`
+	GuildID          Snowflake      `json:"guild_id"`
+	MemberCount      int            `json:"member_count"`
+	AddedMembers     []ThreadMember `json:"added_members,omitempty"`
+	RemovedMemberIDs []Snowflake    `json:"removed_member_ids,omitempty"`
+}
+
+// ThreadUpdateEvent is the payload of the ThreadUpdate dispatch event
+type ThreadUpdateEvent struct {
+	Channel
+}
+
+// TypingStartEvent is the payload of the TypingStart dispatch event
+type TypingStartEvent struct {
+	ChannelID Snowflake    `json:"channel_id"`
+	GuildID   Snowflake    `json:"guild_id,omitempty"`
+	UserID    Snowflake    `json:"user_id"`
+	Timestamp int          `json:"timestamp"`
+	Member    *GuildMember `json:"member,omitempty"`
+}
+
+// UserUpdateEvent is the payload of the UserUpdate dispatch event
+type UserUpdateEvent struct {
+	User
+}
+
+// VoiceServerUpdateEvent is the payload of the VoiceServerUpdate dispatch event
+type VoiceServerUpdateEvent struct {
+	Token    string    `json:"token"`
+	GuildID  Snowflake `json:"guild_id"`
+	Endpoint *string   `json:"endpoint"`
+}
+
+// VoiceStateUpdateEvent is the payload of the VoiceStateUpdate dispatch event
+type VoiceStateUpdateEvent struct {
+	VoiceState
+}
+
+// WebhooksUpdateEvent is the payload of the WebhooksUpdate dispatch event
+type WebhooksUpdateEvent struct {
+	GuildID   Snowflake `json:"guild_id"`
+	ChannelID Snowflake `json:"channel_id"`
+}
+
+// ActionMetadata is generated from the "Action Metadata" table
+type ActionMetadata struct {
+	ChannelID       Snowflake `json:"channel_id"`
+	DurationSeconds int       `json:"duration_seconds"`
+	CustomMessage   string    `json:"custom_message,omitempty"`
+}
+
+// Activity is generated from the "Activity Structure" table
+type Activity struct {
+	Name          string              `json:"name"`
+	Type          int                 `json:"type"`
+	URL           *string             `json:"url,omitempty"`
+	CreatedAt     int                 `json:"created_at"`
+	Timestamps    *ActivityTimestamps `json:"timestamps,omitempty"`
+	ApplicationID Snowflake           `json:"application_id,omitempty"`
+	Details       *string             `json:"details,omitempty"`
+	State         *string             `json:"state,omitempty"`
+	Emoji         *ActivityEmoji      `json:"emoji,omitempty"`
+	Party         *ActivityParty      `json:"party,omitempty"`
+	Assets        *ActivityAssets     `json:"assets,omitempty"`
+	Secrets       *ActivitySecrets    `json:"secrets,omitempty"`
+	Instance      bool                `json:"instance,omitempty"`
+	Flags         int                 `json:"flags,omitempty"`
+	Buttons       []ActivityButtons   `json:"buttons,omitempty"`
+}
+
+// ActivityAssets is generated from the "Activity Assets" table
+type ActivityAssets struct {
+	LargeImage string `json:"large_image,omitempty"`
+	LargeText  string `json:"large_text,omitempty"`
+	SmallImage string `json:"small_image,omitempty"`
+	SmallText  string `json:"small_text,omitempty"`
+}
+
+// ActivityButtons is generated from the "Activity Buttons" table
+type ActivityButtons struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// ActivityEmoji is generated from the "Activity Emoji" table
+type ActivityEmoji struct {
+	Name     string    `json:"name"`
+	ID       Snowflake `json:"id,omitempty"`
+	Animated bool      `json:"animated,omitempty"`
+}
+
+// ActivityParty is generated from the "Activity Party" table
+type ActivityParty struct {
+	ID   string `json:"id,omitempty"`
+	Size [2]int `json:"size,omitempty"`
+}
+
+// ActivitySecrets is generated from the "Activity Secrets" table
+type ActivitySecrets struct {
+	Join     string `json:"join,omitempty"`
+	Spectate string `json:"spectate,omitempty"`
+	Match    string `json:"match,omitempty"`
+}
+
+// ActivityTimestamps is generated from the "Activity Timestamps" table
+type ActivityTimestamps struct {
+	Start int `json:"start,omitempty"`
+	End   int `json:"end,omitempty"`
+}
+
+// Application is generated from the "Application Structure" table
+type Application struct {
+	ID                             Snowflake           `json:"id"`
+	Name                           string              `json:"name"`
+	Icon                           *string             `json:"icon"`
+	Description                    string              `json:"description"`
+	RpcOrigins                     []string            `json:"rpc_origins,omitempty"`
+	BotPublic                      bool                `json:"bot_public"`
+	BotRequireCodeGrant            bool                `json:"bot_require_code_grant"`
+	TermsOfServiceURL              string              `json:"terms_of_service_url,omitempty"`
+	PrivacyPolicyURL               string              `json:"privacy_policy_url,omitempty"`
+	Owner                          *User               `json:"owner,omitempty"`
+	VerifyKey                      string              `json:"verify_key"`
+	Team                           encoding.RawMessage `json:"team"`
+	GuildID                        Snowflake           `json:"guild_id,omitempty"`
+	PrimarySkuID                   Snowflake           `json:"primary_sku_id,omitempty"`
+	Slug                           string              `json:"slug,omitempty"`
+	CoverImage                     string              `json:"cover_image,omitempty"`
+	Flags                          int                 `json:"flags,omitempty"`
+	Tags                           []string            `json:"tags,omitempty"`
+	InstallParams                  *InstallParams      `json:"install_params,omitempty"`
+	CustomInstallURL               string              `json:"custom_install_url,omitempty"`
+	RoleConnectionsVerificationURL string              `json:"role_connections_verification_url,omitempty"`
+}
+
+// ApplicationCommandPermissions is generated from the "Application Command Permissions Structure" table
+type ApplicationCommandPermissions struct {
+	ID         Snowflake `json:"id"`
+	Type       int       `json:"type"`
+	Permission bool      `json:"permission"`
+}
+
+// Attachment is generated from the "Attachment Structure" table
+type Attachment struct {
+	ID           Snowflake `json:"id"`
+	Filename     string    `json:"filename"`
+	Description  string    `json:"description,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	Size         int       `json:"size"`
+	URL          string    `json:"url"`
+	ProxyURL     string    `json:"proxy_url"`
+	Height       *int      `json:"height,omitempty"`
+	Width        *int      `json:"width,omitempty"`
+	Ephemeral    bool      `json:"ephemeral,omitempty"`
+	DurationSecs float64   `json:"duration_secs,omitempty"`
+	Waveform     string    `json:"waveform,omitempty"`
+	Flags        int       `json:"flags,omitempty"`
+}
+
+// AuditLogChange is generated from the "Audit Log Change Structure" table
+type AuditLogChange struct {
+	NewValue encoding.RawMessage `json:"new_value,omitempty"`
+	OldValue encoding.RawMessage `json:"old_value,omitempty"`
+	Key      string              `json:"key"`
+}
+
+// AuditLogEntry is generated from the "Audit Log Entry Structure" table
+type AuditLogEntry struct {
+	TargetID   *string                 `json:"target_id"`
+	Changes    []AuditLogChange        `json:"changes,omitempty"`
+	UserID     *Snowflake              `json:"user_id"`
+	ID         Snowflake               `json:"id"`
+	ActionType int                     `json:"action_type"`
+	Options    *OptionalAuditEntryInfo `json:"options,omitempty"`
+	Reason     string                  `json:"reason,omitempty"`
+}
+
+// AutoModerationAction is generated from the "Auto Moderation Action Structure" table
+type AutoModerationAction struct {
+	Type     int             `json:"type"`
+	Metadata *ActionMetadata `json:"metadata,omitempty"`
+}
+
+// AutoModerationRule is generated from the "Auto Moderation Rule Structure" table
+type AutoModerationRule struct {
+	ID              Snowflake              `json:"id"`
+	GuildID         Snowflake              `json:"guild_id"`
+	Name            string                 `json:"name"`
+	CreatorID       Snowflake              `json:"creator_id"`
+	EventType       int                    `json:"event_type"`
+	TriggerType     int                    `json:"trigger_type"`
+	TriggerMetadata TriggerMetadata        `json:"trigger_metadata"`
+	Actions         []AutoModerationAction `json:"actions"`
+	Enabled         bool                   `json:"enabled"`
+	ExemptRoles     []Snowflake            `json:"exempt_roles"`
+	ExemptChannels  []Snowflake            `json:"exempt_channels"`
+}
+
+// Channel is generated from the "Channel Structure" table
+type Channel struct {
+	ID                            Snowflake        `json:"id"`
+	Type                          int              `json:"type"`
+	GuildID                       Snowflake        `json:"guild_id,omitempty"`
+	Position                      int              `json:"position,omitempty"`
+	PermissionOverwrites          []Overwrite      `json:"permission_overwrites,omitempty"`
+	Name                          *string          `json:"name,omitempty"`
+	Topic                         *string          `json:"topic,omitempty"`
+	NSFW                          bool             `json:"nsfw,omitempty"`
+	LastMessageID                 *Snowflake       `json:"last_message_id,omitempty"`
+	Bitrate                       int              `json:"bitrate,omitempty"`
+	UserLimit                     int              `json:"user_limit,omitempty"`
+	RateLimitPerUser              int              `json:"rate_limit_per_user,omitempty"`
+	Recipients                    []User           `json:"recipients,omitempty"`
+	Icon                          *string          `json:"icon,omitempty"`
+	OwnerID                       Snowflake        `json:"owner_id,omitempty"`
+	ApplicationID                 Snowflake        `json:"application_id,omitempty"`
+	Managed                       bool             `json:"managed,omitempty"`
+	ParentID                      *Snowflake       `json:"parent_id,omitempty"`
+	LastPinTimestamp              *time.Time       `json:"last_pin_timestamp,omitempty"`
+	RTCRegion                     *string          `json:"rtc_region,omitempty"`
+	VideoQualityMode              int              `json:"video_quality_mode,omitempty"`
+	MessageCount                  int              `json:"message_count,omitempty"`
+	MemberCount                   int              `json:"member_count,omitempty"`
+	ThreadMetadata                *ThreadMetadata  `json:"thread_metadata,omitempty"`
+	Member                        *ThreadMember    `json:"member,omitempty"`
+	DefaultAutoArchiveDuration    int              `json:"default_auto_archive_duration,omitempty"`
+	Permissions                   string           `json:"permissions,omitempty"`
+	Flags                         int              `json:"flags,omitempty"`
+	TotalMessageSent              int              `json:"total_message_sent,omitempty"`
+	AvailableTags                 []ForumTag       `json:"available_tags,omitempty"`
+	AppliedTags                   []Snowflake      `json:"applied_tags,omitempty"`
+	DefaultReactionEmoji          *DefaultReaction `json:"default_reaction_emoji,omitempty"`
+	DefaultThreadRateLimitPerUser int              `json:"default_thread_rate_limit_per_user,omitempty"`
+	DefaultSortOrder              *int             `json:"default_sort_order,omitempty"`
+	DefaultForumLayout            int              `json:"default_forum_layout,omitempty"`
+}
+
+// ChannelMention is generated from the "Channel Mention Structure" table
+type ChannelMention struct {
+	ID      Snowflake `json:"id"`
+	GuildID Snowflake `json:"guild_id"`
+	Type    int       `json:"type"`
+	Name    string    `json:"name"`
+}
+
+// ClientStatus is generated from the "Client Status Object" table
+type ClientStatus struct {
+	Desktop string `json:"desktop,omitempty"`
+	Mobile  string `json:"mobile,omitempty"`
+	Web     string `json:"web,omitempty"`
+}
+
+// DefaultReaction is generated from the "Default Reaction Structure" table
+type DefaultReaction struct {
+	EmojiID   *Snowflake `json:"emoji_id"`
+	EmojiName *string    `json:"emoji_name"`
+}
+
+// Embed is generated from the "Embed Structure" table
+type Embed struct {
+	Title       string          `json:"title,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	Description string          `json:"description,omitempty"`
+	URL         string          `json:"url,omitempty"`
+	Timestamp   *time.Time      `json:"timestamp,omitempty"`
+	Color       int             `json:"color,omitempty"`
+	Footer      *EmbedFooter    `json:"footer,omitempty"`
+	Image       *EmbedImage     `json:"image,omitempty"`
+	Thumbnail   *EmbedThumbnail `json:"thumbnail,omitempty"`
+	Video       *EmbedVideo     `json:"video,omitempty"`
+	Provider    *EmbedProvider  `json:"provider,omitempty"`
+	Author      *EmbedAuthor    `json:"author,omitempty"`
+	Fields      []EmbedField    `json:"fields,omitempty"`
+}
+
+// EmbedAuthor is generated from the "Embed Author Structure" table
+type EmbedAuthor struct {
+	Name         string `json:"name"`
+	URL          string `json:"url,omitempty"`
+	IconURL      string `json:"icon_url,omitempty"`
+	ProxyIconURL string `json:"proxy_icon_url,omitempty"`
+}
+
+// EmbedField is generated from the "Embed Field Structure" table
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// EmbedFooter is generated from the "Embed Footer Structure" table
+type EmbedFooter struct {
+	Text         string `json:"text"`
+	IconURL      string `json:"icon_url,omitempty"`
+	ProxyIconURL string `json:"proxy_icon_url,omitempty"`
+}
+
+// EmbedImage is generated from the "Embed Image Structure" table
+type EmbedImage struct {
+	URL      string `json:"url"`
+	ProxyURL string `json:"proxy_url,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Width    int    `json:"width,omitempty"`
+}
+
+// EmbedProvider is generated from the "Embed Provider Structure" table
+type EmbedProvider struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// EmbedThumbnail is generated from the "Embed Thumbnail Structure" table
+type EmbedThumbnail struct {
+	URL      string `json:"url"`
+	ProxyURL string `json:"proxy_url,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Width    int    `json:"width,omitempty"`
+}
+
+// EmbedVideo is generated from the "Embed Video Structure" table
+type EmbedVideo struct {
+	URL      string `json:"url,omitempty"`
+	ProxyURL string `json:"proxy_url,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Width    int    `json:"width,omitempty"`
+}
+
+// Emoji is generated from the "Emoji Structure" table
+type Emoji struct {
+	ID            *Snowflake  `json:"id"`
+	Name          *string     `json:"name"`
+	Roles         []Snowflake `json:"roles,omitempty"`
+	User          *User       `json:"user,omitempty"`
+	RequireColons bool        `json:"require_colons,omitempty"`
+	Managed       bool        `json:"managed,omitempty"`
+	Animated      bool        `json:"animated,omitempty"`
+	Available     bool        `json:"available,omitempty"`
+}
+
+// ForumTag is generated from the "Forum Tag Structure" table
+type ForumTag struct {
+	ID        Snowflake  `json:"id"`
+	Name      string     `json:"name"`
+	Moderated bool       `json:"moderated"`
+	EmojiID   *Snowflake `json:"emoji_id"`
+	EmojiName *string    `json:"emoji_name"`
+}
+
+// Guild is generated from the "Guild Structure" table
+type Guild struct {
+	ID                          Snowflake      `json:"id"`
+	Name                        string         `json:"name"`
+	Icon                        *string        `json:"icon"`
+	IconHash                    *string        `json:"icon_hash,omitempty"`
+	Splash                      *string        `json:"splash"`
+	DiscoverySplash             *string        `json:"discovery_splash"`
+	Owner                       bool           `json:"owner,omitempty"`
+	OwnerID                     Snowflake      `json:"owner_id"`
+	Permissions                 string         `json:"permissions,omitempty"`
+	Region                      *string        `json:"region,omitempty"`
+	AfkChannelID                *Snowflake     `json:"afk_channel_id"`
+	AfkTimeout                  int            `json:"afk_timeout"`
+	WidgetEnabled               bool           `json:"widget_enabled,omitempty"`
+	WidgetChannelID             *Snowflake     `json:"widget_channel_id,omitempty"`
+	VerificationLevel           int            `json:"verification_level"`
+	DefaultMessageNotifications int            `json:"default_message_notifications"`
+	ExplicitContentFilter       int            `json:"explicit_content_filter"`
+	Roles                       []Role         `json:"roles"`
+	Emojis                      []Emoji        `json:"emojis"`
+	Features                    []string       `json:"features"`
+	MFALevel                    int            `json:"mfa_level"`
+	ApplicationID               *Snowflake     `json:"application_id"`
+	SystemChannelID             *Snowflake     `json:"system_channel_id"`
+	SystemChannelFlags          int            `json:"system_channel_flags"`
+	RulesChannelID              *Snowflake     `json:"rules_channel_id"`
+	MaxPresences                *int           `json:"max_presences,omitempty"`
+	MaxMembers                  int            `json:"max_members,omitempty"`
+	VanityURLCode               *string        `json:"vanity_url_code"`
+	Description                 *string        `json:"description"`
+	Banner                      *string        `json:"banner"`
+	PremiumTier                 int            `json:"premium_tier"`
+	PremiumSubscriptionCount    int            `json:"premium_subscription_count,omitempty"`
+	PreferredLocale             string         `json:"preferred_locale"`
+	PublicUpdatesChannelID      *Snowflake     `json:"public_updates_channel_id"`
+	MaxVideoChannelUsers        int            `json:"max_video_channel_users,omitempty"`
+	MaxStageVideoChannelUsers   int            `json:"max_stage_video_channel_users,omitempty"`
+	ApproximateMemberCount      int            `json:"approximate_member_count,omitempty"`
+	ApproximatePresenceCount    int            `json:"approximate_presence_count,omitempty"`
+	WelcomeScreen               *WelcomeScreen `json:"welcome_screen,omitempty"`
+	NSFWLevel                   int            `json:"nsfw_level"`
+	Stickers                    []Sticker      `json:"stickers,omitempty"`
+	PremiumProgressBarEnabled   bool           `json:"premium_progress_bar_enabled"`
+	SafetyAlertsChannelID       *Snowflake     `json:"safety_alerts_channel_id"`
+}
+
+// GuildApplicationCommandPermissions is generated from the "Guild Application Command Permissions Structure" table
+type GuildApplicationCommandPermissions struct {
+	ID            Snowflake                       `json:"id"`
+	ApplicationID Snowflake                       `json:"application_id"`
+	GuildID       Snowflake                       `json:"guild_id"`
+	Permissions   []ApplicationCommandPermissions `json:"permissions"`
+}
+
+// GuildMember is generated from the "Guild Member Structure" table
+type GuildMember struct {
+	User                       *User       `json:"user,omitempty"`
+	Nick                       *string     `json:"nick,omitempty"`
+	Avatar                     *string     `json:"avatar,omitempty"`
+	Roles                      []Snowflake `json:"roles"`
+	JoinedAt                   time.Time   `json:"joined_at"`
+	PremiumSince               *time.Time  `json:"premium_since,omitempty"`
+	Deaf                       bool        `json:"deaf"`
+	Mute                       bool        `json:"mute"`
+	Flags                      int         `json:"flags"`
+	Pending                    bool        `json:"pending,omitempty"`
+	Permissions                string      `json:"permissions,omitempty"`
+	CommunicationDisabledUntil *time.Time  `json:"communication_disabled_until,omitempty"`
+}
+
+// GuildScheduledEvent is generated from the "Guild Scheduled Event Structure" table
+type GuildScheduledEvent struct {
+	ID                 Snowflake                          `json:"id"`
+	GuildID            Snowflake                          `json:"guild_id"`
+	ChannelID          *Snowflake                         `json:"channel_id"`
+	CreatorID          *Snowflake                         `json:"creator_id,omitempty"`
+	Name               string                             `json:"name"`
+	Description        *string                            `json:"description,omitempty"`
+	ScheduledStartTime time.Time                          `json:"scheduled_start_time"`
+	ScheduledEndTime   *time.Time                         `json:"scheduled_end_time"`
+	PrivacyLevel       int                                `json:"privacy_level"`
+	Status             int                                `json:"status"`
+	EntityType         int                                `json:"entity_type"`
+	EntityID           *Snowflake                         `json:"entity_id"`
+	EntityMetadata     *GuildScheduledEventEntityMetadata `json:"entity_metadata"`
+	Creator            *User                              `json:"creator,omitempty"`
+	UserCount          int                                `json:"user_count,omitempty"`
+	Image              *string                            `json:"image,omitempty"`
+}
+
+// GuildScheduledEventEntityMetadata is generated from the "Guild Scheduled Event Entity Metadata" table
+type GuildScheduledEventEntityMetadata struct {
+	Location string `json:"location,omitempty"`
+}
+
+// InstallParams is generated from the "Install Params Structure" table
+type InstallParams struct {
+	Scopes      []string `json:"scopes"`
+	Permissions string   `json:"permissions"`
+}
+
+// Integration is generated from the "Integration Structure" table
+type Integration struct {
+	ID                Snowflake               `json:"id"`
+	Name              string                  `json:"name"`
+	Type              string                  `json:"type"`
+	Enabled           bool                    `json:"enabled"`
+	Syncing           bool                    `json:"syncing,omitempty"`
+	RoleID            Snowflake               `json:"role_id,omitempty"`
+	EnableEmoticons   bool                    `json:"enable_emoticons,omitempty"`
+	ExpireBehavior    int                     `json:"expire_behavior,omitempty"`
+	ExpireGracePeriod int                     `json:"expire_grace_period,omitempty"`
+	User              *User                   `json:"user,omitempty"`
+	Account           IntegrationAccount      `json:"account"`
+	SyncedAt          *time.Time              `json:"synced_at,omitempty"`
+	SubscriberCount   int                     `json:"subscriber_count,omitempty"`
+	Revoked           bool                    `json:"revoked,omitempty"`
+	Application       *IntegrationApplication `json:"application,omitempty"`
+	Scopes            []string                `json:"scopes,omitempty"`
+}
+
+// IntegrationAccount is generated from the "Integration Account Structure" table
+type IntegrationAccount struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// IntegrationApplication is generated from the "Integration Application Structure" table
+type IntegrationApplication struct {
+	ID          Snowflake `json:"id"`
+	Name        string    `json:"name"`
+	Icon        *string   `json:"icon"`
+	Description string    `json:"description"`
+	Bot         *User     `json:"bot,omitempty"`
+}
+
+// Interaction is generated from the "Interaction Structure" table
+type Interaction struct {
+	ID             Snowflake           `json:"id"`
+	ApplicationID  Snowflake           `json:"application_id"`
+	Type           int                 `json:"type"`
+	Data           encoding.RawMessage `json:"data,omitempty"`
+	GuildID        Snowflake           `json:"guild_id,omitempty"`
+	Channel        *Channel            `json:"channel,omitempty"`
+	ChannelID      Snowflake           `json:"channel_id,omitempty"`
+	Member         *GuildMember        `json:"member,omitempty"`
+	User           *User               `json:"user,omitempty"`
+	Token          string              `json:"token"`
+	Version        int                 `json:"version"`
+	Message        *Message            `json:"message,omitempty"`
+	AppPermissions string              `json:"app_permissions,omitempty"`
+	Locale         string              `json:"locale,omitempty"`
+	GuildLocale    string              `json:"guild_locale,omitempty"`
+}
+
+// Message is generated from the "Message Structure" table
+type Message struct {
+	ID                   Snowflake             `json:"id"`
+	ChannelID            Snowflake             `json:"channel_id"`
+	Author               User                  `json:"author"`
+	Content              string                `json:"content"`
+	Timestamp            time.Time             `json:"timestamp"`
+	EditedTimestamp      *time.Time            `json:"edited_timestamp"`
+	TTS                  bool                  `json:"tts"`
+	MentionEveryone      bool                  `json:"mention_everyone"`
+	Mentions             []User                `json:"mentions"`
+	MentionRoles         []Snowflake           `json:"mention_roles"`
+	MentionChannels      []ChannelMention      `json:"mention_channels,omitempty"`
+	Attachments          []Attachment          `json:"attachments"`
+	Embeds               []Embed               `json:"embeds"`
+	Reactions            []Reaction            `json:"reactions,omitempty"`
+	Nonce                encoding.RawMessage   `json:"nonce,omitempty"`
+	Pinned               bool                  `json:"pinned"`
+	WebhookID            Snowflake             `json:"webhook_id,omitempty"`
+	Type                 int                   `json:"type"`
+	Activity             *MessageActivity      `json:"activity,omitempty"`
+	Application          *Application          `json:"application,omitempty"`
+	ApplicationID        Snowflake             `json:"application_id,omitempty"`
+	MessageReference     *MessageReference     `json:"message_reference,omitempty"`
+	Flags                int                   `json:"flags,omitempty"`
+	ReferencedMessage    *Message              `json:"referenced_message,omitempty"`
+	Interaction          *MessageInteraction   `json:"interaction,omitempty"`
+	Thread               *Channel              `json:"thread,omitempty"`
+	Components           []encoding.RawMessage `json:"components,omitempty"`
+	StickerItems         []StickerItem         `json:"sticker_items,omitempty"`
+	Stickers             []Sticker             `json:"stickers,omitempty"`
+	Position             int                   `json:"position,omitempty"`
+	RoleSubscriptionData *RoleSubscriptionData `json:"role_subscription_data,omitempty"`
+}
+
+// MessageActivity is generated from the "Message Activity Structure" table
+type MessageActivity struct {
+	Type    int    `json:"type"`
+	PartyID string `json:"party_id,omitempty"`
+}
+
+// MessageInteraction is generated from the "Message Interaction Structure" table
+type MessageInteraction struct {
+	ID     Snowflake    `json:"id"`
+	Type   int          `json:"type"`
+	Name   string       `json:"name"`
+	User   User         `json:"user"`
+	Member *GuildMember `json:"member,omitempty"`
+}
+
+// MessageReference is generated from the "Message Reference Structure" table
+type MessageReference struct {
+	MessageID       Snowflake `json:"message_id,omitempty"`
+	ChannelID       Snowflake `json:"channel_id,omitempty"`
+	GuildID         Snowflake `json:"guild_id,omitempty"`
+	FailIfNotExists bool      `json:"fail_if_not_exists,omitempty"`
+}
+
+// OptionalAuditEntryInfo is generated from the "Optional Audit Entry Info" table
+type OptionalAuditEntryInfo struct {
+	ApplicationID                 Snowflake `json:"application_id"`
+	AutoModerationRuleName        string    `json:"auto_moderation_rule_name"`
+	AutoModerationRuleTriggerType string    `json:"auto_moderation_rule_trigger_type"`
+	ChannelID                     Snowflake `json:"channel_id"`
+	Count                         string    `json:"count"`
+	DeleteMemberDays              string    `json:"delete_member_days"`
+	ID                            Snowflake `json:"id"`
+	MembersRemoved                string    `json:"members_removed"`
+	MessageID                     Snowflake `json:"message_id"`
+	RoleName                      string    `json:"role_name"`
+	Type                          string    `json:"type"`
+}
+
+// Overwrite is generated from the "Overwrite Structure" table
+type Overwrite struct {
+	ID    Snowflake `json:"id"`
+	Type  int       `json:"type"`
+	Allow string    `json:"allow"`
+	Deny  string    `json:"deny"`
+}
+
+// Reaction is generated from the "Reaction Structure" table
+type Reaction struct {
+	Count int   `json:"count"`
+	Me    bool  `json:"me"`
+	Emoji Emoji `json:"emoji"`
+}
+
+// Role is generated from the "Role Structure" table
+type Role struct {
+	ID           Snowflake `json:"id"`
+	Name         string    `json:"name"`
+	Color        int       `json:"color"`
+	Hoist        bool      `json:"hoist"`
+	Icon         *string   `json:"icon,omitempty"`
+	UnicodeEmoji *string   `json:"unicode_emoji,omitempty"`
+	Position     int       `json:"position"`
+	Permissions  string    `json:"permissions"`
+	Managed      bool      `json:"managed"`
+	Mentionable  bool      `json:"mentionable"`
+	Tags         *RoleTags `json:"tags,omitempty"`
+	Flags        int       `json:"flags"`
+}
+
+// RoleSubscriptionData is generated from the "Role Subscription Data Object Structure" table
+type RoleSubscriptionData struct {
+	RoleSubscriptionListingID Snowflake `json:"role_subscription_listing_id"`
+	TierName                  string    `json:"tier_name"`
+	TotalMonthsSubscribed     int       `json:"total_months_subscribed"`
+	IsRenewal                 bool      `json:"is_renewal"`
+}
+
+// RoleTags is generated from the "Role Tags Structure" table
+type RoleTags struct {
+	BotID                 Snowflake           `json:"bot_id,omitempty"`
+	IntegrationID         Snowflake           `json:"integration_id,omitempty"`
+	PremiumSubscriber     encoding.RawMessage `json:"premium_subscriber,omitempty"`
+	SubscriptionListingID Snowflake           `json:"subscription_listing_id,omitempty"`
+	AvailableForPurchase  encoding.RawMessage `json:"available_for_purchase,omitempty"`
+	GuildConnections      encoding.RawMessage `json:"guild_connections,omitempty"`
+}
+
+// StageInstance is generated from the "Stage Instance Structure" table
+type StageInstance struct {
+	ID                    Snowflake  `json:"id"`
+	GuildID               Snowflake  `json:"guild_id"`
+	ChannelID             Snowflake  `json:"channel_id"`
+	Topic                 string     `json:"topic"`
+	PrivacyLevel          int        `json:"privacy_level"`
+	DiscoverableDisabled  bool       `json:"discoverable_disabled"`
+	GuildScheduledEventID *Snowflake `json:"guild_scheduled_event_id"`
+}
+
+// Sticker is generated from the "Sticker Structure" table
+type Sticker struct {
+	ID          Snowflake `json:"id"`
+	PackID      Snowflake `json:"pack_id,omitempty"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	Tags        string    `json:"tags"`
+	Asset       string    `json:"asset,omitempty"`
+	Type        int       `json:"type"`
+	FormatType  int       `json:"format_type"`
+	Available   bool      `json:"available,omitempty"`
+	GuildID     Snowflake `json:"guild_id,omitempty"`
+	User        *User     `json:"user,omitempty"`
+	SortValue   int       `json:"sort_value,omitempty"`
+}
+
+// StickerItem is generated from the "Sticker Item Structure" table
+type StickerItem struct {
+	ID         Snowflake `json:"id"`
+	Name       string    `json:"name"`
+	FormatType int       `json:"format_type"`
+}
+
+// ThreadMember is generated from the "Thread Member Structure" table
+type ThreadMember struct {
+	ID            Snowflake    `json:"id,omitempty"`
+	UserID        Snowflake    `json:"user_id,omitempty"`
+	JoinTimestamp time.Time    `json:"join_timestamp"`
+	Flags         int          `json:"flags"`
+	Member        *GuildMember `json:"member,omitempty"`
+}
+
+// ThreadMetadata is generated from the "Thread Metadata Structure" table
+type ThreadMetadata struct {
+	Archived            bool       `json:"archived"`
+	AutoArchiveDuration int        `json:"auto_archive_duration"`
+	ArchiveTimestamp    time.Time  `json:"archive_timestamp"`
+	Locked              bool       `json:"locked"`
+	Invitable           bool       `json:"invitable,omitempty"`
+	CreateTimestamp     *time.Time `json:"create_timestamp,omitempty"`
+}
+
+// TriggerMetadata is generated from the "Trigger Metadata" table
+type TriggerMetadata struct {
+	KeywordFilter                []string `json:"keyword_filter"`
+	RegexPatterns                []string `json:"regex_patterns"`
+	Presets                      []int    `json:"presets"`
+	AllowList                    []string `json:"allow_list"`
+	MentionTotalLimit            int      `json:"mention_total_limit"`
+	MentionRaidProtectionEnabled bool     `json:"mention_raid_protection_enabled"`
+}
+
+// User is generated from the "User Structure" table
+type User struct {
+	ID               Snowflake `json:"id"`
+	Username         string    `json:"username"`
+	Discriminator    string    `json:"discriminator"`
+	GlobalName       *string   `json:"global_name"`
+	Avatar           *string   `json:"avatar"`
+	Bot              bool      `json:"bot,omitempty"`
+	System           bool      `json:"system,omitempty"`
+	MFAEnabled       bool      `json:"mfa_enabled,omitempty"`
+	Banner           *string   `json:"banner,omitempty"`
+	AccentColor      *int      `json:"accent_color,omitempty"`
+	Locale           string    `json:"locale,omitempty"`
+	Verified         bool      `json:"verified,omitempty"`
+	Email            *string   `json:"email,omitempty"`
+	Flags            int       `json:"flags,omitempty"`
+	PremiumType      int       `json:"premium_type,omitempty"`
+	PublicFlags      int       `json:"public_flags,omitempty"`
+	AvatarDecoration *string   `json:"avatar_decoration,omitempty"`
+}
+
+// VoiceState is generated from the "Voice State Structure" table
+type VoiceState struct {
+	GuildID                 Snowflake    `json:"guild_id,omitempty"`
+	ChannelID               *Snowflake   `json:"channel_id"`
+	UserID                  Snowflake    `json:"user_id"`
+	Member                  *GuildMember `json:"member,omitempty"`
+	SessionID               string       `json:"session_id"`
+	Deaf                    bool         `json:"deaf"`
+	Mute                    bool         `json:"mute"`
+	SelfDeaf                bool         `json:"self_deaf"`
+	SelfMute                bool         `json:"self_mute"`
+	SelfStream              bool         `json:"self_stream,omitempty"`
+	SelfVideo               bool         `json:"self_video"`
+	Suppress                bool         `json:"suppress"`
+	RequestToSpeakTimestamp *time.Time   `json:"request_to_speak_timestamp"`
+}
+
+// WelcomeScreen is generated from the "Welcome Screen Structure" table
+type WelcomeScreen struct {
+	Description     *string                `json:"description"`
+	WelcomeChannels []WelcomeScreenChannel `json:"welcome_channels"`
+}
+
+// WelcomeScreenChannel is generated from the "Welcome Screen Channel Structure" table
+type WelcomeScreenChannel struct {
+	ChannelID   Snowflake  `json:"channel_id"`
+	Description string     `json:"description"`
+	EmojiID     *Snowflake `json:"emoji_id"`
+	EmojiName   *string    `json:"emoji_name"`
+}
+
+// NewPayload returns a pointer to a new payload struct of the given dispatch event, or nil when the event has no
+// payload struct.
+func NewPayload(t Type) interface{} {
+	switch t {
+	case ApplicationCommandPermissionsUpdate:
+		return &ApplicationCommandPermissionsUpdateEvent{}
+	case AutoModerationActionExecution:
+		return &AutoModerationActionExecutionEvent{}
+	case AutoModerationRuleCreate:
+		return &AutoModerationRuleCreateEvent{}
+	case AutoModerationRuleDelete:
+		return &AutoModerationRuleDeleteEvent{}
+	case AutoModerationRuleUpdate:
+		return &AutoModerationRuleUpdateEvent{}
+	case ChannelCreate:
+		return &ChannelCreateEvent{}
+	case ChannelDelete:
+		return &ChannelDeleteEvent{}
+	case ChannelPinsUpdate:
+		return &ChannelPinsUpdateEvent{}
+	case ChannelUpdate:
+		return &ChannelUpdateEvent{}
+	case GuildAuditLogEntryCreate:
+		return &GuildAuditLogEntryCreateEvent{}
+	case GuildBanAdd:
+		return &GuildBanAddEvent{}
+	case GuildBanRemove:
+		return &GuildBanRemoveEvent{}
+	case GuildCreate:
+		return &GuildCreateEvent{}
+	case GuildDelete:
+		return &GuildDeleteEvent{}
+	case GuildEmojisUpdate:
+		return &GuildEmojisUpdateEvent{}
+	case GuildIntegrationsUpdate:
+		return &GuildIntegrationsUpdateEvent{}
+	case GuildMemberAdd:
+		return &GuildMemberAddEvent{}
+	case GuildMemberRemove:
+		return &GuildMemberRemoveEvent{}
+	case GuildMemberUpdate:
+		return &GuildMemberUpdateEvent{}
+	case GuildMembersChunk:
+		return &GuildMembersChunkEvent{}
+	case GuildRoleCreate:
+		return &GuildRoleCreateEvent{}
+	case GuildRoleDelete:
+		return &GuildRoleDeleteEvent{}
+	case GuildRoleUpdate:
+		return &GuildRoleUpdateEvent{}
+	case GuildScheduledEventCreate:
+		return &GuildScheduledEventCreateEvent{}
+	case GuildScheduledEventDelete:
+		return &GuildScheduledEventDeleteEvent{}
+	case GuildScheduledEventUpdate:
+		return &GuildScheduledEventUpdateEvent{}
+	case GuildScheduledEventUserAdd:
+		return &GuildScheduledEventUserAddEvent{}
+	case GuildScheduledEventUserRemove:
+		return &GuildScheduledEventUserRemoveEvent{}
+	case GuildStickersUpdate:
+		return &GuildStickersUpdateEvent{}
+	case GuildUpdate:
+		return &GuildUpdateEvent{}
+	case IntegrationCreate:
+		return &IntegrationCreateEvent{}
+	case IntegrationDelete:
+		return &IntegrationDeleteEvent{}
+	case IntegrationUpdate:
+		return &IntegrationUpdateEvent{}
+	case InteractionCreate:
+		return &InteractionCreateEvent{}
+	case InviteCreate:
+		return &InviteCreateEvent{}
+	case InviteDelete:
+		return &InviteDeleteEvent{}
+	case MessageCreate:
+		return &MessageCreateEvent{}
+	case MessageDeleteBulk:
+		return &MessageDeleteBulkEvent{}
+	case MessageDelete:
+		return &MessageDeleteEvent{}
+	case MessageReactionAdd:
+		return &MessageReactionAddEvent{}
+	case MessageReactionRemoveAll:
+		return &MessageReactionRemoveAllEvent{}
+	case MessageReactionRemoveEmoji:
+		return &MessageReactionRemoveEmojiEvent{}
+	case MessageReactionRemove:
+		return &MessageReactionRemoveEvent{}
+	case MessageUpdate:
+		return &MessageUpdateEvent{}
+	case PresenceUpdate:
+		return &PresenceUpdateEvent{}
+	case Ready:
+		return &ReadyEvent{}
+	case Resumed:
+		return &ResumedEvent{}
+	case StageInstanceCreate:
+		return &StageInstanceCreateEvent{}
+	case StageInstanceDelete:
+		return &StageInstanceDeleteEvent{}
+	case StageInstanceUpdate:
+		return &StageInstanceUpdateEvent{}
+	case ThreadCreate:
+		return &ThreadCreateEvent{}
+	case ThreadDelete:
+		return &ThreadDeleteEvent{}
+	case ThreadListSync:
+		return &ThreadListSyncEvent{}
+	case ThreadMemberUpdate:
+		return &ThreadMemberUpdateEvent{}
+	case ThreadMembersUpdate:
+		return &ThreadMembersUpdateEvent{}
+	case ThreadUpdate:
+		return &ThreadUpdateEvent{}
+	case TypingStart:
+		return &TypingStartEvent{}
+	case UserUpdate:
+		return &UserUpdateEvent{}
+	case VoiceServerUpdate:
+		return &VoiceServerUpdateEvent{}
+	case VoiceStateUpdate:
+		return &VoiceStateUpdateEvent{}
+	case WebhooksUpdate:
+		return &WebhooksUpdateEvent{}
+	default:
+		return nil
+	}
+}
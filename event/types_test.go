@@ -0,0 +1,114 @@
+package event
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+)
+
+func TestNewPayload(t *testing.T) {
+	for _, evt := range All() {
+		switch evt {
+		case Hello, InvalidSession, Reconnect, Heartbeat, Identify, RequestGuildMembers, Resume, UpdatePresence, UpdateVoiceState:
+			if NewPayload(evt) != nil {
+				t.Errorf("%s is not a dispatch event and should not have a payload", evt)
+			}
+		default:
+			payload := NewPayload(evt)
+			if payload == nil {
+				t.Errorf("missing payload for %s", evt)
+			} else if evt != Resumed && reflect.TypeOf(payload).Elem().NumField() == 0 {
+				// an empty struct silently decodes the payload into nothing
+				t.Errorf("payload of %s has no fields", evt)
+			}
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	message := map[string]interface{}{
+		"id":         uint64(1112234578634489856),
+		"channel_id": "1112234578634489857",
+		"guild_id":   "81384788765712384",
+		"content":    "hello",
+		"timestamp":  "2023-05-30T16:00:00.000000+00:00",
+		"author":     map[string]interface{}{"id": "80351110224678912", "username": "gopher"},
+		"mentions":   []interface{}{map[string]interface{}{"id": "80351110224678913", "username": "mention"}},
+		"member":     map[string]interface{}{"nick": "nick"},
+		"embeds":     []interface{}{},
+	}
+
+	// ETF sends snowflakes as integers, while JSON sends them as strings
+	etfData, err := etf.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message["id"] = "1112234578634489856"
+	jsonData, err := encoding.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name  string
+		codec encoding.Codec
+		data  []byte
+	}{
+		{"json", encoding.JSONCodec{}, jsonData},
+		{"etf", etf.Codec{}, etfData},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := Decode(tc.codec, MessageCreate, tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			evt, ok := payload.(*MessageCreateEvent)
+			if !ok {
+				t.Fatalf("expected *MessageCreateEvent, got %T", payload)
+			}
+			if evt.ID != "1112234578634489856" {
+				t.Errorf("incorrect id. Got '%s'", evt.ID)
+			}
+			if evt.GuildID != "81384788765712384" {
+				t.Errorf("incorrect guild id. Got '%s'", evt.GuildID)
+			}
+			if evt.Content != "hello" || evt.Author.Username != "gopher" {
+				t.Errorf("incorrect message. Got %+v", evt.Message)
+			}
+			if wants := time.Date(2023, 5, 30, 16, 0, 0, 0, time.UTC); !evt.Timestamp.Equal(wants) {
+				t.Errorf("incorrect timestamp. Got %s", evt.Timestamp)
+			}
+			if len(evt.Mentions) != 1 || evt.Mentions[0].Username != "mention" {
+				t.Errorf("incorrect mentions. Got %+v", evt.Mentions)
+			}
+			if evt.Member == nil || evt.Member.Nick == nil || *evt.Member.Nick != "nick" {
+				t.Errorf("incorrect member. Got %+v", evt.Member)
+			}
+		})
+	}
+
+	t.Run("unknown payload", func(t *testing.T) {
+		if _, err := Decode(encoding.JSONCodec{}, Hello, []byte(`{}`)); !errors.Is(err, ErrUnknownPayload) {
+			t.Errorf("expected ErrUnknownPayload, got %v", err)
+		}
+	})
+}
+
+func TestDecode_EmbeddedObject(t *testing.T) {
+	data := []byte(`{"id":"1","guild_id":"2","channel_id":"3","topic":"stage"}`)
+	payload, err := Decode(encoding.JSONCodec{}, StageInstanceDelete, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt, ok := payload.(*StageInstanceDeleteEvent)
+	if !ok || evt.ID != "1" || evt.Topic != "stage" {
+		t.Errorf("expected the stage instance to be decoded. Got %+v", payload)
+	}
+}
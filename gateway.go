@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/discordpkg/gateway/encoding"
@@ -53,6 +54,24 @@ func (c DiscordError) CanReconnect() bool {
 
 type Handler func(shardID ShardID, evt event.Type, data encoding.RawMessage)
 
+// Event is a dispatch event, as given to a HandlerV2.
+type Event struct {
+	ShardID    ShardID
+	Type       event.Type
+	Seq        int64
+	ReceivedAt time.Time
+	Data       encoding.RawMessage
+}
+
+// HandlerV2 receives dispatch events along with a context, which is derived from the context given to
+// Client.ProcessNextContext, such as the event loop context of a shard, and holds the span of the event when a Tracer
+// is configured. A returned error is logged and reported to the metrics, it does not affect the connection.
+type HandlerV2 func(ctx context.Context, evt *Event) error
+
+// PanicHandler is called with the recovered value and stack trace when an event handler panics, see
+// WithPanicRecovery.
+type PanicHandler func(evt *Event, recovered interface{}, stack []byte)
+
 type IdentifyConnectionProperties struct {
 	OS      string `json:"os"`
 	Browser string `json:"browser"`
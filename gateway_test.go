@@ -1,7 +1,9 @@
 package gateway
 
 import (
+	"github.com/discordpkg/gateway/closecode"
 	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/event/opcode"
 	"testing"
 )
 
@@ -28,3 +30,26 @@ func TestPayload(t *testing.T) {
 		}
 	})
 }
+
+func TestDiscordError_CanReconnect(t *testing.T) {
+	tests := []struct {
+		err       DiscordError
+		reconnect bool
+	}{
+		{DiscordError{OpCode: opcode.Reconnect}, true},
+		{DiscordError{OpCode: opcode.Resume}, true},
+		{DiscordError{OpCode: opcode.InvalidSession}, false},
+		{DiscordError{CloseCode: 1001}, false},
+		{DiscordError{CloseCode: closecode.UnknownError}, true},
+		{DiscordError{CloseCode: closecode.InvalidSeq}, true},
+		{DiscordError{CloseCode: closecode.AuthenticationFailed}, false},
+		{DiscordError{CloseCode: closecode.InvalidShard}, false},
+		{DiscordError{CloseCode: closecode.DisallowedIntents}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.err.CanReconnect(); got != test.reconnect {
+			t.Errorf("%s: expected %t, got %t", test.err.Error(), test.reconnect, got)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package gatewaytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/closecode"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/event/opcode"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+var ErrConnClosed = errors.New("gateway test connection was closed")
+
+// closeTimeout is how long Close waits for the client to acknowledge the close frame
+const closeTimeout = time.Second
+
+// outgoing is the wire format of payloads sent by the server
+type outgoing struct {
+	Op        opcode.Type         `json:"op"`
+	Data      encoding.RawMessage `json:"d"`
+	Seq       int64               `json:"s,omitempty"`
+	EventName event.Type          `json:"t,omitempty"`
+}
+
+// Conn is a client connection to the server, which has identified or resumed a session.
+type Conn struct {
+	server *Server
+	conn   net.Conn
+	codec  encoding.Codec
+	frame  ws.OpCode
+
+	writeMu sync.Mutex
+	session *session
+	resumed bool
+
+	identify *gateway.Identify
+
+	heartbeatACK atomic.Bool
+	heartbeats   atomic.Int64
+
+	received chan *gateway.Payload
+	done     chan struct{}
+}
+
+// SessionID returns the id of the session the connection belongs to.
+func (c *Conn) SessionID() string {
+	return c.session.id
+}
+
+// Resumed reports whether the connection resumed an existing session, instead of identifying.
+func (c *Conn) Resumed() bool {
+	return c.resumed
+}
+
+// Identify returns the identify payload sent by the client, or nil when the session was resumed.
+func (c *Conn) Identify() *gateway.Identify {
+	return c.identify
+}
+
+// Seq returns the sequence number of the last dispatched event.
+func (c *Conn) Seq() int64 {
+	return c.session.seq()
+}
+
+// Heartbeats returns the number of heartbeats received on this connection.
+func (c *Conn) Heartbeats() int64 {
+	return c.heartbeats.Load()
+}
+
+// SetHeartbeatACK decides whether heartbeats are acknowledged, which is enabled by default. Disabling it simulates
+// a zombied connection.
+func (c *Conn) SetHeartbeatACK(enabled bool) {
+	c.heartbeatACK.Store(enabled)
+}
+
+// Done is closed once the connection has been closed, by either side.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Receive returns the next payload sent by the client, excluding heartbeats.
+func (c *Conn) Receive(ctx context.Context) (*gateway.Payload, error) {
+	select {
+	case payload := <-c.received:
+		return payload, nil
+	case <-c.done:
+		select {
+		case payload := <-c.received:
+			return payload, nil
+		default:
+			return nil, ErrConnClosed
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Dispatch sends a dispatch event with the next sequence number. The data is encoded by the codec of the
+// connection, raw json is converted when the connection uses etf.
+func (c *Conn) Dispatch(evt event.Type, data interface{}) error {
+	raw, err := c.codec.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	c.session.mu.Lock()
+	defer c.session.mu.Unlock()
+
+	payload := &outgoing{
+		Op:        opcode.Dispatch,
+		Data:      raw,
+		Seq:       int64(len(c.session.events)) + 1,
+		EventName: evt,
+	}
+	if err = c.write(payload); err != nil {
+		return err
+	}
+	c.session.events = append(c.session.events, payload)
+	return nil
+}
+
+// RequestHeartbeat asks the client to send a heartbeat immediately.
+func (c *Conn) RequestHeartbeat() error {
+	return c.send(opcode.Heartbeat, nil)
+}
+
+// InvalidSession notifies the client that the session is invalid. A session which is not resumable is removed from
+// the server, such that any resume attempt fails.
+func (c *Conn) InvalidSession(resumable bool) error {
+	if !resumable {
+		c.server.invalidateSession(c.session.id)
+	}
+	return c.send(opcode.InvalidSession, resumable)
+}
+
+// Reconnect asks the client to reconnect and resume.
+func (c *Conn) Reconnect() error {
+	return c.send(opcode.Reconnect, nil)
+}
+
+// Close sends a close frame with the given close code, and closes the connection once the client acknowledged it.
+// Close codes that do not allow reconnecting also removes the session from the server.
+func (c *Conn) Close(code closecode.Type, reason string) error {
+	if code >= 4000 && !closecode.CanReconnectAfter(code) {
+		c.server.invalidateSession(c.session.id)
+	}
+	return c.close(code, reason)
+}
+
+func (c *Conn) close(code closecode.Type, reason string) error {
+	if err := c.writeClose(code, reason); err != nil {
+		_ = c.conn.Close()
+		return err
+	}
+
+	// the read loop stops once the client acknowledged the close frame
+	select {
+	case <-c.done:
+	case <-time.After(closeTimeout):
+		_ = c.conn.Close()
+	}
+	return nil
+}
+
+func (c *Conn) writeClose(code closecode.Type, reason string) error {
+	frame := ws.NewCloseFrame(ws.NewCloseFrameBody(ws.StatusCode(code), reason))
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return ws.WriteFrame(c.conn, frame)
+}
+
+func (c *Conn) send(op opcode.Type, data interface{}) error {
+	raw, err := c.codec.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return c.write(&outgoing{Op: op, Data: raw})
+}
+
+func (c *Conn) write(payload *outgoing) error {
+	data, err := c.codec.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	select {
+	case <-c.done:
+		return ErrConnClosed
+	default:
+	}
+	return wsutil.WriteServerMessage(c.conn, c.frame, data)
+}
+
+// lockedWriter synchronises the control frames written while reading, with the payloads written by the test
+type lockedWriter struct {
+	conn *Conn
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.conn.writeMu.Lock()
+	defer w.conn.writeMu.Unlock()
+	return w.conn.conn.Write(p)
+}
+
+// run sends hello and processes client payloads until the connection is closed
+func (c *Conn) run() {
+	defer close(c.done)
+	defer func() {
+		_ = c.conn.Close()
+	}()
+
+	interval := c.server.heartbeatInterval.Milliseconds()
+	if err := c.send(opcode.Hello, &gateway.Hello{HeartbeatIntervalMilli: interval}); err != nil {
+		return
+	}
+
+	rw := struct {
+		io.Reader
+		io.Writer
+	}{c.conn, &lockedWriter{c}}
+
+	for {
+		data, _, err := wsutil.ReadClientData(rw)
+		if err != nil {
+			return
+		}
+
+		var payload gateway.Payload
+		code, reason := closecode.DecodeError, "Error while decoding payload."
+		if err = c.codec.Unmarshal(data, &payload); err == nil {
+			code, reason = c.process(&payload)
+		}
+
+		if code != 0 {
+			// keep reading until the client acknowledged the close frame
+			if err = c.writeClose(code, reason); err != nil {
+				return
+			}
+			_ = c.conn.SetReadDeadline(time.Now().Add(closeTimeout))
+		}
+	}
+}
+
+// process handles a client payload, and returns a close code when the connection must be closed
+func (c *Conn) process(payload *gateway.Payload) (closecode.Type, string) {
+	switch payload.Op {
+	case opcode.Heartbeat:
+		c.heartbeats.Add(1)
+		if c.heartbeatACK.Load() {
+			_ = c.send(opcode.HeartbeatACK, nil)
+		}
+		return 0, ""
+	case opcode.Identify:
+		if c.session != nil {
+			return closecode.AlreadyAuthenticated, "You sent more than one identify payload. Don't do that!"
+		}
+		return c.identifySession(payload)
+	case opcode.Resume:
+		if c.session != nil {
+			return closecode.AlreadyAuthenticated, "You sent more than one resume payload. Don't do that!"
+		}
+		return c.resumeSession(payload)
+	}
+
+	if c.session == nil {
+		return closecode.NotAuthenticated, "You sent a payload prior to identifying."
+	}
+
+	select {
+	case c.received <- payload:
+	default:
+		// the test is not reading the payloads, drop them instead of blocking heartbeats
+	}
+	return 0, ""
+}
+
+func (c *Conn) identifySession(payload *gateway.Payload) (closecode.Type, string) {
+	var identify gateway.Identify
+	if err := c.codec.Unmarshal(payload.Data, &identify); err != nil {
+		return closecode.DecodeError, "Error while decoding payload."
+	}
+	if !c.validToken(identify.BotToken) {
+		return closecode.AuthenticationFailed, "Authentication failed."
+	}
+	if identify.Shard[1] < 1 || identify.Shard[0] < 0 || identify.Shard[0] >= identify.Shard[1] {
+		return closecode.InvalidShard, "Invalid shard."
+	}
+
+	c.identify = &identify
+	c.session = c.server.newSession()
+
+	ready := map[string]interface{}{}
+	for key, value := range c.server.ready {
+		ready[key] = value
+	}
+	ready["v"] = 10
+	ready["session_id"] = c.session.id
+	ready["resume_gateway_url"] = c.server.ResumeURL()
+	ready["shard"] = identify.Shard
+	if _, ok := ready["guilds"]; !ok {
+		ready["guilds"] = []interface{}{}
+	}
+
+	if err := c.Dispatch(event.Ready, ready); err != nil {
+		return closecode.UnknownError, err.Error()
+	}
+	c.established()
+	return 0, ""
+}
+
+func (c *Conn) resumeSession(payload *gateway.Payload) (closecode.Type, string) {
+	var resume gateway.Resume
+	if err := c.codec.Unmarshal(payload.Data, &resume); err != nil {
+		return closecode.DecodeError, "Error while decoding payload."
+	}
+	if !c.validToken(resume.BotToken) {
+		return closecode.AuthenticationFailed, "Authentication failed."
+	}
+
+	sess := c.server.session(resume.SessionID)
+	if sess == nil {
+		_ = c.send(opcode.InvalidSession, false)
+		return 0, ""
+	}
+
+	missed := sess.since(resume.SequenceNumber)
+	if missed == nil {
+		return closecode.InvalidSeq, fmt.Sprintf("Invalid seq %d.", resume.SequenceNumber)
+	}
+
+	c.session, c.resumed = sess, true
+	for _, evt := range missed {
+		if err := c.write(evt); err != nil {
+			return closecode.UnknownError, err.Error()
+		}
+	}
+	if err := c.Dispatch(event.Resumed, nil); err != nil {
+		return closecode.UnknownError, err.Error()
+	}
+	c.established()
+	return 0, ""
+}
+
+func (c *Conn) validToken(token string) bool {
+	if c.server.token == "" {
+		return token != ""
+	}
+	return token == c.server.token
+}
+
+// established hands the connection over to Server.Accept, without blocking the read loop
+func (c *Conn) established() {
+	go func() {
+		select {
+		case c.server.conns <- c:
+		case <-c.server.closed:
+		case <-c.done:
+		}
+	}()
+}
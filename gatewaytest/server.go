@@ -0,0 +1,259 @@
+// Package gatewaytest provides an in-process fake of the Discord gateway, such that clients and shards can be tested
+// offline and deterministically.
+//
+// The server speaks the gateway protocol: it sends Hello, validates Identify and Resume, and replies with Ready or
+// Resumed. Once a connection is established, it is returned by Server.Accept and the test decides what happens next,
+// such as dispatching events, requesting heartbeats, invalidating the session or closing with a close code.
+//
+//	server := gatewaytest.NewServer(gatewaytest.WithBotToken("token"))
+//	defer server.Close()
+//
+//	// dial server.URL with a client or shard
+//
+//	conn, err := server.Accept(ctx)
+//	err = conn.Dispatch(event.MessageCreate, map[string]interface{}{"content": "hello"})
+//	err = conn.Close(closecode.SessionTimedOut, "session timed out")
+//
+// Transport compression is not supported.
+package gatewaytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+
+	"github.com/gobwas/ws"
+)
+
+var ErrServerClosed = errors.New("gateway test server was closed")
+
+// Option for configuring a new Server.
+type Option func(server *Server)
+
+// WithBotToken makes the server close connections that identify or resume with a different token, using the
+// AuthenticationFailed close code. By default, any non-empty token is accepted.
+func WithBotToken(token string) Option {
+	return func(server *Server) {
+		server.token = token
+	}
+}
+
+// WithHeartbeatInterval sets the interval sent in the Hello payload. Defaults to 45 seconds.
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(server *Server) {
+		server.heartbeatInterval = interval
+	}
+}
+
+// WithReady adds fields to the Ready payload sent after a successful Identify. The server always sets session_id,
+// resume_gateway_url and shard.
+func WithReady(fields map[string]interface{}) Option {
+	return func(server *Server) {
+		server.ready = fields
+	}
+}
+
+// WithResumeURL sets the resume_gateway_url sent in Ready, such as the url of a server that refuses connections. By
+// default, the url of the server itself is sent.
+func WithResumeURL(resumeURL string) Option {
+	return func(server *Server) {
+		server.resumeURL = resumeURL
+	}
+}
+
+// Server is a local websocket server acting as the Discord gateway.
+type Server struct {
+	// URL is the dial url of the server, using api version 10 and json encoding
+	URL string
+
+	token             string
+	heartbeatInterval time.Duration
+	ready             map[string]interface{}
+	resumeURL         string
+
+	listener net.Listener
+	conns    chan *Conn
+	closed   chan struct{}
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	active   map[*Conn]struct{}
+	nextID   int
+}
+
+// NewServer starts listening on a random local port. The server must be closed after use.
+func NewServer(options ...Option) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("gatewaytest: failed to listen on a port: %s", err))
+	}
+
+	server := &Server{
+		URL:               "ws://" + listener.Addr().String() + "/?v=10&encoding=json",
+		heartbeatInterval: 45 * time.Second,
+		listener:          listener,
+		conns:             make(chan *Conn),
+		closed:            make(chan struct{}),
+		sessions:          map[string]*session{},
+		active:            map[*Conn]struct{}{},
+	}
+	for i := range options {
+		options[i](server)
+	}
+
+	server.wg.Add(1)
+	go server.serve()
+	return server
+}
+
+// ResumeURL is the resume_gateway_url sent in Ready, it has no query parameters just like the one sent by Discord.
+func (s *Server) ResumeURL() string {
+	if s.resumeURL != "" {
+		return s.resumeURL
+	}
+	return "ws://" + s.listener.Addr().String() + "/"
+}
+
+// Accept returns the next connection that has successfully identified or resumed.
+func (s *Server) Accept(ctx context.Context) (*Conn, error) {
+	select {
+	case conn := <-s.conns:
+		return conn, nil
+	case <-s.closed:
+		return nil, ErrServerClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close stops accepting connections, closes every active connection and waits for them to finish.
+func (s *Server) Close() {
+	select {
+	case <-s.closed:
+		return
+	default:
+	}
+	close(s.closed)
+	_ = s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.active {
+		_ = conn.conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(netConn)
+		}()
+	}
+}
+
+func (s *Server) handle(netConn net.Conn) {
+	var query url.Values
+	upgrader := ws.Upgrader{
+		OnRequest: func(uri []byte) error {
+			u, err := url.ParseRequestURI(string(uri))
+			if err != nil {
+				return err
+			}
+			query = u.Query()
+			return nil
+		},
+	}
+	if _, err := upgrader.Upgrade(netConn); err != nil {
+		_ = netConn.Close()
+		return
+	}
+
+	conn := &Conn{
+		server:   s,
+		conn:     netConn,
+		codec:    encoding.JSONCodec{},
+		frame:    ws.OpText,
+		received: make(chan *gateway.Payload, 100),
+		done:     make(chan struct{}),
+	}
+	conn.heartbeatACK.Store(true)
+	if query.Get("encoding") == "etf" {
+		conn.codec, conn.frame = etf.Codec{}, ws.OpBinary
+	}
+
+	s.mu.Lock()
+	s.active[conn] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.active, conn)
+		s.mu.Unlock()
+	}()
+
+	conn.run()
+}
+
+// newSession registers a new session for an identified connection
+func (s *Server) newSession() *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	sess := &session{id: "session-" + strconv.Itoa(s.nextID)}
+	s.sessions[sess.id] = sess
+	return sess
+}
+
+func (s *Server) session(id string) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[id]
+}
+
+func (s *Server) invalidateSession(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// session holds the dispatched events, such that they can be replayed on resume
+type session struct {
+	id     string
+	mu     sync.Mutex
+	events []*outgoing
+}
+
+func (s *session) seq() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.events))
+}
+
+func (s *session) since(seq int64) []*outgoing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seq < 0 || seq > int64(len(s.events)) {
+		return nil
+	}
+	return append([]*outgoing{}, s.events[seq:]...)
+}
@@ -0,0 +1,119 @@
+package gatewaytest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/closecode"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/event/opcode"
+	"github.com/discordpkg/gateway/gatewaytest"
+	"github.com/discordpkg/gateway/gatewayutil"
+)
+
+func newShard(t *testing.T, token string, handler gateway.Handler, options ...gateway.Option) *gatewayutil.Shard {
+	options = append([]gateway.Option{
+		gateway.WithBotToken(token),
+		gateway.WithEventHandler(handler),
+		gateway.WithGuildEvents(event.MessageCreate),
+		gateway.WithCommandRateLimiter(gatewayutil.NewCommandRateLimiter()),
+		gateway.WithIdentifyRateLimiter(gatewayutil.NewLocalIdentifyRateLimiter()),
+	}, options...)
+
+	shard, err := gatewayutil.NewShard(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return shard
+}
+
+func TestServer(t *testing.T) {
+	for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			server := gatewaytest.NewServer(gatewaytest.WithBotToken("token"), gatewaytest.WithHeartbeatInterval(20*time.Millisecond))
+			defer server.Close()
+
+			contents := make(chan string, 1)
+			shard := newShard(t, "token", func(_ gateway.ShardID, evt event.Type, data encoding.RawMessage) {
+				var message struct {
+					Content string `json:"content"`
+				}
+				if err := codec.Unmarshal(data, &message); err != nil {
+					t.Error(err)
+				}
+				contents <- message.Content
+			}, gateway.WithCodec(codec))
+
+			if _, err := shard.Dial(ctx, func() (string, error) { return server.URL, nil }); err != nil {
+				t.Fatal(err)
+			}
+			loop := make(chan error, 1)
+			go func() {
+				loop <- shard.EventLoop(ctx)
+			}()
+
+			conn, err := server.Accept(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if conn.Resumed() || conn.Identify().BotToken != "token" {
+				t.Errorf("expected a new session identified with the token, got %+v", conn.Identify())
+			}
+
+			if err = conn.Dispatch(event.MessageCreate, map[string]interface{}{"content": "hello"}); err != nil {
+				t.Fatal(err)
+			}
+			if content := <-contents; content != "hello" {
+				t.Errorf("incorrect content. Got '%s'", content)
+			}
+
+			if err = shard.UpdatePresence(&gateway.UpdatePresence{Status: gateway.StatusIdle}); err != nil {
+				t.Fatal(err)
+			}
+			payload, err := conn.Receive(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if payload.Op != opcode.PresenceUpdate {
+				t.Errorf("expected presence update, got op %d", payload.Op)
+			}
+
+			for conn.Heartbeats() == 0 {
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			if err = conn.Close(closecode.DisallowedIntents, "Disallowed intent(s)."); err != nil {
+				t.Fatal(err)
+			}
+
+			var discordErr *gateway.DiscordError
+			if err = <-loop; !errors.As(err, &discordErr) || discordErr.CloseCode != closecode.DisallowedIntents {
+				t.Errorf("expected disallowed intents close code, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServer_AuthenticationFailed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := gatewaytest.NewServer(gatewaytest.WithBotToken("token"))
+	defer server.Close()
+
+	shard := newShard(t, "wrong", nil)
+	err := shard.Run(ctx, func() (string, error) { return server.URL, nil })
+
+	var discordErr *gateway.DiscordError
+	if !errors.As(err, &discordErr) || discordErr.CloseCode != closecode.AuthenticationFailed {
+		t.Errorf("expected authentication failed close code, got %v", err)
+	}
+}
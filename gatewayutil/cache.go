@@ -0,0 +1,572 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/gatewayutil/log"
+)
+
+// CacheEntity is a kind of guild state the GuildCache maintains. Guilds themselves are always cached.
+type CacheEntity uint
+
+const (
+	CacheChannels CacheEntity = 1 << iota
+	CacheThreads
+	CacheRoles
+	CacheMembers
+	CacheEmojis
+	CacheVoiceStates
+
+	CacheAll = CacheChannels | CacheThreads | CacheRoles | CacheMembers | CacheEmojis | CacheVoiceStates
+)
+
+type CacheOption func(cache *GuildCache) error
+
+// WithCacheCodec sets the codec used to decode the events, which must match the client codec. Defaults to
+// encoding.JSONCodec.
+func WithCacheCodec(codec encoding.Codec) CacheOption {
+	return func(cache *GuildCache) error {
+		if codec == nil {
+			return errors.New("codec can not be nil")
+		}
+		cache.codec = codec
+		return nil
+	}
+}
+
+// WithCacheEntities limits the cached guild state to the given entities. Defaults to CacheAll. Note that the events
+// of an entity are only received when the matching intents are requested, such as GUILD_MEMBERS for members.
+func WithCacheEntities(entities ...CacheEntity) CacheOption {
+	return func(cache *GuildCache) error {
+		cache.entities = 0
+		for _, entity := range entities {
+			cache.entities |= entity
+		}
+		return nil
+	}
+}
+
+// NewGuildCache creates an empty GuildCache.
+func NewGuildCache(options ...CacheOption) (*GuildCache, error) {
+	cache := &GuildCache{
+		codec:    encoding.JSONCodec{},
+		entities: CacheAll,
+		guilds:   map[event.Snowflake]*cachedGuild{},
+		channels: map[event.Snowflake]event.Snowflake{},
+	}
+	for i := range options {
+		if err := options[i](cache); err != nil {
+			return nil, err
+		}
+	}
+	return cache, nil
+}
+
+// GuildCache maintains the state of guilds from the dispatch events, such as GUILD_CREATE, CHANNEL_UPDATE and
+// GUILD_MEMBER_ADD. Events must be passed on in the order they were received, so either feed the cache from the
+// client event handler or use a Dispatcher with guild or key ordering:
+//
+//	cache, _ := gatewayutil.NewGuildCache(gatewayutil.WithCacheEntities(gatewayutil.CacheChannels, gatewayutil.CacheRoles))
+//	shard, _ := gatewayutil.NewShard(
+//		gateway.WithGuildEvents(event.All()...),
+//		gateway.WithEventHandler(cache.Handler(handler)),
+//	)
+//
+// Lookups are safe for concurrent use. The returned values share their slices with the cache, and must not be
+// modified.
+//
+// A guild that becomes unavailable due to an outage keeps its state until the next GUILD_CREATE, while the state of
+// a guild the bot was removed from is evicted. Events of guilds that are not cached, such as a guild the bot was
+// removed from, are ignored until the guild is received through GUILD_CREATE.
+type GuildCache struct {
+	codec    encoding.Codec
+	entities CacheEntity
+
+	mu     sync.RWMutex
+	guilds map[event.Snowflake]*cachedGuild
+
+	// channels maps the id of every cached channel and thread to its guild
+	channels map[event.Snowflake]event.Snowflake
+}
+
+type cachedGuild struct {
+	guild       event.Guild
+	unavailable bool
+
+	channels    map[event.Snowflake]event.Channel
+	threads     map[event.Snowflake]event.Channel
+	roles       map[event.Snowflake]event.Role
+	members     map[event.Snowflake]event.GuildMember
+	emojis      map[event.Snowflake]event.Emoji
+	voiceStates map[event.Snowflake]event.VoiceState
+}
+
+func newCachedGuild(id event.Snowflake) *cachedGuild {
+	return &cachedGuild{
+		guild:       event.Guild{ID: id},
+		channels:    map[event.Snowflake]event.Channel{},
+		threads:     map[event.Snowflake]event.Channel{},
+		roles:       map[event.Snowflake]event.Role{},
+		members:     map[event.Snowflake]event.GuildMember{},
+		emojis:      map[event.Snowflake]event.Emoji{},
+		voiceStates: map[event.Snowflake]event.VoiceState{},
+	}
+}
+
+func (c *GuildCache) enabled(entity CacheEntity) bool {
+	return c.entities&entity == entity
+}
+
+// Handler wraps the event handler of a client, updating the cache before the event is forwarded to next, which may
+// be nil. Events that fail to decode are logged.
+func (c *GuildCache) Handler(next gateway.Handler) gateway.Handler {
+	return func(shardID gateway.ShardID, evt event.Type, data encoding.RawMessage) {
+		if err := c.Update(evt, data); err != nil {
+			log.Error("guild cache failed to handle %s event: %s", evt, err)
+		}
+		if next != nil {
+			next(shardID, evt, data)
+		}
+	}
+}
+
+// Handle updates the cache, and is meant to be used as a gateway.HandlerV2 such as a Router subscriber.
+func (c *GuildCache) Handle(_ context.Context, evt *gateway.Event) error {
+	return c.Update(evt.Type, evt.Data)
+}
+
+// Update applies a dispatch event to the cache. Events that do not affect guild state are ignored.
+func (c *GuildCache) Update(evt event.Type, data encoding.RawMessage) error {
+	switch evt {
+	case event.Ready:
+		return update(c, data, c.ready)
+	case event.GuildCreate:
+		return update(c, data, c.guildCreate)
+	case event.GuildUpdate:
+		return update(c, data, c.guildUpdate)
+	case event.GuildDelete:
+		return update(c, data, c.guildDelete)
+	case event.ChannelCreate, event.ChannelUpdate:
+		return update(c, data, func(payload *event.ChannelCreateEvent) { c.setChannel(&payload.Channel) })
+	case event.ChannelDelete:
+		return update(c, data, func(payload *event.ChannelDeleteEvent) { c.deleteChannel(&payload.Channel) })
+	case event.ThreadCreate, event.ThreadUpdate:
+		return update(c, data, func(payload *event.ThreadCreateEvent) { c.setThread(&payload.Channel) })
+	case event.ThreadDelete:
+		return update(c, data, func(payload *event.ThreadDeleteEvent) { c.deleteChannel(&payload.Channel) })
+	case event.ThreadListSync:
+		return update(c, data, c.threadListSync)
+	case event.GuildRoleCreate, event.GuildRoleUpdate:
+		return update(c, data, func(payload *event.GuildRoleCreateEvent) { c.setRole(payload.GuildID, &payload.Role) })
+	case event.GuildRoleDelete:
+		return update(c, data, c.roleDelete)
+	case event.GuildMemberAdd:
+		return update(c, data, func(payload *event.GuildMemberAddEvent) {
+			c.setMembers(payload.GuildID, []event.GuildMember{payload.GuildMember})
+		})
+	case event.GuildMemberUpdate:
+		return update(c, data, c.memberUpdate)
+	case event.GuildMemberRemove:
+		return update(c, data, c.memberRemove)
+	case event.GuildMembersChunk:
+		return update(c, data, func(payload *event.GuildMembersChunkEvent) { c.setMembers(payload.GuildID, payload.Members) })
+	case event.GuildEmojisUpdate:
+		return update(c, data, func(payload *event.GuildEmojisUpdateEvent) { c.setEmojis(payload.GuildID, payload.Emojis) })
+	case event.VoiceStateUpdate:
+		return update(c, data, func(payload *event.VoiceStateUpdateEvent) { c.setVoiceState(&payload.VoiceState) })
+	}
+	return nil
+}
+
+// update decodes the payload and applies it while holding the write lock
+func update[T any](c *GuildCache, data encoding.RawMessage, apply func(payload *T)) error {
+	payload := new(T)
+	if err := c.codec.Unmarshal(data, payload); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	apply(payload)
+	return nil
+}
+
+// guild returns the cached guild, which is created when missing
+func (c *GuildCache) guild(id event.Snowflake) *cachedGuild {
+	guild, ok := c.guilds[id]
+	if !ok {
+		guild = newCachedGuild(id)
+		c.guilds[id] = guild
+	}
+	return guild
+}
+
+// cached returns the guild when it is known from READY or GUILD_CREATE. Other events never create a guild, as it
+// would be cached without most of its state.
+func (c *GuildCache) cached(id event.Snowflake) (*cachedGuild, bool) {
+	guild, ok := c.guilds[id]
+	return guild, ok
+}
+
+func (c *GuildCache) ready(payload *event.ReadyEvent) {
+	for _, unavailable := range payload.Guilds {
+		c.guild(unavailable.ID).unavailable = true
+	}
+}
+
+func (c *GuildCache) guildCreate(payload *event.GuildCreateEvent) {
+	if payload.Unavailable {
+		c.guild(payload.ID).unavailable = true
+		return
+	}
+
+	// the guild is sent in full, so any stale state is replaced
+	c.evict(payload.ID)
+	guild := c.guild(payload.ID)
+	c.setGuild(guild, &payload.Guild)
+
+	for i := range payload.Channels {
+		payload.Channels[i].GuildID = payload.ID
+		c.setChannel(&payload.Channels[i])
+	}
+	for i := range payload.Threads {
+		payload.Threads[i].GuildID = payload.ID
+		c.setThread(&payload.Threads[i])
+	}
+	c.setMembers(payload.ID, payload.Members)
+	for i := range payload.VoiceStates {
+		payload.VoiceStates[i].GuildID = payload.ID
+		c.setVoiceState(&payload.VoiceStates[i])
+	}
+}
+
+func (c *GuildCache) guildUpdate(payload *event.GuildUpdateEvent) {
+	if guild, ok := c.cached(payload.ID); ok {
+		c.setGuild(guild, &payload.Guild)
+	}
+}
+
+// setGuild stores the guild, where roles and emojis are kept apart as they are updated by their own events
+func (c *GuildCache) setGuild(guild *cachedGuild, update *event.Guild) {
+	guild.guild = *update
+	guild.guild.Roles, guild.guild.Emojis = nil, nil
+	guild.unavailable = false
+
+	if c.enabled(CacheRoles) {
+		guild.roles = make(map[event.Snowflake]event.Role, len(update.Roles))
+		for i := range update.Roles {
+			guild.roles[update.Roles[i].ID] = update.Roles[i]
+		}
+	}
+	c.setEmojis(update.ID, update.Emojis)
+}
+
+func (c *GuildCache) guildDelete(payload *event.GuildDeleteEvent) {
+	if payload.Unavailable {
+		// an outage, the guild is sent again once it becomes available
+		c.guild(payload.ID).unavailable = true
+		return
+	}
+	c.evict(payload.ID)
+}
+
+// evict removes the guild and all its state
+func (c *GuildCache) evict(id event.Snowflake) {
+	guild, ok := c.guilds[id]
+	if !ok {
+		return
+	}
+	for channelID := range guild.channels {
+		delete(c.channels, channelID)
+	}
+	for threadID := range guild.threads {
+		delete(c.channels, threadID)
+	}
+	delete(c.guilds, id)
+}
+
+func (c *GuildCache) setChannel(channel *event.Channel) {
+	if !c.enabled(CacheChannels) || channel.GuildID == "" {
+		return
+	}
+	guild, ok := c.cached(channel.GuildID)
+	if !ok {
+		return
+	}
+	guild.channels[channel.ID] = *channel
+	c.channels[channel.ID] = channel.GuildID
+}
+
+func (c *GuildCache) setThread(thread *event.Channel) {
+	if !c.enabled(CacheThreads) || thread.GuildID == "" {
+		return
+	}
+	guild, ok := c.cached(thread.GuildID)
+	if !ok {
+		return
+	}
+	guild.threads[thread.ID] = *thread
+	c.channels[thread.ID] = thread.GuildID
+}
+
+// deleteChannel removes a channel or thread
+func (c *GuildCache) deleteChannel(channel *event.Channel) {
+	guildID, ok := c.channels[channel.ID]
+	if !ok {
+		return
+	}
+	delete(c.channels, channel.ID)
+	if guild, ok := c.guilds[guildID]; ok {
+		delete(guild.channels, channel.ID)
+		delete(guild.threads, channel.ID)
+	}
+}
+
+// threadListSync replaces the active threads of the synced channels, or of the whole guild when no channels are given
+func (c *GuildCache) threadListSync(payload *event.ThreadListSyncEvent) {
+	guild, ok := c.cached(payload.GuildID)
+	if !c.enabled(CacheThreads) || !ok {
+		return
+	}
+
+	synced := make(map[event.Snowflake]struct{}, len(payload.ChannelIDs))
+	for _, id := range payload.ChannelIDs {
+		synced[id] = struct{}{}
+	}
+	for id, thread := range guild.threads {
+		if len(synced) > 0 {
+			if thread.ParentID == nil {
+				continue
+			}
+			if _, ok := synced[*thread.ParentID]; !ok {
+				continue
+			}
+		}
+		delete(guild.threads, id)
+		delete(c.channels, id)
+	}
+
+	for i := range payload.Threads {
+		payload.Threads[i].GuildID = payload.GuildID
+		c.setThread(&payload.Threads[i])
+	}
+}
+
+func (c *GuildCache) setRole(guildID event.Snowflake, role *event.Role) {
+	guild, ok := c.cached(guildID)
+	if !c.enabled(CacheRoles) || !ok {
+		return
+	}
+	guild.roles[role.ID] = *role
+}
+
+func (c *GuildCache) roleDelete(payload *event.GuildRoleDeleteEvent) {
+	if guild, ok := c.guilds[payload.GuildID]; ok {
+		delete(guild.roles, payload.RoleID)
+	}
+}
+
+func (c *GuildCache) setMembers(guildID event.Snowflake, members []event.GuildMember) {
+	guild, ok := c.cached(guildID)
+	if !c.enabled(CacheMembers) || !ok {
+		return
+	}
+
+	for i := range members {
+		if members[i].User != nil {
+			guild.members[members[i].User.ID] = members[i]
+		}
+	}
+}
+
+// memberUpdate updates the member, which is added when missing as the event holds every field of the member
+func (c *GuildCache) memberUpdate(payload *event.GuildMemberUpdateEvent) {
+	guild, ok := c.cached(payload.GuildID)
+	if !c.enabled(CacheMembers) || !ok {
+		return
+	}
+
+	member := guild.members[payload.User.ID]
+	member.User = &payload.User
+	member.Roles = payload.Roles
+	member.Nick = payload.Nick
+	member.Avatar = payload.Avatar
+	if payload.JoinedAt != nil {
+		member.JoinedAt = *payload.JoinedAt
+	}
+	member.PremiumSince = payload.PremiumSince
+	member.Deaf = payload.Deaf
+	member.Mute = payload.Mute
+	member.Pending = payload.Pending
+	member.CommunicationDisabledUntil = payload.CommunicationDisabledUntil
+	member.Flags = payload.Flags
+	guild.members[payload.User.ID] = member
+}
+
+func (c *GuildCache) memberRemove(payload *event.GuildMemberRemoveEvent) {
+	if guild, ok := c.guilds[payload.GuildID]; ok {
+		delete(guild.members, payload.User.ID)
+		delete(guild.voiceStates, payload.User.ID)
+	}
+}
+
+func (c *GuildCache) setEmojis(guildID event.Snowflake, emojis []event.Emoji) {
+	guild, ok := c.cached(guildID)
+	if !c.enabled(CacheEmojis) || !ok {
+		return
+	}
+
+	// the emoji events always hold every emoji of the guild
+	guild.emojis = make(map[event.Snowflake]event.Emoji, len(emojis))
+	for i := range emojis {
+		if emojis[i].ID != nil {
+			guild.emojis[*emojis[i].ID] = emojis[i]
+		}
+	}
+}
+
+// setVoiceState stores the voice state, or removes it when the user left the voice channel
+func (c *GuildCache) setVoiceState(state *event.VoiceState) {
+	guild, ok := c.cached(state.GuildID)
+	if !c.enabled(CacheVoiceStates) || !ok {
+		return
+	}
+
+	if state.ChannelID == nil {
+		delete(guild.voiceStates, state.UserID)
+		return
+	}
+	guild.voiceStates[state.UserID] = *state
+}
+
+// Guild returns the cached guild. Its roles and emojis are left out, see Roles and Emojis.
+func (c *GuildCache) Guild(id event.Snowflake) (event.Guild, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	guild, ok := c.guilds[id]
+	if !ok {
+		return event.Guild{}, false
+	}
+	return guild.guild, true
+}
+
+// GuildIDs returns the ids of every cached guild, including unavailable guilds.
+func (c *GuildCache) GuildIDs() []event.Snowflake {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]event.Snowflake, 0, len(c.guilds))
+	for id := range c.guilds {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Unavailable reports whether the guild is known, but unavailable due to an outage or because the guild has not
+// been sent yet after connecting. The cached state of an unavailable guild may be outdated.
+func (c *GuildCache) Unavailable(id event.Snowflake) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	guild, ok := c.guilds[id]
+	return ok && guild.unavailable
+}
+
+// Channel returns a cached channel or thread of any guild.
+func (c *GuildCache) Channel(id event.Snowflake) (event.Channel, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	guild, ok := c.guilds[c.channels[id]]
+	if !ok {
+		return event.Channel{}, false
+	}
+	if channel, ok := guild.channels[id]; ok {
+		return channel, true
+	}
+	thread, ok := guild.threads[id]
+	return thread, ok
+}
+
+// Channels returns the cached channels of the guild, excluding threads.
+func (c *GuildCache) Channels(guildID event.Snowflake) []event.Channel {
+	return values(c, guildID, func(guild *cachedGuild) map[event.Snowflake]event.Channel { return guild.channels })
+}
+
+// Threads returns the cached active threads of the guild.
+func (c *GuildCache) Threads(guildID event.Snowflake) []event.Channel {
+	return values(c, guildID, func(guild *cachedGuild) map[event.Snowflake]event.Channel { return guild.threads })
+}
+
+// Role returns a cached role of the guild.
+func (c *GuildCache) Role(guildID, roleID event.Snowflake) (event.Role, bool) {
+	return lookup(c, guildID, roleID, func(guild *cachedGuild) map[event.Snowflake]event.Role { return guild.roles })
+}
+
+// Roles returns the cached roles of the guild.
+func (c *GuildCache) Roles(guildID event.Snowflake) []event.Role {
+	return values(c, guildID, func(guild *cachedGuild) map[event.Snowflake]event.Role { return guild.roles })
+}
+
+// Member returns a cached member of the guild.
+func (c *GuildCache) Member(guildID, userID event.Snowflake) (event.GuildMember, bool) {
+	return lookup(c, guildID, userID, func(guild *cachedGuild) map[event.Snowflake]event.GuildMember { return guild.members })
+}
+
+// Members returns the cached members of the guild. Without the GUILD_MEMBERS intent, or requesting the members using
+// RequestGuildMembers, only a subset of the members is known.
+func (c *GuildCache) Members(guildID event.Snowflake) []event.GuildMember {
+	return values(c, guildID, func(guild *cachedGuild) map[event.Snowflake]event.GuildMember { return guild.members })
+}
+
+// Emojis returns the cached custom emojis of the guild.
+func (c *GuildCache) Emojis(guildID event.Snowflake) []event.Emoji {
+	return values(c, guildID, func(guild *cachedGuild) map[event.Snowflake]event.Emoji { return guild.emojis })
+}
+
+// VoiceState returns the voice state of a user in a voice channel of the guild.
+func (c *GuildCache) VoiceState(guildID, userID event.Snowflake) (event.VoiceState, bool) {
+	return lookup(c, guildID, userID, func(guild *cachedGuild) map[event.Snowflake]event.VoiceState { return guild.voiceStates })
+}
+
+// VoiceStates returns the voice states of the users in the voice channels of the guild.
+func (c *GuildCache) VoiceStates(guildID event.Snowflake) []event.VoiceState {
+	return values(c, guildID, func(guild *cachedGuild) map[event.Snowflake]event.VoiceState { return guild.voiceStates })
+}
+
+func lookup[T any](c *GuildCache, guildID, id event.Snowflake, entities func(guild *cachedGuild) map[event.Snowflake]T) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var entity T
+	guild, ok := c.guilds[guildID]
+	if !ok {
+		return entity, false
+	}
+	entity, ok = entities(guild)[id]
+	return entity, ok
+}
+
+func values[T any](c *GuildCache, guildID event.Snowflake, entities func(guild *cachedGuild) map[event.Snowflake]T) []T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	guild, ok := c.guilds[guildID]
+	if !ok {
+		return nil
+	}
+
+	cached := entities(guild)
+	list := make([]T, 0, len(cached))
+	for _, entity := range cached {
+		list = append(list, entity)
+	}
+	return list
+}
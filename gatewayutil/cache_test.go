@@ -0,0 +1,195 @@
+package gatewayutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+)
+
+type cacheEvent struct {
+	evt  event.Type
+	data map[string]interface{}
+}
+
+func feedCache(t *testing.T, cache *GuildCache, codec encoding.Codec, events ...cacheEvent) {
+	for _, e := range events {
+		data, err := codec.Marshal(e.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = cache.Handle(context.Background(), &gateway.Event{Type: e.evt, Data: data}); err != nil {
+			t.Fatalf("%s: %v", e.evt, err)
+		}
+	}
+}
+
+func guildCreate() cacheEvent {
+	user := func(id string) map[string]interface{} {
+		return map[string]interface{}{"id": id, "username": "user " + id}
+	}
+
+	return cacheEvent{event.GuildCreate, map[string]interface{}{
+		"id":   "1",
+		"name": "guild",
+		"roles": []interface{}{
+			map[string]interface{}{"id": "10", "name": "admin"},
+		},
+		"emojis": []interface{}{
+			map[string]interface{}{"id": "20", "name": "smile"},
+		},
+		"channels": []interface{}{
+			map[string]interface{}{"id": "30", "name": "general"},
+			map[string]interface{}{"id": "31", "name": "voice"},
+		},
+		"threads": []interface{}{
+			map[string]interface{}{"id": "40", "name": "thread", "parent_id": "30"},
+		},
+		"members": []interface{}{
+			map[string]interface{}{"user": user("50"), "roles": []interface{}{"10"}},
+			map[string]interface{}{"user": user("51"), "roles": []interface{}{}},
+		},
+		"voice_states": []interface{}{
+			map[string]interface{}{"user_id": "50", "channel_id": "31", "session_id": "session"},
+		},
+	}}
+}
+
+func TestGuildCache(t *testing.T) {
+	for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			cache, err := NewGuildCache(WithCacheCodec(codec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			feedCache(t, cache, codec, guildCreate())
+
+			if guild, ok := cache.Guild("1"); !ok || guild.Name != "guild" || guild.Roles != nil {
+				t.Errorf("incorrect guild. Got %+v", guild)
+			}
+			if channel, ok := cache.Channel("40"); !ok || channel.GuildID != "1" {
+				t.Errorf("expected the thread to be found by id. Got %+v", channel)
+			}
+			if len(cache.Channels("1")) != 2 || len(cache.Threads("1")) != 1 || len(cache.Roles("1")) != 1 ||
+				len(cache.Members("1")) != 2 || len(cache.Emojis("1")) != 1 || len(cache.VoiceStates("1")) != 1 {
+				t.Fatal("expected the guild create event to fill the cache")
+			}
+
+			feedCache(t, cache, codec,
+				cacheEvent{event.ChannelUpdate, map[string]interface{}{"id": "30", "guild_id": "1", "name": "renamed"}},
+				cacheEvent{event.ChannelDelete, map[string]interface{}{"id": "31", "guild_id": "1"}},
+				cacheEvent{event.ThreadListSync, map[string]interface{}{"guild_id": "1", "channel_ids": []interface{}{"30"}, "threads": []interface{}{}}},
+				cacheEvent{event.GuildRoleCreate, map[string]interface{}{"guild_id": "1", "role": map[string]interface{}{"id": "11", "name": "mod"}}},
+				cacheEvent{event.GuildRoleDelete, map[string]interface{}{"guild_id": "1", "role_id": "10"}},
+				cacheEvent{event.GuildMemberUpdate, map[string]interface{}{"guild_id": "1", "user": map[string]interface{}{"id": "51"}, "roles": []interface{}{"11"}, "nick": "nick"}},
+				cacheEvent{event.GuildMemberRemove, map[string]interface{}{"guild_id": "1", "user": map[string]interface{}{"id": "50"}}},
+				cacheEvent{event.GuildEmojisUpdate, map[string]interface{}{"guild_id": "1", "emojis": []interface{}{}}},
+				cacheEvent{event.VoiceStateUpdate, map[string]interface{}{"guild_id": "1", "user_id": "51", "channel_id": "30", "session_id": "session"}},
+			)
+
+			if channel, ok := cache.Channel("30"); !ok || channel.Name == nil || *channel.Name != "renamed" {
+				t.Errorf("expected the channel to be updated. Got %+v", channel)
+			}
+			if _, ok := cache.Channel("31"); ok {
+				t.Error("expected the channel to be deleted")
+			}
+			if len(cache.Threads("1")) != 0 {
+				t.Error("expected the threads of the synced channel to be replaced")
+			}
+			if _, ok := cache.Role("1", "10"); ok || len(cache.Roles("1")) != 1 {
+				t.Errorf("incorrect roles. Got %+v", cache.Roles("1"))
+			}
+			if member, ok := cache.Member("1", "51"); !ok || member.Nick == nil || *member.Nick != "nick" || len(member.Roles) != 1 {
+				t.Errorf("expected the member to be updated. Got %+v", member)
+			}
+			if _, ok := cache.Member("1", "50"); ok {
+				t.Error("expected the member to be removed")
+			}
+			if _, ok := cache.VoiceState("1", "50"); ok {
+				t.Error("expected the voice state of the removed member to be removed")
+			}
+			if state, ok := cache.VoiceState("1", "51"); !ok || state.ChannelID == nil || *state.ChannelID != "30" {
+				t.Errorf("incorrect voice state. Got %+v", state)
+			}
+			if len(cache.Emojis("1")) != 0 {
+				t.Error("expected the emojis to be replaced")
+			}
+		})
+	}
+}
+
+func TestGuildCache_GuildDelete(t *testing.T) {
+	cache, err := NewGuildCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := encoding.JSONCodec{}
+
+	feedCache(t, cache, codec, guildCreate(), cacheEvent{event.GuildDelete, map[string]interface{}{"id": "1", "unavailable": true}})
+	if !cache.Unavailable("1") || len(cache.Channels("1")) != 2 {
+		t.Error("expected an unavailable guild to keep its state")
+	}
+
+	feedCache(t, cache, codec, guildCreate())
+	if cache.Unavailable("1") {
+		t.Error("expected the guild to be available again")
+	}
+
+	feedCache(t, cache, codec, cacheEvent{event.GuildDelete, map[string]interface{}{"id": "1"}})
+	if _, ok := cache.Guild("1"); ok || len(cache.GuildIDs()) != 0 {
+		t.Error("expected a removed guild to be evicted")
+	}
+	if _, ok := cache.Channel("30"); ok {
+		t.Error("expected the channels of a removed guild to be evicted")
+	}
+}
+
+func TestGuildCache_UnknownGuild(t *testing.T) {
+	cache, err := NewGuildCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := encoding.JSONCodec{}
+
+	user := map[string]interface{}{"id": "50"}
+	feedCache(t, cache, codec,
+		cacheEvent{event.GuildUpdate, map[string]interface{}{"id": "2", "name": "guild"}},
+		cacheEvent{event.ChannelCreate, map[string]interface{}{"id": "30", "guild_id": "2"}},
+		cacheEvent{event.ThreadCreate, map[string]interface{}{"id": "40", "guild_id": "2"}},
+		cacheEvent{event.ThreadListSync, map[string]interface{}{"guild_id": "2", "threads": []interface{}{}}},
+		cacheEvent{event.GuildRoleCreate, map[string]interface{}{"guild_id": "2", "role": map[string]interface{}{"id": "10"}}},
+		cacheEvent{event.GuildMemberAdd, map[string]interface{}{"guild_id": "2", "user": user}},
+		cacheEvent{event.GuildMemberUpdate, map[string]interface{}{"guild_id": "2", "user": user, "roles": []interface{}{}}},
+		cacheEvent{event.GuildEmojisUpdate, map[string]interface{}{"guild_id": "2", "emojis": []interface{}{}}},
+		cacheEvent{event.VoiceStateUpdate, map[string]interface{}{"guild_id": "2", "user_id": "50", "channel_id": "31"}},
+	)
+	if ids := cache.GuildIDs(); len(ids) != 0 {
+		t.Errorf("expected the events of an unknown guild to be ignored, got guilds %v", ids)
+	}
+	if _, ok := cache.Channel("30"); ok {
+		t.Error("expected the channel of an unknown guild to not be cached")
+	}
+
+	feedCache(t, cache, codec, guildCreate(), cacheEvent{event.ChannelCreate, map[string]interface{}{"id": "32", "guild_id": "1"}})
+	if len(cache.Channels("1")) != 3 {
+		t.Error("expected the events to be applied once the guild is created")
+	}
+}
+
+func TestWithCacheEntities(t *testing.T) {
+	cache, err := NewGuildCache(WithCacheEntities(CacheChannels, CacheRoles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feedCache(t, cache, encoding.JSONCodec{}, guildCreate())
+
+	if len(cache.Channels("1")) != 2 || len(cache.Roles("1")) != 1 {
+		t.Error("expected the enabled entities to be cached")
+	}
+	if len(cache.Threads("1")) != 0 || len(cache.Members("1")) != 0 || len(cache.Emojis("1")) != 0 || len(cache.VoiceStates("1")) != 0 {
+		t.Error("expected the disabled entities to not be cached")
+	}
+}
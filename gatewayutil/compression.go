@@ -0,0 +1,246 @@
+package gatewayutil
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// TransportCompression is the compression applied to the whole websocket connection, specified by the "compress"
+// query parameter of the dial url.
+//
+// See https://discord.com/developers/docs/topics/gateway#transport-compression
+type TransportCompression string
+
+const (
+	NoCompression TransportCompression = ""
+	ZlibStream    TransportCompression = "zlib-stream"
+	ZstdStream    TransportCompression = "zstd-stream"
+)
+
+var ErrUnsupportedCompression = fmt.Errorf("only %+v transport compression is supported", supportedCompressions)
+
+var supportedCompressions = []string{
+	string(ZlibStream),
+	string(ZstdStream),
+}
+
+// decompressor inflates the binary websocket messages of a compressed connection. One decompressor must be used per
+// connection, as the compression context is shared across messages.
+type decompressor interface {
+	// Decompress takes the payload of a websocket message and returns the inflated data. A nil slice is returned
+	// when the message is incomplete and more data must be buffered.
+	Decompress(data []byte) ([]byte, error)
+	Close() error
+}
+
+func newDecompressor(compression TransportCompression) (decompressor, error) {
+	switch compression {
+	case NoCompression:
+		return nil, nil
+	case ZlibStream:
+		return &transportStream{
+			complete: zlibComplete,
+			inflater: &zlibInflater{},
+		}, nil
+	case ZstdStream:
+		return &transportStream{
+			complete: zstdComplete,
+			inflater: newZstdInflater(),
+		}, nil
+	default:
+		return nil, ErrUnsupportedCompression
+	}
+}
+
+// inflater decompresses complete messages of a compression stream
+type inflater interface {
+	Inflate(compressed []byte) ([]byte, error)
+	Close() error
+}
+
+// transportStream buffers websocket messages until they form a complete compressed payload, before handing them to
+// the inflater of the given compression.
+type transportStream struct {
+	buffer   []byte
+	complete func(buffer []byte) bool
+	inflater inflater
+}
+
+var _ decompressor = &transportStream{}
+
+func (t *transportStream) Decompress(data []byte) ([]byte, error) {
+	t.buffer = append(t.buffer, data...)
+	if !t.complete(t.buffer) {
+		return nil, nil
+	}
+
+	compressed := t.buffer
+	t.buffer = nil
+	return t.inflater.Inflate(compressed)
+}
+
+func (t *transportStream) Close() error {
+	return t.inflater.Close()
+}
+
+// zlibSuffix is the Z_SYNC_FLUSH marker Discord ends every complete message with
+var zlibSuffix = []byte{0x00, 0x00, 0xff, 0xff}
+
+func zlibComplete(buffer []byte) bool {
+	return bytes.HasSuffix(buffer, zlibSuffix)
+}
+
+// zlibWindowSize is the largest distance a deflate back reference may reach
+const zlibWindowSize = 32 * 1024
+
+// zlibInflater decompresses a zlib-stream connection where every message ends with a sync flush. The flate reader
+// can not continue after it runs out of input, so it is instead reset for every message with the previous output as
+// the dictionary, which keeps the shared compression context intact.
+type zlibInflater struct {
+	window  []byte
+	inflate io.ReadCloser
+}
+
+var _ inflater = &zlibInflater{}
+
+func (z *zlibInflater) Inflate(compressed []byte) ([]byte, error) {
+	if z.inflate == nil {
+		// the zlib header is only sent once, at the start of the connection
+		if len(compressed) < 2 || compressed[0]&0x0f != 8 || (uint16(compressed[0])<<8|uint16(compressed[1]))%31 != 0 {
+			return nil, errors.New("invalid zlib header")
+		}
+		if compressed[1]&0x20 != 0 {
+			return nil, errors.New("zlib preset dictionaries are not supported")
+		}
+
+		z.inflate = flate.NewReader(bytes.NewReader(compressed[2:]))
+	} else if err := z.inflate.(flate.Resetter).Reset(bytes.NewReader(compressed), z.window); err != nil {
+		return nil, err
+	}
+
+	// the stream is never finished, so the flate reader always ends with an unexpected EOF once the input is consumed
+	inflated, err := io.ReadAll(z.inflate)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("unable to inflate message. %w", err)
+	}
+
+	z.window = append(z.window, inflated...)
+	if len(z.window) > zlibWindowSize {
+		n := copy(z.window, z.window[len(z.window)-zlibWindowSize:])
+		z.window = z.window[:n]
+	}
+
+	return inflated, nil
+}
+
+func (z *zlibInflater) Close() error {
+	z.inflate, z.window = nil, nil
+	return nil
+}
+
+// zstdComplete every zstd-stream message ends with a flushed block, so no buffering across messages is needed
+func zstdComplete(_ []byte) bool {
+	return true
+}
+
+// zstdInflater decompresses a zstd-stream connection. The zstd decoder can not be reset without losing the frame
+// context, so it runs in a separate goroutine reading from the inflater itself. Every message is handed over to the
+// decoder, and once the decoder asks for more input the message has been fully decompressed.
+type zstdInflater struct {
+	input   chan []byte
+	drained chan struct{}
+	done    chan struct{}
+	err     error
+
+	// owned by the decoder goroutine between a hand-over and the drained signal
+	pending []byte
+	started bool
+	output  bytes.Buffer
+}
+
+var _ inflater = &zstdInflater{}
+
+func newZstdInflater() *zstdInflater {
+	z := &zstdInflater{
+		input:   make(chan []byte),
+		drained: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go z.decode()
+	return z
+}
+
+func (z *zstdInflater) decode() {
+	defer close(z.done)
+
+	decoder, err := zstd.NewReader(z, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+	if err != nil {
+		z.err = err
+		return
+	}
+	defer decoder.Close()
+
+	if _, err = io.Copy(&z.output, decoder); err != nil {
+		z.err = err
+	} else {
+		z.err = io.ErrUnexpectedEOF
+	}
+}
+
+// Read is used by the decoder, and blocks until the next message is handed over.
+func (z *zstdInflater) Read(p []byte) (int, error) {
+	if len(z.pending) == 0 {
+		if z.started {
+			z.drained <- struct{}{}
+		}
+
+		data, ok := <-z.input
+		if !ok {
+			return 0, io.EOF
+		}
+		z.started = true
+		z.pending = data
+	}
+
+	n := copy(p, z.pending)
+	z.pending = z.pending[n:]
+	return n, nil
+}
+
+func (z *zstdInflater) Inflate(compressed []byte) ([]byte, error) {
+	if len(compressed) == 0 {
+		return []byte{}, nil
+	}
+
+	select {
+	case z.input <- compressed:
+	case <-z.done:
+		return nil, fmt.Errorf("unable to inflate message. %w", z.err)
+	}
+
+	select {
+	case <-z.drained:
+	case <-z.done:
+		return nil, fmt.Errorf("unable to inflate message. %w", z.err)
+	}
+
+	inflated := make([]byte, z.output.Len())
+	copy(inflated, z.output.Bytes())
+	z.output.Reset()
+	return inflated, nil
+}
+
+func (z *zstdInflater) Close() error {
+	select {
+	case <-z.done:
+	default:
+		close(z.input)
+		<-z.done
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package gatewayutil
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/event"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zlibStreamMessages compresses the payloads as a single zlib stream, flushing after every payload like Discord does
+func zlibStreamMessages(t testing.TB, payloads ...string) [][]byte {
+	var buffer bytes.Buffer
+	writer := zlib.NewWriter(&buffer)
+
+	var messages [][]byte
+	for _, payload := range payloads {
+		if _, err := writer.Write([]byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		messages = append(messages, append([]byte{}, buffer.Bytes()...))
+		buffer.Reset()
+	}
+
+	return messages
+}
+
+// zstdStreamMessages compresses the payloads as a single zstd stream, flushing after every payload like Discord does
+func zstdStreamMessages(t testing.TB, payloads ...string) [][]byte {
+	var buffer bytes.Buffer
+	writer, err := zstd.NewWriter(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var messages [][]byte
+	for _, payload := range payloads {
+		if _, err := writer.Write([]byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		messages = append(messages, append([]byte{}, buffer.Bytes()...))
+		buffer.Reset()
+	}
+
+	return messages
+}
+
+var testPayloads = []string{
+	`{"op":10,"d":{"heartbeat_interval":41250}}`,
+	`{"op":11}`,
+	fmt.Sprintf(`{"op":0,"t":"GUILD_CREATE","d":{"name":"%s"}}`, strings.Repeat("abcdefghij", 8000)),
+	`{"op":0,"t":"MESSAGE_CREATE","d":{"content":"abcdefghijabcdefghij"}}`,
+	`{"op":11}`,
+}
+
+func TestZlibStream(t *testing.T) {
+	payloads := testPayloads
+
+	t.Run("messages", func(t *testing.T) {
+		stream, _ := newDecompressor(ZlibStream)
+		for i, message := range zlibStreamMessages(t, payloads...) {
+			inflated, err := stream.Decompress(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(inflated) != payloads[i] {
+				t.Errorf("payload %d was not correctly inflated", i)
+			}
+		}
+	})
+
+	t.Run("split messages", func(t *testing.T) {
+		stream, _ := newDecompressor(ZlibStream)
+		for i, message := range zlibStreamMessages(t, payloads...) {
+			half := len(message) / 2
+			inflated, err := stream.Decompress(message[:half])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inflated != nil {
+				t.Fatal("incomplete message should be buffered")
+			}
+
+			inflated, err = stream.Decompress(message[half:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(inflated) != payloads[i] {
+				t.Errorf("payload %d was not correctly inflated", i)
+			}
+		}
+	})
+
+	t.Run("corrupt header", func(t *testing.T) {
+		stream, _ := newDecompressor(ZlibStream)
+		if _, err := stream.Decompress(append([]byte("{}"), zlibSuffix...)); err == nil {
+			t.Error("expected invalid header error")
+		}
+	})
+}
+
+func TestZstdStream(t *testing.T) {
+	payloads := testPayloads
+
+	t.Run("messages", func(t *testing.T) {
+		stream, _ := newDecompressor(ZstdStream)
+		defer stream.Close()
+
+		for i, message := range zstdStreamMessages(t, payloads...) {
+			inflated, err := stream.Decompress(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(inflated) != payloads[i] {
+				t.Errorf("payload %d was not correctly inflated", i)
+			}
+		}
+	})
+
+	t.Run("corrupt stream", func(t *testing.T) {
+		stream, _ := newDecompressor(ZstdStream)
+		defer stream.Close()
+
+		if _, err := stream.Decompress([]byte("{\"op\":11}")); err == nil {
+			t.Error("expected invalid frame error")
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		stream, _ := newDecompressor(ZstdStream)
+		if err := stream.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// loadGatewayTraffic reads the sample of gateway payloads, one payload per line
+func loadGatewayTraffic(b *testing.B) (payloads []string, size int64) {
+	f, err := os.Open("testdata/gateway_traffic.jsonl")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		payloads = append(payloads, scanner.Text())
+		size += int64(len(scanner.Bytes()))
+	}
+	if err = scanner.Err(); err != nil {
+		b.Fatal(err)
+	}
+
+	return payloads, size
+}
+
+func BenchmarkTransportCompression(b *testing.B) {
+	payloads, size := loadGatewayTraffic(b)
+
+	benchmarks := []struct {
+		compression TransportCompression
+		messages    [][]byte
+	}{
+		{ZlibStream, zlibStreamMessages(b, payloads...)},
+		{ZstdStream, zstdStreamMessages(b, payloads...)},
+	}
+
+	for _, benchmark := range benchmarks {
+		var compressedSize int
+		for _, message := range benchmark.messages {
+			compressedSize += len(message)
+		}
+
+		b.Run(string(benchmark.compression), func(b *testing.B) {
+			b.SetBytes(size)
+			b.ReportMetric(float64(compressedSize)/float64(size), "ratio")
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				stream, _ := newDecompressor(benchmark.compression)
+				for _, message := range benchmark.messages {
+					if _, err := stream.Decompress(message); err != nil {
+						b.Fatal(err)
+					}
+				}
+				_ = stream.Close()
+			}
+		})
+	}
+}
+
+func TestShard_DialCompressionLeak(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	shard, err := NewShard(
+		gateway.WithBotToken("token"),
+		gateway.WithGuildEvents(event.MessageCreate),
+		gateway.WithCommandRateLimiter(NewCommandRateLimiter()),
+		gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		_, err = shard.Dial(context.Background(), func() (string, error) {
+			return "ws://" + address + "?v=10&encoding=json&compress=zstd-stream", nil
+		})
+		if err == nil {
+			t.Fatal("expected the dial to fail")
+		}
+	}
+
+	// the zstd decoder runs a goroutine, which must not be started for connections that failed
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("leaked goroutines, %d before and %d after the failed dials", before, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,423 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/gatewayutil/log"
+)
+
+var ErrDispatcherClosed = errors.New("dispatcher has been closed")
+
+// OverflowPolicy decides what happens to new events when the dispatch queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the event loop until there is room in the queue
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest queued event to make room for the new event
+	OverflowDropOldest
+	// OverflowDropNewest drops the new event
+	OverflowDropNewest
+)
+
+// DispatcherMetrics receives measurements of a Dispatcher. The Metrics of gatewayutil/prometheus implements it.
+type DispatcherMetrics interface {
+	// DispatchQueueDepth is called with the number of queued events, whenever it changes.
+	DispatchQueueDepth(depth int)
+
+	// DispatchDropped is called for every event dropped due to the overflow policy.
+	DispatchDropped(evt event.Type)
+}
+
+type DispatcherOption func(dispatcher *Dispatcher) error
+
+// WithWorkers sets the number of goroutines handling events. Defaults to GOMAXPROCS.
+func WithWorkers(workers int) DispatcherOption {
+	return func(dispatcher *Dispatcher) error {
+		if workers < 1 {
+			return errors.New("dispatcher requires at least one worker")
+		}
+		dispatcher.workers = workers
+		return nil
+	}
+}
+
+// WithQueueSize sets the maximum number of queued events, across every worker. Defaults to 1000.
+func WithQueueSize(size int) DispatcherOption {
+	return func(dispatcher *Dispatcher) error {
+		if size < 1 {
+			return errors.New("dispatch queue size must be 1 or higher")
+		}
+		dispatcher.queueSize = size
+		return nil
+	}
+}
+
+// WithOverflowPolicy decides what happens when the queue is full. Defaults to OverflowBlock.
+func WithOverflowPolicy(policy OverflowPolicy) DispatcherOption {
+	return func(dispatcher *Dispatcher) error {
+		dispatcher.policy = policy
+		return nil
+	}
+}
+
+// WithGuildOrdering guarantees that events of the same guild are handled in the order they were received, by
+// hashing the guild id of an event to a worker. Direct messages are ordered by channel id instead, see EventKey.
+// Each worker gets its own share of the queue, so a slow guild delays the other guilds of the same worker. The codec
+// must match the client codec. Events without a guild id are spread across the workers without any ordering
+// guarantees.
+func WithGuildOrdering(codec encoding.Codec) DispatcherOption {
+	return func(dispatcher *Dispatcher) error {
+		if codec == nil {
+			return errors.New("codec can not be nil")
+		}
+		dispatcher.codec, dispatcher.keyed = codec, false
+		return nil
+	}
+}
+
+// WithKeyOrdering guarantees that events with the same key, the guild id or the channel id for direct messages (see
+// EventKey), are handled one at a time in the order they were received. Unlike WithGuildOrdering, keys are not bound
+// to a worker: any free worker picks up the next event of a key, such that different guilds are always handled in
+// parallel. The codec must match the client codec. Events without a key are handled without any ordering guarantees.
+//
+// With OverflowDropOldest, the oldest event waiting for a free worker is dropped. When every queued event waits for
+// an earlier event of the same key, the new event is dropped instead.
+func WithKeyOrdering(codec encoding.Codec) DispatcherOption {
+	return func(dispatcher *Dispatcher) error {
+		if codec == nil {
+			return errors.New("codec can not be nil")
+		}
+		dispatcher.codec, dispatcher.keyed = codec, true
+		return nil
+	}
+}
+
+// WithDispatcherMetrics reports the queue depth and dropped events.
+func WithDispatcherMetrics(metrics DispatcherMetrics) DispatcherOption {
+	return func(dispatcher *Dispatcher) error {
+		dispatcher.metrics = metrics
+		return nil
+	}
+}
+
+// WithErrorHandler is called when the handler returns an error. By default, errors are logged.
+func WithErrorHandler(handler func(evt *gateway.Event, err error)) DispatcherOption {
+	return func(dispatcher *Dispatcher) error {
+		dispatcher.onError = handler
+		return nil
+	}
+}
+
+// NewDispatcher starts the workers of a dispatcher, which must be closed after use. The dispatcher is used as the
+// event handler of one or more clients:
+//
+//	dispatcher, err := gatewayutil.NewDispatcher(handler, gatewayutil.WithWorkers(8))
+//	defer dispatcher.Close()
+//
+//	shard, err := gatewayutil.NewShard(
+//		gateway.WithEventHandlerV2(dispatcher.Handle),
+//		// ...
+//	)
+func NewDispatcher(handler gateway.HandlerV2, options ...DispatcherOption) (*Dispatcher, error) {
+	if handler == nil {
+		return nil, errors.New("dispatcher requires a handler")
+	}
+
+	dispatcher := &Dispatcher{
+		handler:   handler,
+		workers:   runtime.GOMAXPROCS(0),
+		queueSize: 1000,
+		closed:    make(chan struct{}),
+	}
+	for i := range options {
+		if err := options[i](dispatcher); err != nil {
+			return nil, err
+		}
+	}
+
+	// a shared queue, unless events are ordered by guild
+	queues, size := 1, dispatcher.queueSize
+	if dispatcher.keyed {
+		dispatcher.slots = make(chan struct{}, dispatcher.queueSize)
+		dispatcher.pending = map[string][]*queuedEvent{}
+	} else if dispatcher.codec != nil {
+		queues, size = dispatcher.workers, dispatcher.queueSize/dispatcher.workers
+		if size < 1 {
+			size = 1
+		}
+	}
+	for i := 0; i < queues; i++ {
+		dispatcher.queues = append(dispatcher.queues, make(chan *queuedEvent, size))
+	}
+
+	for i := 0; i < dispatcher.workers; i++ {
+		dispatcher.wg.Add(1)
+		go dispatcher.work(dispatcher.queues[i%queues])
+	}
+	return dispatcher, nil
+}
+
+// Dispatcher hands events over to a pool of workers through a bounded queue, such that the event loop is not blocked
+// by slow handlers. Events are handled concurrently, see WithGuildOrdering for ordering guarantees.
+type Dispatcher struct {
+	handler   gateway.HandlerV2
+	workers   int
+	queueSize int
+	policy    OverflowPolicy
+	codec     encoding.Codec
+	keyed     bool
+	metrics   DispatcherMetrics
+	onError   func(evt *gateway.Event, err error)
+
+	queues []chan *queuedEvent
+	depth  atomic.Int64
+
+	// with key ordering, slots bound the number of queued events, and pending holds the events of each key that
+	// is being handled or queued, in order
+	slots     chan struct{}
+	pendingMu sync.Mutex
+	pending   map[string][]*queuedEvent
+
+	// mu guards the queues from being closed while events are queued
+	mu        sync.RWMutex
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+type queuedEvent struct {
+	ctx context.Context
+	evt *gateway.Event
+	key string
+}
+
+// Handle queues the event in accordance with the overflow policy, and is meant to be used as a gateway.HandlerV2.
+// An error is only returned when the dispatcher is closed, or the context is done while waiting for room in the
+// queue. The context is passed on to the handler.
+func (d *Dispatcher) Handle(ctx context.Context, evt *gateway.Event) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	select {
+	case <-d.closed:
+		return ErrDispatcherClosed
+	default:
+	}
+
+	if d.keyed {
+		return d.handleKeyed(ctx, &queuedEvent{ctx: ctx, evt: evt, key: EventKey(d.codec, evt.Type, evt.Data)})
+	}
+
+	queue := d.queues[d.queueIndex(evt)]
+	queued := &queuedEvent{ctx: ctx, evt: evt}
+
+	// the depth is increased up front, as a worker may pick up the event right away
+	d.setDepth(d.depth.Add(1))
+
+	switch d.policy {
+	case OverflowDropNewest:
+		select {
+		case queue <- queued:
+		default:
+			d.setDepth(d.depth.Add(-1))
+			d.dropped(evt)
+		}
+	case OverflowDropOldest:
+		for sent := false; !sent; {
+			select {
+			case queue <- queued:
+				sent = true
+			default:
+				select {
+				case oldest := <-queue:
+					d.setDepth(d.depth.Add(-1))
+					d.dropped(oldest.evt)
+				default:
+				}
+			}
+		}
+	default:
+		select {
+		case queue <- queued:
+		case <-ctx.Done():
+			d.setDepth(d.depth.Add(-1))
+			return ctx.Err()
+		case <-d.closed:
+			d.setDepth(d.depth.Add(-1))
+			return ErrDispatcherClosed
+		}
+	}
+	return nil
+}
+
+// Depth returns the number of queued events.
+func (d *Dispatcher) Depth() int {
+	return int(d.depth.Load())
+}
+
+// Close stops accepting events, and waits for the workers to handle every queued event.
+func (d *Dispatcher) Close() {
+	d.closeOnce.Do(func() {
+		close(d.closed)
+
+		d.mu.Lock()
+		for _, queue := range d.queues {
+			close(queue)
+		}
+		d.mu.Unlock()
+	})
+	d.wg.Wait()
+}
+
+// handleKeyed queues the event when no other event of the same key is queued or being handled, otherwise the event
+// is added to the pending events of the key
+func (d *Dispatcher) handleKeyed(ctx context.Context, queued *queuedEvent) error {
+	if err := d.acquireSlot(ctx, queued.evt); errors.Is(err, errDropped) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	d.setDepth(d.depth.Add(1))
+	if queued.key != "" {
+		d.pendingMu.Lock()
+		if pending, ok := d.pending[queued.key]; ok {
+			d.pending[queued.key] = append(pending, queued)
+			d.pendingMu.Unlock()
+			return nil
+		}
+		d.pending[queued.key] = nil
+		d.pendingMu.Unlock()
+	}
+
+	// the slots guarantee room in the queue
+	d.queues[0] <- queued
+	return nil
+}
+
+// errDropped signals that the event was dropped due to the overflow policy
+var errDropped = errors.New("event was dropped")
+
+// acquireSlot reserves room for one queued event, in accordance with the overflow policy
+func (d *Dispatcher) acquireSlot(ctx context.Context, evt *gateway.Event) error {
+	switch d.policy {
+	case OverflowDropNewest:
+		select {
+		case d.slots <- struct{}{}:
+			return nil
+		default:
+			d.dropped(evt)
+			return errDropped
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case d.slots <- struct{}{}:
+				return nil
+			default:
+			}
+
+			select {
+			case oldest := <-d.queues[0]:
+				<-d.slots
+				d.setDepth(d.depth.Add(-1))
+				d.dropped(oldest.evt)
+				if next := d.next(oldest.key); next != nil {
+					d.queues[0] <- next
+				}
+			default:
+				// every queued event waits for an earlier event of the same key
+				d.dropped(evt)
+				return errDropped
+			}
+		}
+	default:
+		select {
+		case d.slots <- struct{}{}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.closed:
+			return ErrDispatcherClosed
+		}
+	}
+}
+
+// next returns the next pending event of the key, or nil when the key has no pending events left
+func (d *Dispatcher) next(key string) *queuedEvent {
+	if key == "" {
+		return nil
+	}
+
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+
+	pending := d.pending[key]
+	if len(pending) == 0 {
+		delete(d.pending, key)
+		return nil
+	}
+	d.pending[key] = pending[1:]
+	return pending[0]
+}
+
+func (d *Dispatcher) work(queue <-chan *queuedEvent) {
+	defer d.wg.Done()
+	for queued := range queue {
+		// with key ordering, the worker continues with the pending events of the same key
+		for ; queued != nil; queued = d.next(queued.key) {
+			if d.keyed {
+				<-d.slots
+			}
+			d.setDepth(d.depth.Add(-1))
+			d.handle(queued)
+		}
+	}
+}
+
+func (d *Dispatcher) handle(queued *queuedEvent) {
+	if err := d.handler(queued.ctx, queued.evt); err != nil {
+		if d.onError != nil {
+			d.onError(queued.evt, err)
+		} else {
+			log.Error("dispatcher failed to handle %s event with sequence number %d: %s", queued.evt.Type, queued.evt.Seq, err)
+		}
+	}
+}
+
+// queueIndex hashes the guild id to a queue, when events are ordered by guild
+func (d *Dispatcher) queueIndex(evt *gateway.Event) int {
+	if len(d.queues) == 1 {
+		return 0
+	}
+
+	key := EventKey(d.codec, evt.Type, evt.Data)
+	if key == "" {
+		// no ordering is required, so the sequence number spreads the events
+		return int(uint64(evt.Seq) % uint64(len(d.queues)))
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(len(d.queues)))
+}
+
+func (d *Dispatcher) setDepth(depth int64) {
+	if d.metrics != nil {
+		d.metrics.DispatchQueueDepth(int(depth))
+	}
+}
+
+func (d *Dispatcher) dropped(evt *gateway.Event) {
+	if d.metrics != nil {
+		d.metrics.DispatchDropped(evt.Type)
+	}
+}
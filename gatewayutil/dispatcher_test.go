@@ -0,0 +1,264 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+)
+
+type droppedMetrics struct {
+	mu      sync.Mutex
+	dropped []event.Type
+}
+
+func (m *droppedMetrics) DispatchQueueDepth(_ int) {}
+
+func (m *droppedMetrics) DispatchDropped(evt event.Type) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped = append(m.dropped, evt)
+}
+
+// blockingHandler records the sequence numbers it handled, and blocks until released
+type blockingHandler struct {
+	mu      sync.Mutex
+	handled []int64
+	started chan int64
+	release chan struct{}
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{started: make(chan int64, 100), release: make(chan struct{})}
+}
+
+func (h *blockingHandler) Handle(_ context.Context, evt *gateway.Event) error {
+	h.started <- evt.Seq
+	<-h.release
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handled = append(h.handled, evt.Seq)
+	return nil
+}
+
+func newDispatcher(t *testing.T, handler gateway.HandlerV2, options ...DispatcherOption) *Dispatcher {
+	dispatcher, err := NewDispatcher(handler, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(dispatcher.Close)
+	return dispatcher
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("concurrent", func(t *testing.T) {
+		handler := newBlockingHandler()
+		dispatcher := newDispatcher(t, handler.Handle, WithWorkers(4))
+		defer close(handler.release)
+
+		for i := 1; i <= 4; i++ {
+			if err := dispatcher.Handle(context.Background(), &gateway.Event{Seq: int64(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := 0; i < 4; i++ {
+			select {
+			case <-handler.started:
+			case <-time.After(time.Second):
+				t.Fatal("expected every worker to handle an event concurrently")
+			}
+		}
+	})
+
+	overflow := []struct {
+		policy  OverflowPolicy
+		handled string
+		dropped int
+	}{
+		{OverflowDropNewest, "[1 2]", 1},
+		{OverflowDropOldest, "[1 3]", 1},
+	}
+	for _, test := range overflow {
+		t.Run(fmt.Sprintf("overflow policy %d", test.policy), func(t *testing.T) {
+			handler, metrics := newBlockingHandler(), &droppedMetrics{}
+			dispatcher := newDispatcher(t, handler.Handle,
+				WithWorkers(1),
+				WithQueueSize(1),
+				WithOverflowPolicy(test.policy),
+				WithDispatcherMetrics(metrics),
+			)
+
+			ctx := context.Background()
+			_ = dispatcher.Handle(ctx, &gateway.Event{Seq: 1})
+			<-handler.started
+			_ = dispatcher.Handle(ctx, &gateway.Event{Seq: 2})
+			_ = dispatcher.Handle(ctx, &gateway.Event{Seq: 3})
+			if dispatcher.Depth() != 1 {
+				t.Errorf("expected one queued event, got %d", dispatcher.Depth())
+			}
+
+			close(handler.release)
+			dispatcher.Close()
+			if got := fmt.Sprint(handler.handled); got != test.handled {
+				t.Errorf("incorrect events handled. Got %s, wants %s", got, test.handled)
+			}
+			if len(metrics.dropped) != test.dropped {
+				t.Errorf("expected %d dropped events, got %d", test.dropped, len(metrics.dropped))
+			}
+		})
+	}
+
+	t.Run("block", func(t *testing.T) {
+		handler := newBlockingHandler()
+		dispatcher := newDispatcher(t, handler.Handle, WithWorkers(1), WithQueueSize(1))
+		defer close(handler.release)
+
+		_ = dispatcher.Handle(context.Background(), &gateway.Event{Seq: 1})
+		<-handler.started
+		_ = dispatcher.Handle(context.Background(), &gateway.Event{Seq: 2})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := dispatcher.Handle(ctx, &gateway.Event{Seq: 3}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the full queue to block until the context is done, got %v", err)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		var errs []error
+		dispatcher := newDispatcher(t, func(_ context.Context, evt *gateway.Event) error {
+			return fmt.Errorf("failed %d", evt.Seq)
+		}, WithErrorHandler(func(_ *gateway.Event, err error) {
+			errs = append(errs, err)
+		}), WithWorkers(1))
+
+		for i := 1; i <= 3; i++ {
+			_ = dispatcher.Handle(context.Background(), &gateway.Event{Seq: int64(i)})
+		}
+		dispatcher.Close()
+
+		if len(errs) != 3 {
+			t.Errorf("expected queued events to be handled before closing. Got %v", errs)
+		}
+		if err := dispatcher.Handle(context.Background(), &gateway.Event{}); !errors.Is(err, ErrDispatcherClosed) {
+			t.Errorf("expected closed error, got %v", err)
+		}
+	})
+}
+
+func TestDispatcher_Ordering(t *testing.T) {
+	orderings := map[string]func(codec encoding.Codec) DispatcherOption{
+		"guild": WithGuildOrdering,
+		"key":   WithKeyOrdering,
+	}
+
+	for name, ordering := range orderings {
+		for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+			t.Run(name+"/"+codec.Name(), func(t *testing.T) {
+				testOrdering(t, codec, ordering(codec))
+			})
+		}
+	}
+}
+
+func testOrdering(t *testing.T, codec encoding.Codec, ordering DispatcherOption) {
+	var mu sync.Mutex
+	handled := map[string][]int64{}
+
+	dispatcher := newDispatcher(t, func(_ context.Context, evt *gateway.Event) error {
+		var data struct {
+			GuildID event.Snowflake `json:"guild_id"`
+		}
+		if err := codec.Unmarshal(evt.Data, &data); err != nil {
+			return err
+		}
+		time.Sleep(time.Duration(rand.Intn(200)) * time.Microsecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		handled[string(data.GuildID)] = append(handled[string(data.GuildID)], evt.Seq)
+		return nil
+	}, WithWorkers(4), WithQueueSize(8), ordering)
+
+	guilds := []string{"1", "2", "3", "4", "5"}
+	for seq := int64(1); seq <= 200; seq++ {
+		data, err := codec.Marshal(map[string]interface{}{"guild_id": guilds[seq%int64(len(guilds))]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = dispatcher.Handle(context.Background(), &gateway.Event{Seq: seq, Data: data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dispatcher.Close()
+
+	for guild, seqs := range handled {
+		if len(seqs) != 40 {
+			t.Errorf("guild %s: expected 40 events, got %d", guild, len(seqs))
+		}
+		for i := 1; i < len(seqs); i++ {
+			if seqs[i] < seqs[i-1] {
+				t.Fatalf("guild %s: events were handled out of order: %v", guild, seqs)
+			}
+		}
+	}
+}
+
+func TestDispatcher_KeyOrdering(t *testing.T) {
+	var mu sync.Mutex
+	var handled []int64
+	started := make(chan int64, 10)
+	release := make(chan struct{})
+
+	dispatcher := newDispatcher(t, func(_ context.Context, evt *gateway.Event) error {
+		started <- evt.Seq
+		if string(evt.Data) == `{"guild_id":"slow"}` {
+			<-release
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, evt.Seq)
+		return nil
+	}, WithWorkers(2), WithKeyOrdering(encoding.JSONCodec{}))
+
+	events := []string{`{"guild_id":"slow"}`, `{"guild_id":"slow"}`, `{"guild_id":"fast"}`, `{"channel_id":"fast"}`}
+	for i, data := range events {
+		if err := dispatcher.Handle(context.Background(), &gateway.Event{Seq: int64(i + 1), Data: []byte(data)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the second worker handles the other key, while the first event of the slow key blocks
+	var seqs []int64
+	for len(seqs) < 3 {
+		select {
+		case seq := <-started:
+			seqs = append(seqs, seq)
+		case <-time.After(time.Second):
+			t.Fatalf("expected the fast key to be handled while the slow key is blocked. Got %v", seqs)
+		}
+	}
+	if sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] }); fmt.Sprint(seqs) != "[1 3 4]" {
+		t.Fatalf("expected events 1, 3 and 4 to be handled, got %v", seqs)
+	}
+	if dispatcher.Depth() != 1 {
+		t.Errorf("expected the second event of the slow key to be queued, got depth %d", dispatcher.Depth())
+	}
+
+	close(release)
+	dispatcher.Close()
+	if got := fmt.Sprint(handled); got != "[3 4 1 2]" {
+		t.Errorf("incorrect order. Got %s", got)
+	}
+}
@@ -0,0 +1,154 @@
+package gatewayutil
+
+import (
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/event"
+)
+
+// EventKey returns the id that orders an event: the guild id, or the channel id for events outside of guilds such
+// as direct messages. An empty string is returned when the event has neither. The guild events GUILD_CREATE,
+// GUILD_UPDATE and GUILD_DELETE hold the guild itself, so their id is used.
+//
+// Json payloads are not decoded, only the top level keys are scanned until the guild id is found. Other encodings
+// are decoded using the codec.
+func EventKey(codec encoding.Codec, evt event.Type, data encoding.RawMessage) string {
+	guildKey := "guild_id"
+	switch evt {
+	case event.GuildCreate, event.GuildUpdate, event.GuildDelete:
+		guildKey = "id"
+	}
+
+	if codec.Name() == "json" {
+		guildID, channelID := scanJSONKeys(data, guildKey, "channel_id")
+		if guildID != "" {
+			return guildID
+		}
+		return channelID
+	}
+
+	var ids struct {
+		ID        event.Snowflake `json:"id"`
+		GuildID   event.Snowflake `json:"guild_id"`
+		ChannelID event.Snowflake `json:"channel_id"`
+	}
+	if err := codec.Unmarshal(data, &ids); err != nil {
+		return ""
+	}
+
+	guildID := ids.GuildID
+	if guildKey == "id" {
+		guildID = ids.ID
+	}
+	if guildID != "" {
+		return string(guildID)
+	}
+	return string(ids.ChannelID)
+}
+
+// scanJSONKeys finds the string values of two top level keys in a json object, and stops as soon as the first key
+// is found. Values that are not strings, and malformed json, result in empty strings.
+func scanJSONKeys(data []byte, first, second string) (firstValue, secondValue string) {
+	i := skipJSONSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return "", ""
+	}
+	i++
+
+	for {
+		i = skipJSONSpace(data, i)
+		if i >= len(data) || data[i] != '"' {
+			return "", secondValue
+		}
+
+		keyStart := i + 1
+		if i = skipJSONString(data, i); i < 0 {
+			return "", secondValue
+		}
+		key := data[keyStart : i-1]
+
+		i = skipJSONSpace(data, i)
+		if i >= len(data) || data[i] != ':' {
+			return "", secondValue
+		}
+		i = skipJSONSpace(data, i+1)
+
+		valueStart := i
+		if i = skipJSONValue(data, i); i < 0 {
+			return "", secondValue
+		}
+		if value := data[valueStart:i]; len(value) >= 2 && value[0] == '"' {
+			switch string(key) {
+			case first:
+				return string(value[1 : len(value)-1]), secondValue
+			case second:
+				secondValue = string(value[1 : len(value)-1])
+			}
+		}
+
+		i = skipJSONSpace(data, i)
+		if i >= len(data) || data[i] != ',' {
+			// either the end of the object or malformed json
+			return "", secondValue
+		}
+		i++
+	}
+}
+
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// skipJSONString returns the index after the closing quote of the string starting at i, or -1
+func skipJSONString(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// skipJSONValue returns the index after the value starting at i, or -1
+func skipJSONValue(data []byte, i int) int {
+	if i >= len(data) {
+		return -1
+	}
+
+	switch data[i] {
+	case '"':
+		return skipJSONString(data, i)
+	case '{', '[':
+		depth := 0
+		for ; i < len(data); i++ {
+			switch data[i] {
+			case '"':
+				if i = skipJSONString(data, i); i < 0 {
+					return -1
+				}
+				i--
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return -1
+	default:
+		// numbers, booleans and null
+		for ; i < len(data); i++ {
+			switch data[i] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				return i
+			}
+		}
+		return i
+	}
+}
@@ -0,0 +1,87 @@
+package gatewayutil
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+)
+
+func TestEventKey(t *testing.T) {
+	tests := []struct {
+		name string
+		evt  event.Type
+		data string
+		key  string
+	}{
+		{
+			name: "guild id after nested guild ids",
+			evt:  event.MessageCreate,
+			data: `{"message_reference":{"guild_id":"1","channel_id":"2"},"content":"\"guild_id\":\"3\" {[","channel_id":"4","guild_id":"5"}`,
+			key:  "5",
+		},
+		{
+			name: "direct message",
+			evt:  event.MessageCreate,
+			data: `{"id":"1", "channel_id" : "2", "mentions":[{"id":"3"}], "tts":false, "nonce":12}`,
+			key:  "2",
+		},
+		{
+			name: "guild",
+			evt:  event.GuildCreate,
+			data: `{"id":"1","channels":[{"id":"2","guild_id":"1"}]}`,
+			key:  "1",
+		},
+		{
+			name: "null guild id",
+			evt:  event.TypingStart,
+			data: `{"guild_id":null,"channel_id":"2"}`,
+			key:  "2",
+		},
+		{
+			name: "no key",
+			evt:  event.Ready,
+			data: `{"v":10,"user":{"id":"1"}}`,
+			key:  "",
+		},
+		{
+			name: "not an object",
+			evt:  event.MessageCreate,
+			data: `["guild_id","1"]`,
+			key:  "",
+		},
+	}
+
+	for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+		for _, test := range tests {
+			t.Run(codec.Name()+"/"+test.name, func(t *testing.T) {
+				data := []byte(test.data)
+				if codec.Name() != "json" {
+					var v interface{}
+					if err := json.Unmarshal(data, &v); err != nil {
+						t.Fatal(err)
+					}
+
+					var err error
+					if data, err = codec.Marshal(v); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				if key := EventKey(codec, test.evt, data); key != test.key {
+					t.Errorf("incorrect key. Got '%s', wants '%s'", key, test.key)
+				}
+			})
+		}
+	}
+}
+
+func TestEventKey_Malformed(t *testing.T) {
+	for _, data := range []string{``, `{`, `{"guild_id"`, `{"guild_id":`, `{"guild_id":"1`, `{"a":{"b":"}`, `{"a":1 "guild_id":"1"}`} {
+		if key := EventKey(encoding.JSONCodec{}, event.MessageCreate, []byte(data)); key != "" {
+			t.Errorf("%s: expected no key, got '%s'", data, key)
+		}
+	}
+}
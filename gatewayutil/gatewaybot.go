@@ -0,0 +1,288 @@
+package gatewayutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAPIURL is the base url of the Discord REST api used by GatewayBotClient
+const DefaultAPIURL = "https://discord.com/api/v10"
+
+const defaultGatewayBotCacheTTL = 5 * time.Minute
+const defaultGatewayBotTimeout = 10 * time.Second
+
+var ErrGatewayBotRequest = errors.New("get gateway bot request failed")
+var ErrSessionStartLimit = errors.New("session start limit reached")
+
+// GatewayBotError is returned when the "Get Gateway Bot" request fails. It matches ErrGatewayBotRequest using
+// errors.Is, and unwraps to the cause such as a cancelled context.
+type GatewayBotError struct {
+	Err error
+}
+
+func (e *GatewayBotError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrGatewayBotRequest, e.Err)
+}
+
+func (e *GatewayBotError) Is(target error) bool {
+	return target == ErrGatewayBotRequest
+}
+
+func (e *GatewayBotError) Unwrap() error {
+	return e.Err
+}
+
+// SessionStartLimit describes how many sessions can be identified before the limit resets. Identifying more sessions
+// than the remaining amount causes Discord to reset the bot token.
+type SessionStartLimit struct {
+	Total          int
+	Remaining      int
+	ResetAfter     time.Duration
+	MaxConcurrency int
+}
+
+// GatewayBot is the response of the "Get Gateway Bot" endpoint.
+//
+// See https://discord.com/developers/docs/topics/gateway#get-gateway-bot
+type GatewayBot struct {
+	URL               string
+	Shards            int
+	SessionStartLimit SessionStartLimit
+}
+
+type gatewayBotResponse struct {
+	URL               string `json:"url"`
+	Shards            int    `json:"shards"`
+	SessionStartLimit struct {
+		Total          int   `json:"total"`
+		Remaining      int   `json:"remaining"`
+		ResetAfter     int64 `json:"reset_after"`
+		MaxConcurrency int   `json:"max_concurrency"`
+	} `json:"session_start_limit"`
+}
+
+type GatewayBotOption func(client *GatewayBotClient) error
+
+// WithAPIURL sets the base url of the REST api, such as an httptest server or a proxy. Defaults to DefaultAPIURL.
+func WithAPIURL(baseURL string) GatewayBotOption {
+	return func(client *GatewayBotClient) error {
+		if baseURL == "" {
+			return errors.New("api url must be set")
+		}
+
+		client.baseURL = strings.TrimSuffix(baseURL, "/")
+		return nil
+	}
+}
+
+// WithHTTPClient sets the http client used for the requests. Defaults to a http client with a timeout of 10 seconds.
+func WithHTTPClient(httpClient *http.Client) GatewayBotOption {
+	return func(client *GatewayBotClient) error {
+		if httpClient == nil {
+			return errors.New("http client must be set")
+		}
+
+		client.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithGatewayBotCacheTTL sets how long a response is reused before the endpoint is called again. Defaults to 5
+// minutes, and a ttl of 0 disables the cache.
+func WithGatewayBotCacheTTL(ttl time.Duration) GatewayBotOption {
+	return func(client *GatewayBotClient) error {
+		if ttl < 0 {
+			return errors.New("cache ttl must be 0 or higher")
+		}
+
+		client.ttl = ttl
+		return nil
+	}
+}
+
+// NewGatewayBotClient creates a client for the "Get Gateway Bot" endpoint, authorized with the given bot token.
+func NewGatewayBotClient(botToken string, options ...GatewayBotOption) (*GatewayBotClient, error) {
+	if botToken == "" {
+		return nil, errors.New("missing bot token")
+	}
+
+	client := &GatewayBotClient{
+		token:      botToken,
+		baseURL:    DefaultAPIURL,
+		httpClient: &http.Client{Timeout: defaultGatewayBotTimeout},
+		ttl:        defaultGatewayBotCacheTTL,
+	}
+	for i := range options {
+		if err := options[i](client); err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+// GatewayBotClient calls the "Get Gateway Bot" endpoint and caches the response. Use the URL method as the
+// GetGatewayBotURL of Shard.Dial, Shard.Run and ShardManager.Start, which counts every call as an identify against
+// the session start limit. Shards only request a url to identify, resumes use the resume url of the session.
+type GatewayBotClient struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+	ttl        time.Duration
+
+	mu        sync.Mutex
+	cached    *GatewayBot
+	fetchedAt time.Time
+}
+
+// Get returns the cached response, or calls the endpoint when the cache expired. The remaining session starts are
+// reduced by the identifies counted since the response was fetched.
+func (c *GatewayBotClient) Get(ctx context.Context) (*GatewayBot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.get(ctx); err != nil {
+		return nil, err
+	}
+	gatewayBot := *c.cached
+	return &gatewayBot, nil
+}
+
+// Refresh calls the endpoint regardless of the cache, such as after identifying many sessions.
+func (c *GatewayBotClient) Refresh(ctx context.Context) (*GatewayBot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.fetch(ctx); err != nil {
+		return nil, err
+	}
+	gatewayBot := *c.cached
+	return &gatewayBot, nil
+}
+
+func (c *GatewayBotClient) get(ctx context.Context) error {
+	if c.cached != nil && time.Since(c.fetchedAt) < c.ttl {
+		return nil
+	}
+	return c.fetch(ctx)
+}
+
+func (c *GatewayBotClient) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/gateway/bot", nil)
+	if err != nil {
+		return &GatewayBotError{Err: err}
+	}
+	req.Header.Set("Authorization", "Bot "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &GatewayBotError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &GatewayBotError{Err: err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &GatewayBotError{Err: fmt.Errorf("status %s: %s", resp.Status, body)}
+	}
+
+	var payload gatewayBotResponse
+	if err = json.Unmarshal(body, &payload); err != nil {
+		return &GatewayBotError{Err: err}
+	}
+	if payload.URL == "" {
+		return &GatewayBotError{Err: errors.New("response is missing the gateway url")}
+	}
+
+	limit := payload.SessionStartLimit
+	c.cached = &GatewayBot{
+		URL:    payload.URL,
+		Shards: payload.Shards,
+		SessionStartLimit: SessionStartLimit{
+			Total:          limit.Total,
+			Remaining:      limit.Remaining,
+			ResetAfter:     time.Duration(limit.ResetAfter) * time.Millisecond,
+			MaxConcurrency: limit.MaxConcurrency,
+		},
+	}
+	c.fetchedAt = time.Now()
+	return nil
+}
+
+// URL returns the gateway url completed by DialURL, and satisfies GetGatewayBotURL. Every call counts as an identify,
+// and once no session starts remain ErrSessionStartLimit is returned until the limit resets, as identifying anyway
+// causes Discord to reset the bot token. See URLContext to cancel the request.
+func (c *GatewayBotClient) URL() (string, error) {
+	return c.URLContext(context.Background())
+}
+
+// URLContext is URL with a context for the "Get Gateway Bot" request, as the response may be outdated.
+func (c *GatewayBotClient) URLContext(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.get(ctx); err != nil {
+		return "", err
+	}
+
+	limit := &c.cached.SessionStartLimit
+	if limit.Remaining <= 0 {
+		resetAt := c.fetchedAt.Add(limit.ResetAfter)
+		if wait := time.Until(resetAt); wait > 0 {
+			return "", fmt.Errorf("%w, resets in %s", ErrSessionStartLimit, wait.Round(time.Second))
+		}
+
+		// the limit has been reset since the response was fetched
+		if err := c.fetch(ctx); err != nil {
+			return "", err
+		}
+		if limit = &c.cached.SessionStartLimit; limit.Remaining <= 0 {
+			return "", fmt.Errorf("%w, resets in %s", ErrSessionStartLimit, limit.ResetAfter.Round(time.Second))
+		}
+	}
+
+	limit.Remaining--
+	return DialURL(c.cached.URL)
+}
+
+// URLFunc returns a GetGatewayBotURL that uses the given context for the requests, such that cancelling the context
+// of Shard.Run or ShardManager.Start also cancels an ongoing request.
+func (c *GatewayBotClient) URLFunc(ctx context.Context) GetGatewayBotURL {
+	return func() (string, error) {
+		return c.URLContext(ctx)
+	}
+}
+
+// IdentifyRateLimiter creates an identify rate limiter from the max_concurrency of the session start limit, for shards
+// that are not run by a ShardManager. See NewShardedIdentifyRateLimiter.
+func (c *GatewayBotClient) IdentifyRateLimiter(ctx context.Context) (*ShardedIdentifyRateLimiter, error) {
+	gatewayBot, err := c.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewShardedIdentifyRateLimiter(gatewayBot.SessionStartLimit.MaxConcurrency), nil
+}
+
+// ShardManager creates a shard manager for the recommended number of shards, which identifies in accordance with the
+// max_concurrency of the session start limit. The options are applied afterwards, such as WithShardRange. When the
+// shards are spread across processes, the shard count must be fixed, so use NewShardManager with WithMaxConcurrency.
+func (c *GatewayBotClient) ShardManager(ctx context.Context, options ...ShardManagerOption) (*ShardManager, error) {
+	gatewayBot, err := c.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxConcurrency := gatewayBot.SessionStartLimit.MaxConcurrency; maxConcurrency > 0 {
+		options = append([]ShardManagerOption{WithMaxConcurrency(maxConcurrency)}, options...)
+	}
+	return NewShardManager(gatewayBot.Shards, options...)
+}
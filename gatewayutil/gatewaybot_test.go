@@ -0,0 +1,133 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGatewayBotClient(t *testing.T) {
+	var requests int
+	server := newGatewayBotServer(t, &requests, 998, 3600000)
+
+	client, err := NewGatewayBotClient("token", WithAPIURL(server.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gatewayBot, err := client.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wants := GatewayBot{
+		URL:    "wss://gateway.discord.gg",
+		Shards: 4,
+		SessionStartLimit: SessionStartLimit{
+			Total:          1000,
+			Remaining:      998,
+			ResetAfter:     time.Hour,
+			MaxConcurrency: 2,
+		},
+	}
+	if *gatewayBot != wants {
+		t.Errorf("incorrect response. Got %+v, wants %+v", *gatewayBot, wants)
+	}
+
+	u, err := client.URL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "wss://gateway.discord.gg?encoding=json&v=10" {
+		t.Errorf("incorrect dial url. Got %s", u)
+	}
+
+	limiter, err := client.IdentifyRateLimiter(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limiter.buckets) != 2 {
+		t.Errorf("expected one bucket per max_concurrency, got %d", len(limiter.buckets))
+	}
+
+	manager, err := client.ShardManager(context.Background(), WithShardRange(0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manager.totalNumberOfShards != 4 || manager.maxConcurrency != 2 || len(manager.ids) != 2 {
+		t.Errorf("expected a manager for the recommended shards and max_concurrency. Got %+v", manager)
+	}
+	if requests != 1 {
+		t.Errorf("expected the response to be cached, got %d requests", requests)
+	}
+
+	if gatewayBot, err = client.Get(context.Background()); err != nil || gatewayBot.SessionStartLimit.Remaining != 997 {
+		t.Errorf("expected the dial url to count as an identify. Got %+v (%v)", gatewayBot, err)
+	}
+
+	if _, err = client.Refresh(context.Background()); err != nil || requests != 2 {
+		t.Errorf("expected refresh to call the endpoint, got %d requests (%v)", requests, err)
+	}
+
+	unauthorized, err := NewGatewayBotClient("invalid", WithAPIURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = unauthorized.URL(); !errors.Is(err, ErrGatewayBotRequest) {
+		t.Errorf("expected a request error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = client.Refresh(ctx); !errors.Is(err, ErrGatewayBotRequest) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the request error to wrap the cancelled context, got %v", err)
+	}
+	if _, err = unauthorized.URLFunc(ctx)(); !errors.Is(err, ErrGatewayBotRequest) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the url request to use the cancelled context, got %v", err)
+	}
+	if client.httpClient.Timeout != defaultGatewayBotTimeout {
+		t.Errorf("expected the default http client to time out, got %s", client.httpClient.Timeout)
+	}
+}
+
+func TestGatewayBotClient_SessionStartLimit(t *testing.T) {
+	var requests int
+	server := newGatewayBotServer(t, &requests, 1, 50)
+
+	client, err := NewGatewayBotClient("token", WithAPIURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.URL(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.URL(); !errors.Is(err, ErrSessionStartLimit) {
+		t.Fatalf("expected the session start limit to be reached, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err = client.URL(); err != nil {
+		t.Errorf("expected the limit to be reset, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected the endpoint to be called again once the limit reset, got %d requests", requests)
+	}
+}
+
+func newGatewayBotServer(t *testing.T, requests *int, remaining, resetAfter int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.URL.Path != "/gateway/bot" || r.Header.Get("Authorization") != "Bot token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"url":"wss://gateway.discord.gg","shards":4,"session_start_limit":{"total":1000,"remaining":%d,"reset_after":%d,"max_concurrency":2}}`,
+			remaining, resetAfter)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
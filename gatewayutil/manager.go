@@ -0,0 +1,266 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/gatewayutil/log"
+)
+
+var ErrManagerStarted = errors.New("shard manager has already been started")
+var ErrManagerNotStarted = errors.New("shard manager has not been started")
+
+type ShardState string
+
+const (
+	// ShardWaiting the shard is waiting for its identify slot
+	ShardWaiting ShardState = "waiting"
+	// ShardConnecting the shard is dialing the gateway
+	ShardConnecting ShardState = "connecting"
+	// ShardRunning the shard is connected and processing events
+	ShardRunning ShardState = "running"
+	// ShardReconnecting the shard lost the connection due to a recoverable error, and will reconnect after a delay
+	ShardReconnecting ShardState = "reconnecting"
+	// ShardStopped the shard was stopped as its context was cancelled
+	ShardStopped ShardState = "stopped"
+	// ShardFailed the shard stopped due to an error that can not be recovered from, see ShardStatus.Err
+	ShardFailed ShardState = "failed"
+)
+
+// ShardStatus is a snapshot of a supervised shard
+type ShardStatus struct {
+	ID       gateway.ShardID
+	State    ShardState
+	Restarts int
+
+	// Err is the last error that caused the shard to disconnect
+	Err error
+}
+
+type ShardManagerOption func(manager *ShardManager) error
+
+// WithShardRange limits the manager to the shard ids from first to last (inclusive). Use this when the shards are
+// spread across multiple processes. By default, every shard id is run by the manager.
+func WithShardRange(first, last gateway.ShardID) ShardManagerOption {
+	return func(manager *ShardManager) error {
+		if first > last {
+			return errors.New("first shard id must be lower or equal to the last shard id")
+		}
+		if int(last) >= manager.totalNumberOfShards {
+			return errors.New("shard range exceeds the shard count")
+		}
+
+		manager.ids = manager.ids[:0]
+		for id := first; id <= last; id++ {
+			manager.ids = append(manager.ids, id)
+		}
+		return nil
+	}
+}
+
+// WithMaxConcurrency sets the number of shards that may identify at the same time. See the max_concurrency field
+// from the "Get Gateway Bot" endpoint. Defaults to 1.
+func WithMaxConcurrency(maxConcurrency int) ShardManagerOption {
+	return func(manager *ShardManager) error {
+		if maxConcurrency < 1 {
+			return errors.New("max concurrency must be 1 or higher")
+		}
+
+		manager.maxConcurrency = maxConcurrency
+		return nil
+	}
+}
+
+// WithShardOptions specifies the gateway options used for every shard. The manager takes care of setting the
+// shard info and the rate limiters, although the rate limiters can be overwritten.
+func WithShardOptions(options ...gateway.Option) ShardManagerOption {
+	return func(manager *ShardManager) error {
+		manager.options = append(manager.options, options...)
+		return nil
+	}
+}
+
+// NewShardManager creates a manager for running and supervising multiple shards in the same process.
+func NewShardManager(totalNumberOfShards int, options ...ShardManagerOption) (*ShardManager, error) {
+	if totalNumberOfShards < 1 {
+		return nil, errors.New("shard count must be 1 or higher")
+	}
+
+	manager := &ShardManager{
+		totalNumberOfShards: totalNumberOfShards,
+		maxConcurrency:      1,
+	}
+	for id := 0; id < totalNumberOfShards; id++ {
+		manager.ids = append(manager.ids, gateway.ShardID(id))
+	}
+
+	for i := range options {
+		if err := options[i](manager); err != nil {
+			return nil, err
+		}
+	}
+
+	return manager, nil
+}
+
+// ShardManager creates one Shard per shard id and keeps them connected. Shards are started in accordance with the
+// max_concurrency identify buckets, and are restarted whenever they disconnect due to a recoverable error.
+type ShardManager struct {
+	totalNumberOfShards int
+	maxConcurrency      int
+	ids                 []gateway.ShardID
+	options             []gateway.Option
+
+	mu     sync.RWMutex
+	shards map[gateway.ShardID]*managedShard
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type managedShard struct {
+	shard  *Shard
+	status ShardStatus
+}
+
+// Start creates the shards and connects them to Discord in the background. Shards in the same identify bucket are
+// started 5 seconds apart. Use Stop to disconnect the shards.
+func (m *ShardManager) Start(ctx context.Context, getURL GetGatewayBotURL) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.shards != nil {
+		return ErrManagerStarted
+	}
+
+	identifyRateLimiter := NewShardedIdentifyRateLimiter(m.maxConcurrency)
+
+	shards := make(map[gateway.ShardID]*managedShard, len(m.ids))
+	for _, id := range m.ids {
+		options := []gateway.Option{
+			gateway.WithCommandRateLimiter(NewCommandRateLimiter()),
+			gateway.WithIdentifyRateLimiter(identifyRateLimiter),
+		}
+		options = append(options, m.options...)
+		options = append(options, gateway.WithShardInfo(id, m.totalNumberOfShards))
+
+		shard, err := NewShard(options...)
+		if err != nil {
+			return err
+		}
+
+		id := id
+		shard.onStateChange = func(state ShardState, err error) {
+			m.setStatus(id, state, err)
+		}
+
+		shards[id] = &managedShard{
+			shard:  shard,
+			status: ShardStatus{ID: id, State: ShardWaiting},
+		}
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.shards = shards
+
+	for id, delay := range identifyDelays(m.ids, m.maxConcurrency) {
+		m.wg.Add(1)
+		go func(id gateway.ShardID, delay time.Duration) {
+			defer m.wg.Done()
+			m.supervise(ctx, id, delay, getURL)
+		}(id, delay)
+	}
+
+	return nil
+}
+
+// Stop disconnects every shard and waits for them to close.
+func (m *ShardManager) Stop() error {
+	m.mu.RLock()
+	cancel := m.cancel
+	m.mu.RUnlock()
+
+	if cancel == nil {
+		return ErrManagerNotStarted
+	}
+
+	cancel()
+	m.wg.Wait()
+	return nil
+}
+
+// Status returns the status of every shard, ordered by shard id.
+func (m *ShardManager) Status() []ShardStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]ShardStatus, 0, len(m.ids))
+	for _, id := range m.ids {
+		status := ShardStatus{ID: id, State: ShardWaiting}
+		if managed, ok := m.shards[id]; ok {
+			status = managed.status
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// Shard returns the shard with the given id, or nil if the shard is not run by this manager or the manager has not
+// been started yet.
+func (m *ShardManager) Shard(id gateway.ShardID) *Shard {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if managed, ok := m.shards[id]; ok {
+		return managed.shard
+	}
+	return nil
+}
+
+func (m *ShardManager) setStatus(id gateway.ShardID, state ShardState, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := &m.shards[id].status
+	if state == ShardReconnecting {
+		status.Restarts++
+	}
+	if err != nil {
+		status.Err = err
+	}
+	status.State = state
+}
+
+func (m *ShardManager) supervise(ctx context.Context, id gateway.ShardID, delay time.Duration, getURL GetGatewayBotURL) {
+	if !sleep(ctx, delay) {
+		m.setStatus(id, ShardStopped, nil)
+		return
+	}
+
+	if err := m.Shard(id).Run(ctx, getURL); err != nil && ctx.Err() == nil {
+		log.Error("shard %d stopped and will not be restarted: %s", id, err)
+	}
+}
+
+// identifyDelays spreads the shards such that only one shard in each identify bucket identifies at a time.
+func identifyDelays(ids []gateway.ShardID, maxConcurrency int) map[gateway.ShardID]time.Duration {
+	sorted := make([]gateway.ShardID, len(ids))
+	copy(sorted, ids)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	positions := make(map[int]int, maxConcurrency)
+	delays := make(map[gateway.ShardID]time.Duration, len(ids))
+	for _, id := range sorted {
+		bucket := identifyBucket(id, maxConcurrency)
+		delays[id] = time.Duration(positions[bucket]) * identifyInterval
+		positions[bucket]++
+	}
+
+	return delays
+}
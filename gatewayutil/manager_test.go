@@ -0,0 +1,144 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway"
+)
+
+func TestNewShardManager(t *testing.T) {
+	t.Run("all shards", func(t *testing.T) {
+		manager, err := NewShardManager(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		statuses := manager.Status()
+		if len(statuses) != 4 {
+			t.Fatalf("expected 4 shards, got %d", len(statuses))
+		}
+		for i := range statuses {
+			if statuses[i].ID != gateway.ShardID(i) {
+				t.Errorf("expected shard id %d, got %d", i, statuses[i].ID)
+			}
+			if statuses[i].State != ShardWaiting {
+				t.Errorf("expected shard to be waiting, got %s", statuses[i].State)
+			}
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		manager, err := NewShardManager(10, WithShardRange(4, 6))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		statuses := manager.Status()
+		if len(statuses) != 3 {
+			t.Fatalf("expected 3 shards, got %d", len(statuses))
+		}
+		if statuses[0].ID != 4 || statuses[2].ID != 6 {
+			t.Error("wrong shard ids in range")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := NewShardManager(0); err == nil {
+			t.Error("expected error for shard count 0")
+		}
+		if _, err := NewShardManager(4, WithShardRange(2, 4)); err == nil {
+			t.Error("expected error for range exceeding the shard count")
+		}
+		if _, err := NewShardManager(4, WithShardRange(3, 2)); err == nil {
+			t.Error("expected error for inverted range")
+		}
+		if _, err := NewShardManager(4, WithMaxConcurrency(0)); err == nil {
+			t.Error("expected error for max concurrency 0")
+		}
+	})
+}
+
+func TestIdentifyDelays(t *testing.T) {
+	ids := []gateway.ShardID{0, 1, 2, 3, 4, 5}
+	delays := identifyDelays(ids, 2)
+
+	wants := map[gateway.ShardID]time.Duration{
+		0: 0,
+		1: 0,
+		2: identifyInterval,
+		3: identifyInterval,
+		4: 2 * identifyInterval,
+		5: 2 * identifyInterval,
+	}
+	for id, delay := range wants {
+		if delays[id] != delay {
+			t.Errorf("shard %d: expected delay %s, got %s", id, delay, delays[id])
+		}
+	}
+}
+
+func TestShardedIdentifyRateLimiter(t *testing.T) {
+	limiter := NewShardedIdentifyRateLimiter(2)
+
+	if ok, _ := limiter.Try(0); !ok {
+		t.Error("first identify in bucket 0 should be allowed")
+	}
+	if ok, _ := limiter.Try(1); !ok {
+		t.Error("first identify in bucket 1 should be allowed")
+	}
+	if ok, _ := limiter.Try(2); ok {
+		t.Error("second identify in bucket 0 should be rate limited")
+	}
+}
+
+func TestShardManager(t *testing.T) {
+	manager, err := NewShardManager(2, WithShardOptions(
+		gateway.WithBotToken("token"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = manager.Stop(); !errors.Is(err, ErrManagerNotStarted) {
+		t.Errorf("expected ErrManagerNotStarted, got %v", err)
+	}
+
+	// an url that is not a websocket url is a configuration error, which is not retried
+	err = manager.Start(context.Background(), func() (string, error) {
+		return "https://gateway.discord.gg?v=10&encoding=json", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manager.Shard(0) == nil || manager.Shard(1) == nil {
+		t.Fatal("missing shards")
+	}
+
+	if err = manager.Start(context.Background(), nil); !errors.Is(err, ErrManagerStarted) {
+		t.Errorf("expected ErrManagerStarted, got %v", err)
+	}
+
+	// shard 0 fails right away, while shard 1 waits for its identify slot
+	deadline := time.Now().Add(time.Second)
+	for manager.Status()[0].State != ShardFailed {
+		if time.Now().After(deadline) {
+			t.Fatal("shard 0 did not fail")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err = manager.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := manager.Status()
+	if !errors.Is(statuses[0].Err, ErrURLScheme) {
+		t.Errorf("expected shard 0 to fail with the url error, got %v", statuses[0].Err)
+	}
+	if statuses[1].State != ShardStopped {
+		t.Errorf("expected shard 1 to be stopped, got %s", statuses[1].State)
+	}
+}
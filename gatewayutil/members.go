@@ -0,0 +1,211 @@
+package gatewayutil
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/event"
+)
+
+var ErrMemberChunksTimeout = errors.New("timed out waiting for guild member chunks")
+var ErrMemberChunksDisconnected = errors.New("disconnected before receiving every guild member chunk")
+
+// GuildMembers holds the aggregated guild member chunks of a single request. Err is set when not every chunk
+// arrived in time, in which case the members are incomplete.
+type GuildMembers struct {
+	GuildID   event.Snowflake
+	Members   []event.GuildMember
+	Presences []event.PresenceUpdateEvent
+	NotFound  []event.Snowflake
+	Err       error
+}
+
+// MemberRequester sends request guild members commands, such as a Shard.
+type MemberRequester interface {
+	RequestGuildMembers(request *gateway.RequestGuildMembers) error
+}
+
+var _ MemberRequester = &Shard{}
+
+// connectionRequester is implemented by Shard, and reports when the connection a request was sent on is closed
+type connectionRequester interface {
+	requestGuildMembers(request *gateway.RequestGuildMembers) (closed <-chan struct{}, err error)
+}
+
+var _ connectionRequester = &Shard{}
+
+// MemberChunkAggregator correlates GUILD_MEMBERS_CHUNK events with the request that caused them, using the nonce.
+// The aggregator must see the incoming events, so the handler of the client must be wrapped by Handler, and
+// GUILD_MEMBERS_CHUNK must be part of the requested events:
+//
+//	aggregator := &gatewayutil.MemberChunkAggregator{}
+//	shard, _ := gatewayutil.NewShard(
+//		gateway.WithGuildEvents(event.GuildMembersChunk, ...),
+//		gateway.WithEventHandler(aggregator.Handler(handler)),
+//	)
+//
+//	result := <-aggregator.RequestGuildMembers(shard, &gateway.RequestGuildMembers{GuildID: guildID})
+//
+// When the requester is a Shard, pending requests fail with ErrMemberChunksDisconnected once the connection they were
+// sent on is closed, instead of waiting for the timeout.
+type MemberChunkAggregator struct {
+	// Codec decodes the chunks, and must match the codec of the client. Defaults to encoding.JSONCodec.
+	Codec encoding.Codec
+
+	// Timeout is the maximum duration to wait for every chunk of a request. Defaults to 30 seconds.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pendingMembers
+}
+
+type pendingMembers struct {
+	result GuildMembers
+	chunks map[int]struct{}
+	timer  *time.Timer
+	done   chan *GuildMembers
+
+	// completed is closed once the result is sent to done
+	completed chan struct{}
+}
+
+const defaultMemberChunksTimeout = 30 * time.Second
+
+// RequestGuildMembers sends the request with a generated nonce, and returns a channel which receives the aggregated
+// result once every chunk has arrived, or the timeout is reached. Any nonce in the request is overwritten.
+func (a *MemberChunkAggregator) RequestGuildMembers(requester MemberRequester, request *gateway.RequestGuildMembers) (<-chan *GuildMembers, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	req := *request
+	req.Nonce = nonce
+
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = defaultMemberChunksTimeout
+	}
+
+	pending := &pendingMembers{
+		result:    GuildMembers{GuildID: req.GuildID},
+		chunks:    map[int]struct{}{},
+		done:      make(chan *GuildMembers, 1),
+		completed: make(chan struct{}),
+	}
+
+	a.mu.Lock()
+	if a.pending == nil {
+		a.pending = map[string]*pendingMembers{}
+	}
+	a.pending[nonce] = pending
+	pending.timer = time.AfterFunc(timeout, func() {
+		a.complete(nonce, ErrMemberChunksTimeout)
+	})
+	a.mu.Unlock()
+
+	var closed <-chan struct{}
+	if r, ok := requester.(connectionRequester); ok {
+		closed, err = r.requestGuildMembers(&req)
+	} else {
+		err = requester.RequestGuildMembers(&req)
+	}
+	if err != nil {
+		a.mu.Lock()
+		delete(a.pending, nonce)
+		a.mu.Unlock()
+		pending.timer.Stop()
+		return nil, err
+	}
+
+	if closed != nil {
+		go func() {
+			select {
+			case <-closed:
+				a.complete(nonce, ErrMemberChunksDisconnected)
+			case <-pending.completed:
+			}
+		}()
+	}
+	return pending.done, nil
+}
+
+// Handler wraps the event handler of a client, consuming the member chunks of pending requests. Any other event is
+// forwarded to next, which may be nil.
+func (a *MemberChunkAggregator) Handler(next gateway.Handler) gateway.Handler {
+	return func(shardID gateway.ShardID, evt event.Type, data encoding.RawMessage) {
+		if evt == event.GuildMembersChunk && a.handleChunk(data) {
+			return
+		}
+		if next != nil {
+			next(shardID, evt, data)
+		}
+	}
+}
+
+// handleChunk reports whether the chunk belonged to a pending request
+func (a *MemberChunkAggregator) handleChunk(data encoding.RawMessage) bool {
+	codec := a.Codec
+	if codec == nil {
+		codec = encoding.JSONCodec{}
+	}
+
+	var chunk event.GuildMembersChunkEvent
+	if err := codec.Unmarshal(data, &chunk); err != nil || chunk.Nonce == "" {
+		return false
+	}
+
+	var notFound []event.Snowflake
+	if len(chunk.NotFound) > 0 {
+		_ = codec.Unmarshal(chunk.NotFound, &notFound)
+	}
+
+	a.mu.Lock()
+	pending, ok := a.pending[chunk.Nonce]
+	if !ok {
+		a.mu.Unlock()
+		return false
+	}
+
+	pending.result.Members = append(pending.result.Members, chunk.Members...)
+	pending.result.Presences = append(pending.result.Presences, chunk.Presences...)
+	pending.result.NotFound = append(pending.result.NotFound, notFound...)
+	pending.chunks[chunk.ChunkIndex] = struct{}{}
+	completed := len(pending.chunks) >= chunk.ChunkCount
+	a.mu.Unlock()
+
+	if completed {
+		a.complete(chunk.Nonce, nil)
+	}
+	return true
+}
+
+func (a *MemberChunkAggregator) complete(nonce string, err error) {
+	a.mu.Lock()
+	pending, ok := a.pending[nonce]
+	delete(a.pending, nonce)
+	a.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	pending.timer.Stop()
+	pending.result.Err = err
+	pending.done <- &pending.result
+	close(pending.done)
+	close(pending.completed)
+}
+
+// newNonce returns a random nonce of 32 characters, the maximum length allowed by Discord
+func newNonce() (string, error) {
+	b := make([]byte, gateway.MaxNonceLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,194 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/event/opcode"
+	"github.com/discordpkg/gateway/gatewaytest"
+)
+
+type requesterFunc func(request *gateway.RequestGuildMembers) error
+
+func (f requesterFunc) RequestGuildMembers(request *gateway.RequestGuildMembers) error {
+	return f(request)
+}
+
+func TestMemberChunkAggregator(t *testing.T) {
+	chunk := func(codec encoding.Codec, nonce string, index, count int, userID string) []byte {
+		data, err := codec.Marshal(map[string]interface{}{
+			"guild_id":    "1",
+			"members":     []interface{}{map[string]interface{}{"user": map[string]interface{}{"id": userID}}},
+			"chunk_index": index,
+			"chunk_count": count,
+			"not_found":   []interface{}{"404"},
+			"nonce":       nonce,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			var forwarded []event.Type
+			aggregator := &MemberChunkAggregator{Codec: codec}
+			handler := aggregator.Handler(func(_ gateway.ShardID, evt event.Type, _ encoding.RawMessage) {
+				forwarded = append(forwarded, evt)
+			})
+
+			var nonce string
+			requester := requesterFunc(func(request *gateway.RequestGuildMembers) error {
+				nonce = request.Nonce
+				return nil
+			})
+
+			result, err := aggregator.RequestGuildMembers(requester, &gateway.RequestGuildMembers{GuildID: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(nonce) != gateway.MaxNonceLength {
+				t.Fatalf("expected a nonce of %d characters, got '%s'", gateway.MaxNonceLength, nonce)
+			}
+
+			handler(0, event.GuildMembersChunk, chunk(codec, "unknown", 0, 1, "0"))
+			for i := 2; i >= 0; i-- {
+				handler(0, event.GuildMembersChunk, chunk(codec, nonce, i, 3, fmt.Sprint(i+10)))
+			}
+			handler(0, event.MessageCreate, nil)
+
+			select {
+			case members := <-result:
+				if members.Err != nil {
+					t.Fatal(members.Err)
+				}
+				if members.GuildID != "1" || len(members.Members) != 3 || len(members.NotFound) != 3 {
+					t.Errorf("incorrect result. Got %+v", members)
+				}
+				if members.Members[0].User == nil || members.Members[0].User.ID != "12" {
+					t.Errorf("incorrect first member. Got %+v", members.Members[0])
+				}
+			case <-time.After(time.Second):
+				t.Fatal("result was not completed")
+			}
+
+			if len(forwarded) != 2 || forwarded[0] != event.GuildMembersChunk || forwarded[1] != event.MessageCreate {
+				t.Errorf("only unrelated events should be forwarded. Got %v", forwarded)
+			}
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		aggregator := &MemberChunkAggregator{Timeout: 10 * time.Millisecond}
+		handler := aggregator.Handler(nil)
+
+		var nonce string
+		requester := requesterFunc(func(request *gateway.RequestGuildMembers) error {
+			nonce = request.Nonce
+			return nil
+		})
+
+		result, err := aggregator.RequestGuildMembers(requester, &gateway.RequestGuildMembers{GuildID: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler(0, event.GuildMembersChunk, chunk(encoding.JSONCodec{}, nonce, 0, 2, "10"))
+
+		members := <-result
+		if !errors.Is(members.Err, ErrMemberChunksTimeout) {
+			t.Errorf("expected timeout error, got %v", members.Err)
+		}
+		if len(members.Members) != 1 {
+			t.Errorf("expected the partial members, got %+v", members.Members)
+		}
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		server := gatewaytest.NewServer()
+		defer server.Close()
+
+		aggregator := &MemberChunkAggregator{Timeout: time.Minute}
+		shard, err := NewShard(
+			gateway.WithBotToken("token"),
+			gateway.WithCommandRateLimiter(&unlimitedRateLimiter{}),
+			gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+			gateway.WithEventHandler(aggregator.Handler(nil)),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shard.Backoff = func(int) time.Duration { return 0 }
+
+		request := &gateway.RequestGuildMembers{GuildID: "1", Query: "go", Limit: 10}
+		if _, err = aggregator.RequestGuildMembers(shard, request); !errors.Is(err, ErrNotConnected) {
+			t.Errorf("expected a request before connecting to fail, got %v", err)
+		}
+
+		result := make(chan error, 1)
+		go func() {
+			result <- shard.Run(ctx, func() (string, error) { return server.URL, nil })
+		}()
+
+		conn, err := server.Accept(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the server accepts the connection once ready is sent, which the client may not have processed yet
+		members, err := aggregator.RequestGuildMembers(shard, request)
+		for errors.Is(err, gateway.ErrNotConnectedYet) && ctx.Err() == nil {
+			time.Sleep(time.Millisecond)
+			members, err = aggregator.RequestGuildMembers(shard, request)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload, err := conn.Receive(ctx); err != nil || payload.Op != opcode.RequestGuildMembers {
+			t.Fatalf("expected the request to be sent, got %+v (%v)", payload, err)
+		}
+		if err = conn.Reconnect(); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case got := <-members:
+			if !errors.Is(got.Err, ErrMemberChunksDisconnected) {
+				t.Errorf("expected the request to fail on disconnect, got %v", got.Err)
+			}
+		case <-ctx.Done():
+			t.Fatal("pending request was not failed on disconnect")
+		}
+		if len(aggregator.pending) != 0 {
+			t.Error("failed request should not be pending")
+		}
+
+		cancel()
+		if err = <-result; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context cancelled, got %v", err)
+		}
+	})
+
+	t.Run("request error", func(t *testing.T) {
+		aggregator := &MemberChunkAggregator{}
+		requester := requesterFunc(func(request *gateway.RequestGuildMembers) error {
+			return gateway.ErrInvalidCommand
+		})
+
+		if _, err := aggregator.RequestGuildMembers(requester, &gateway.RequestGuildMembers{}); !errors.Is(err, gateway.ErrInvalidCommand) {
+			t.Errorf("expected request error, got %v", err)
+		}
+		if len(aggregator.pending) != 0 {
+			t.Error("failed request should not be pending")
+		}
+	})
+}
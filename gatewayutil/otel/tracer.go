@@ -0,0 +1,88 @@
+// Package otel starts OpenTelemetry spans for the dispatch events of gateway clients:
+//
+//	shard, err := gatewayutil.NewShard(
+//		gateway.WithTracer(otel.NewTracer(otel.WithTracerProvider(provider))),
+//		gateway.WithEventHandlerV2(func(ctx context.Context, evt *gateway.Event) error {
+//			// ctx holds the span of the event
+//			return nil
+//		}),
+//		// ...
+//	)
+package otel
+
+import (
+	"context"
+
+	"github.com/discordpkg/gateway"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/discordpkg/gateway/gatewayutil/otel"
+
+// Span attributes set on every dispatch span.
+const (
+	ShardIDKey     = attribute.Key("discord.gateway.shard_id")
+	EventKey       = attribute.Key("discord.gateway.event")
+	SequenceKey    = attribute.Key("discord.gateway.sequence")
+	PayloadSizeKey = attribute.Key("discord.gateway.payload_size")
+)
+
+// Option for configuring a new Tracer.
+type Option func(tracer *Tracer)
+
+// WithTracerProvider sets the provider of the tracer. Defaults to the global provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(tracer *Tracer) {
+		tracer.provider = provider
+	}
+}
+
+// WithAttributes adds attributes to every span, such as the name of the bot.
+func WithAttributes(attributes ...attribute.KeyValue) Option {
+	return func(tracer *Tracer) {
+		tracer.attributes = append(tracer.attributes, attributes...)
+	}
+}
+
+// Tracer implements gateway.Tracer. Spans are named after the event type, such as "MESSAGE_CREATE", and are of the
+// consumer kind.
+type Tracer struct {
+	provider   trace.TracerProvider
+	attributes []attribute.KeyValue
+	tracer     trace.Tracer
+}
+
+var _ gateway.Tracer = &Tracer{}
+
+func NewTracer(options ...Option) *Tracer {
+	tracer := &Tracer{}
+	for i := range options {
+		options[i](tracer)
+	}
+
+	if tracer.provider == nil {
+		tracer.provider = otel.GetTracerProvider()
+	}
+	tracer.tracer = tracer.provider.Tracer(instrumentationName)
+	return tracer
+}
+
+func (t *Tracer) StartDispatch(ctx context.Context, info gateway.DispatchInfo) (context.Context, func()) {
+	attributes := append([]attribute.KeyValue{
+		ShardIDKey.Int64(int64(info.ShardID)),
+		EventKey.String(string(info.Event)),
+		SequenceKey.Int64(info.Seq),
+		PayloadSizeKey.Int(info.Size),
+	}, t.attributes...)
+
+	ctx, span := t.tracer.Start(ctx, string(info.Event),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attributes...),
+	)
+	return ctx, func() {
+		span.End()
+	}
+}
@@ -0,0 +1,72 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/gatewayutil"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	frames := []gatewayutil.Frame{
+		{Direction: gatewayutil.Inbound, Data: []byte(`{"op":10,"d":{"heartbeat_interval":45000}}`)},
+		{Direction: gatewayutil.Inbound, Data: []byte(`{"op":0,"s":1,"t":"READY","d":{"session_id":"id"}}`)},
+		{Direction: gatewayutil.Inbound, Data: []byte(`{"op":0,"s":2,"t":"MESSAGE_CREATE","d":{"id":"1"}}`)},
+		{Direction: gatewayutil.Inbound, Data: []byte(`{"op":0,"s":3,"t":"TYPING_START","d":{}}`)},
+	}
+
+	var handlerSpan trace.SpanContext
+	_, _, err := gatewayutil.Replay(frames,
+		gateway.WithBotToken("token"),
+		gateway.WithShardInfo(1, 2),
+		gateway.WithGuildEvents(event.MessageCreate),
+		gateway.WithTracer(NewTracer(WithTracerProvider(provider), WithAttributes(attribute.String("bot", "tester")))),
+		gateway.WithEventHandlerV2(func(ctx context.Context, _ *gateway.Event) error {
+			handlerSpan = trace.SpanContextFromContext(ctx)
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span for the handled event, got %d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != string(event.MessageCreate) || span.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("incorrect span. Got name '%s' of kind %s", span.Name(), span.SpanKind())
+	}
+	if !handlerSpan.IsValid() || handlerSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Error("expected the span to be available to the handler")
+	}
+
+	wants := map[attribute.Key]attribute.Value{
+		ShardIDKey:     attribute.Int64Value(1),
+		EventKey:       attribute.StringValue(string(event.MessageCreate)),
+		SequenceKey:    attribute.Int64Value(2),
+		PayloadSizeKey: attribute.IntValue(len(`{"id":"1"}`)),
+		"bot":          attribute.StringValue("tester"),
+	}
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	for key, want := range wants {
+		if got := attributes[key]; got != want {
+			t.Errorf("%s: got %v, wants %v", key, got.Emit(), want.Emit())
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package prometheus exposes the metrics of gateway clients and shards as Prometheus collectors:
+//
+//	metrics := gatewayprometheus.NewMetrics("discord")
+//	prometheus.MustRegister(metrics)
+//
+//	shard, err := gatewayutil.NewShard(
+//		gateway.WithMetrics(metrics),
+//		// ...
+//	)
+//
+// Every metric of a client is labeled with the shard id, such that one Metrics instance can be shared by every shard.
+// The same instance can also be given to a gatewayutil.Dispatcher, see gatewayutil.WithDispatcherMetrics.
+package prometheus
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/closecode"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/gatewayutil"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements gateway.Metrics, gatewayutil.DispatcherMetrics and prometheus.Collector.
+type Metrics struct {
+	eventsReceived     *prometheus.CounterVec
+	bytesRead          *prometheus.CounterVec
+	heartbeatLatency   *prometheus.HistogramVec
+	heartbeatsMissed   *prometheus.CounterVec
+	handlerFailures    *prometheus.CounterVec
+	stateTransitions   *prometheus.CounterVec
+	closeCodes         *prometheus.CounterVec
+	commandRateLimited *prometheus.CounterVec
+	rateLimitWait      *prometheus.CounterVec
+	reconnects         *prometheus.CounterVec
+	sequenceDuplicates *prometheus.CounterVec
+	sequenceGaps       *prometheus.CounterVec
+	dispatchQueueDepth prometheus.Gauge
+	dispatchDropped    *prometheus.CounterVec
+}
+
+var _ gateway.Metrics = &Metrics{}
+var _ gatewayutil.DispatcherMetrics = &Metrics{}
+var _ prometheus.Collector = &Metrics{}
+
+// NewMetrics creates the collectors, where every metric name is prefixed by the namespace. The namespace may be
+// empty. The metrics must be registered before they are exposed.
+func NewMetrics(namespace string) *Metrics {
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "gateway",
+			Name:      name,
+			Help:      help,
+		}, append([]string{"shard"}, labels...))
+	}
+
+	return &Metrics{
+		eventsReceived: counter("events_received_total", "Number of dispatch events received.", "event"),
+		bytesRead:      counter("read_bytes_total", "Number of payload bytes read, after decompression."),
+		heartbeatLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "gateway",
+			Name:      "heartbeat_latency_seconds",
+			Help:      "Duration between sending a heartbeat and receiving the acknowledgement.",
+			Buckets:   []float64{.025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"shard"}),
+		heartbeatsMissed:   counter("heartbeats_missed_total", "Number of heartbeats that were not acknowledged."),
+		handlerFailures:    counter("handler_failures_total", "Number of events the event handler failed to handle.", "event"),
+		stateTransitions:   counter("state_transitions_total", "Number of client state transitions.", "state"),
+		closeCodes:         counter("close_codes_total", "Number of close codes received from Discord.", "code"),
+		commandRateLimited: counter("commands_rate_limited_total", "Number of commands rejected by a rate limiter.", "event"),
+		rateLimitWait:      counter("rate_limit_wait_seconds_total", "Time spent waiting for the command rate limiter.", "event"),
+		reconnects:         counter("reconnects_total", "Number of reconnects after losing the connection."),
+		sequenceDuplicates: counter("sequence_duplicates_total", "Number of dispatch events dropped as duplicates."),
+		sequenceGaps:       counter("sequence_gaps_total", "Number of times dispatch events skipped sequence numbers."),
+		dispatchQueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "gateway",
+			Name:      "dispatch_queue_depth",
+			Help:      "Number of events queued by the dispatcher.",
+		}),
+		dispatchDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "gateway",
+			Name:      "dispatch_dropped_total",
+			Help:      "Number of events dropped by the dispatcher, due to a full queue.",
+		}, []string{"event"}),
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.eventsReceived,
+		m.bytesRead,
+		m.heartbeatLatency,
+		m.heartbeatsMissed,
+		m.handlerFailures,
+		m.stateTransitions,
+		m.closeCodes,
+		m.commandRateLimited,
+		m.rateLimitWait,
+		m.reconnects,
+		m.sequenceDuplicates,
+		m.sequenceGaps,
+		m.dispatchQueueDepth,
+		m.dispatchDropped,
+	}
+}
+
+func (m *Metrics) Describe(descs chan<- *prometheus.Desc) {
+	for _, collector := range m.collectors() {
+		collector.Describe(descs)
+	}
+}
+
+func (m *Metrics) Collect(metrics chan<- prometheus.Metric) {
+	for _, collector := range m.collectors() {
+		collector.Collect(metrics)
+	}
+}
+
+func shard(shardID gateway.ShardID) string {
+	return strconv.FormatUint(uint64(shardID), 10)
+}
+
+func (m *Metrics) EventReceived(shardID gateway.ShardID, evt event.Type) {
+	m.eventsReceived.WithLabelValues(shard(shardID), string(evt)).Inc()
+}
+
+func (m *Metrics) BytesRead(shardID gateway.ShardID, n int) {
+	m.bytesRead.WithLabelValues(shard(shardID)).Add(float64(n))
+}
+
+func (m *Metrics) HeartbeatLatency(shardID gateway.ShardID, latency time.Duration) {
+	m.heartbeatLatency.WithLabelValues(shard(shardID)).Observe(latency.Seconds())
+}
+
+func (m *Metrics) HeartbeatMissed(shardID gateway.ShardID) {
+	m.heartbeatsMissed.WithLabelValues(shard(shardID)).Inc()
+}
+
+func (m *Metrics) HandlerFailed(shardID gateway.ShardID, evt event.Type) {
+	m.handlerFailures.WithLabelValues(shard(shardID), string(evt)).Inc()
+}
+
+func (m *Metrics) StateChanged(shardID gateway.ShardID, state string) {
+	m.stateTransitions.WithLabelValues(shard(shardID), state).Inc()
+}
+
+func (m *Metrics) CloseCodeReceived(shardID gateway.ShardID, code closecode.Type) {
+	m.closeCodes.WithLabelValues(shard(shardID), strconv.Itoa(int(code))).Inc()
+}
+
+func (m *Metrics) CommandRateLimited(shardID gateway.ShardID, evt event.Type) {
+	m.commandRateLimited.WithLabelValues(shard(shardID), string(evt)).Inc()
+}
+
+func (m *Metrics) RateLimitWait(shardID gateway.ShardID, evt event.Type, wait time.Duration) {
+	m.rateLimitWait.WithLabelValues(shard(shardID), string(evt)).Add(wait.Seconds())
+}
+
+func (m *Metrics) Reconnect(shardID gateway.ShardID) {
+	m.reconnects.WithLabelValues(shard(shardID)).Inc()
+}
+
+func (m *Metrics) SequenceDuplicate(shardID gateway.ShardID) {
+	m.sequenceDuplicates.WithLabelValues(shard(shardID)).Inc()
+}
+
+func (m *Metrics) SequenceGap(shardID gateway.ShardID, _, _ int64) {
+	m.sequenceGaps.WithLabelValues(shard(shardID)).Inc()
+}
+
+func (m *Metrics) DispatchQueueDepth(depth int) {
+	m.dispatchQueueDepth.Set(float64(depth))
+}
+
+func (m *Metrics) DispatchDropped(evt event.Type) {
+	m.dispatchDropped.WithLabelValues(string(evt)).Inc()
+}
@@ -0,0 +1,86 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway/closecode"
+	"github.com/discordpkg/gateway/event"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics("discord")
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(metrics); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics.EventReceived(1, event.MessageCreate)
+	metrics.EventReceived(1, event.MessageCreate)
+	metrics.BytesRead(1, 512)
+	metrics.HeartbeatLatency(1, 40*time.Millisecond)
+	metrics.HeartbeatMissed(1)
+	metrics.HandlerFailed(1, event.MessageCreate)
+	metrics.StateChanged(1, "connected")
+	metrics.CloseCodeReceived(1, closecode.SessionTimedOut)
+	metrics.CommandRateLimited(1, event.UpdatePresence)
+	metrics.RateLimitWait(1, event.Heartbeat, 2*time.Second)
+	metrics.Reconnect(1)
+	metrics.SequenceDuplicate(1)
+	metrics.SequenceGap(1, 3, 5)
+	metrics.DispatchQueueDepth(3)
+	metrics.DispatchDropped(event.MessageCreate)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			// dispatchers are shared by shards, so the dispatch metrics have no shard label
+			if labels["shard"] != "1" && !strings.HasPrefix(family.GetName(), "discord_gateway_dispatch_") {
+				t.Errorf("%s: expected shard label 1, got %v", family.GetName(), labels)
+			}
+
+			switch {
+			case metric.GetGauge() != nil:
+				values[family.GetName()] = metric.GetGauge().GetValue()
+			case metric.GetCounter() != nil:
+				values[family.GetName()] = metric.GetCounter().GetValue()
+			case metric.GetHistogram() != nil:
+				values[family.GetName()] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	wants := map[string]float64{
+		"discord_gateway_events_received_total":         2,
+		"discord_gateway_read_bytes_total":              512,
+		"discord_gateway_heartbeat_latency_seconds":     1,
+		"discord_gateway_heartbeats_missed_total":       1,
+		"discord_gateway_handler_failures_total":        1,
+		"discord_gateway_state_transitions_total":       1,
+		"discord_gateway_close_codes_total":             1,
+		"discord_gateway_commands_rate_limited_total":   1,
+		"discord_gateway_rate_limit_wait_seconds_total": 2,
+		"discord_gateway_reconnects_total":              1,
+		"discord_gateway_sequence_duplicates_total":     1,
+		"discord_gateway_sequence_gaps_total":           1,
+		"discord_gateway_dispatch_queue_depth":          3,
+		"discord_gateway_dispatch_dropped_total":        1,
+	}
+	for name, want := range wants {
+		if got, ok := values[name]; !ok || got != want {
+			t.Errorf("%s: got %v, wants %v", name, got, want)
+		}
+	}
+}
@@ -29,7 +29,7 @@ func (rl *LocalCommandRateLimiter) Try(_ gateway.ShardID) (bool, time.Duration)
 
 func NewLocalIdentifyRateLimiter() *LocalIdentifyRateLimiter {
 	return &LocalIdentifyRateLimiter{
-		limiter: rate.New(1, 5*time.Second),
+		limiter: rate.New(1, identifyInterval),
 	}
 }
 
@@ -42,3 +42,40 @@ var _ gateway.RateLimiter = &LocalIdentifyRateLimiter{}
 func (rl *LocalIdentifyRateLimiter) Try(_ gateway.ShardID) (bool, time.Duration) {
 	return rl.limiter.Try()
 }
+
+// NewShardedIdentifyRateLimiter creates an identify rate limiter that respects the max_concurrency value given by
+// the "Get Gateway Bot" endpoint. Shards are grouped into buckets using `shard_id % max_concurrency`, and each bucket
+// allows one identify every 5 seconds.
+//
+// See https://discord.com/developers/docs/topics/gateway#sharding-max-concurrency
+func NewShardedIdentifyRateLimiter(maxConcurrency int) *ShardedIdentifyRateLimiter {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	buckets := make([]*rate.RateLimiter, maxConcurrency)
+	for i := range buckets {
+		buckets[i] = rate.New(1, identifyInterval)
+	}
+
+	return &ShardedIdentifyRateLimiter{
+		buckets: buckets,
+	}
+}
+
+type ShardedIdentifyRateLimiter struct {
+	buckets []*rate.RateLimiter
+}
+
+var _ gateway.RateLimiter = &ShardedIdentifyRateLimiter{}
+
+func (rl *ShardedIdentifyRateLimiter) Try(id gateway.ShardID) (bool, time.Duration) {
+	return rl.buckets[identifyBucket(id, len(rl.buckets))].Try()
+}
+
+// identifyInterval is the time Discord requires between two identify commands within the same bucket
+const identifyInterval = 5 * time.Second
+
+func identifyBucket(id gateway.ShardID, maxConcurrency int) int {
+	return int(id) % maxConcurrency
+}
@@ -0,0 +1,230 @@
+package gatewayutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Direction of a recorded frame, as seen from the client.
+type Direction string
+
+const (
+	Inbound  Direction = "inbound"
+	Outbound Direction = "outbound"
+)
+
+// Frame is a single payload read or written by a client. Close frames received from Discord are recorded as the
+// inbound payload holding the close code, see Shard. Close frames written by the client are marked as Close, and
+// the data holds the close code written by gateway.Client.Close.
+type Frame struct {
+	Time      time.Time
+	Direction Direction
+	Close     bool
+	Data      []byte
+}
+
+// recordedFrame is the file format of a frame. Text payloads, such as json, are stored as is for readability, while
+// binary payloads, such as etf and close codes, are base64 encoded.
+type recordedFrame struct {
+	Time      time.Time `json:"time"`
+	Direction Direction `json:"direction"`
+	Close     bool      `json:"close,omitempty"`
+	Text      *string   `json:"text,omitempty"`
+	Binary    []byte    `json:"binary,omitempty"`
+}
+
+func (f Frame) MarshalJSON() ([]byte, error) {
+	frame := recordedFrame{
+		Time:      f.Time,
+		Direction: f.Direction,
+		Close:     f.Close,
+	}
+	if !f.Close && utf8.Valid(f.Data) {
+		text := string(f.Data)
+		frame.Text = &text
+	} else {
+		frame.Binary = f.Data
+	}
+	return json.Marshal(&frame)
+}
+
+func (f *Frame) UnmarshalJSON(data []byte) error {
+	var frame recordedFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return err
+	}
+
+	f.Time, f.Direction, f.Close, f.Data = frame.Time, frame.Direction, frame.Close, frame.Binary
+	if frame.Text != nil {
+		f.Data = []byte(*frame.Text)
+	}
+	return nil
+}
+
+// Recorder persists the frames read and written by a client as json lines, such that a session can be inspected or
+// replayed later (see Replay). The recorder wraps the readers passed to gateway.Client.ProcessNext, and the writers
+// passed to gateway.Client.Write and gateway.Client.Close. Set Shard.Recorder to record every connection of a shard:
+//
+//	recorder, err := gatewayutil.CreateRecording("session.jsonl")
+//	defer recorder.Close()
+//
+//	shard.Recorder = recorder
+//
+// Recordings hold every payload as is, including the identify payload and therefore the bot token.
+type Recorder struct {
+	mu     sync.Mutex
+	record func(frame *Frame) error
+	closer io.Closer
+	err    error
+}
+
+// NewRecorder creates a recorder which writes the frames to w. The writer is closed by Recorder.Close when it
+// implements io.Closer.
+func NewRecorder(w io.Writer) *Recorder {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	encoder.SetEscapeHTML(false)
+
+	recorder := &Recorder{
+		record: func(frame *Frame) error {
+			if err := encoder.Encode(frame); err != nil {
+				return err
+			}
+			return buffered.Flush()
+		},
+	}
+	if closer, ok := w.(io.Closer); ok {
+		recorder.closer = closer
+	}
+	return recorder
+}
+
+// CreateRecording creates or truncates the file at path, and returns a recorder writing to it.
+func CreateRecording(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(file), nil
+}
+
+// Reader wraps the reader of an incoming payload. The payload is recorded once it has been read completely.
+func (r *Recorder) Reader(reader io.Reader) io.Reader {
+	return &recordingReader{recorder: r, reader: reader}
+}
+
+// Writer wraps a payload writer, every write is recorded as an outbound frame.
+func (r *Recorder) Writer(writer io.Writer) io.Writer {
+	return &recordingWriter{recorder: r, writer: writer}
+}
+
+// CloseWriter wraps the writer of close frames, every write is recorded as an outbound close frame.
+func (r *Recorder) CloseWriter(writer io.Writer) io.Writer {
+	return &recordingWriter{recorder: r, writer: writer, close: true}
+}
+
+// Err returns the first error that occurred while recording. A failing recorder never interrupts the client, it
+// stops recording instead.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close closes the underlying writer, and returns the first recording error if any.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closer != nil {
+		if err := r.closer.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+		r.closer = nil
+	}
+	return r.err
+}
+
+func (r *Recorder) add(direction Direction, closeFrame bool, data []byte) {
+	frame := &Frame{
+		Time:      time.Now(),
+		Direction: direction,
+		Close:     closeFrame,
+		Data:      append([]byte{}, data...),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if err := r.record(frame); err != nil {
+		r.err = fmt.Errorf("unable to record frame. %w", err)
+	}
+}
+
+type recordingReader struct {
+	recorder *Recorder
+	reader   io.Reader
+	buffer   bytes.Buffer
+	recorded bool
+}
+
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.reader.Read(p)
+	rr.buffer.Write(p[:n])
+	if errors.Is(err, io.EOF) && !rr.recorded {
+		rr.recorded = true
+		rr.recorder.add(Inbound, false, rr.buffer.Bytes())
+	}
+	return n, err
+}
+
+type recordingWriter struct {
+	recorder *Recorder
+	writer   io.Writer
+	close    bool
+}
+
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	n, err := rw.writer.Write(p)
+	if err == nil {
+		rw.recorder.add(Outbound, rw.close, p)
+	}
+	return n, err
+}
+
+// ReadRecording reads every frame of a recording.
+func ReadRecording(reader io.Reader) ([]Frame, error) {
+	var frames []Frame
+	decoder := json.NewDecoder(reader)
+	for {
+		var frame Frame
+		if err := decoder.Decode(&frame); err != nil {
+			if errors.Is(err, io.EOF) {
+				return frames, nil
+			}
+			return frames, fmt.Errorf("unable to read frame %d of recording. %w", len(frames), err)
+		}
+		frames = append(frames, frame)
+	}
+}
+
+// OpenRecording reads every frame of the recording file at path.
+func OpenRecording(path string) ([]Frame, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadRecording(file)
+}
@@ -0,0 +1,163 @@
+package gatewayutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/event/opcode"
+	"github.com/discordpkg/gateway/gatewaytest"
+)
+
+func TestFrame_JSON(t *testing.T) {
+	frames := []Frame{
+		{Time: time.Unix(1, 0).UTC(), Direction: Inbound, Data: []byte(`{"op":10,"d":{"heartbeat_interval":45000}}`)},
+		{Time: time.Unix(2, 0).UTC(), Direction: Outbound, Data: []byte{131, 116, 0, 0, 0, 0}},
+		{Time: time.Unix(3, 0).UTC(), Direction: Outbound, Close: true, Data: []byte{3, 232}},
+	}
+
+	var buffer bytes.Buffer
+	recorder := NewRecorder(&buffer)
+	for i := range frames {
+		if err := recorder.record(&frames[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Contains(buffer.Bytes(), []byte(`"text":"{\"op\":10`)) {
+		t.Errorf("text frames should be readable. Got %s", buffer.String())
+	}
+
+	decoded, err := ReadRecording(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(frames) {
+		t.Fatalf("expected %d frames, got %d", len(frames), len(decoded))
+	}
+	for i := range frames {
+		if !decoded[i].Time.Equal(frames[i].Time) || decoded[i].Direction != frames[i].Direction ||
+			decoded[i].Close != frames[i].Close || !bytes.Equal(decoded[i].Data, frames[i].Data) {
+			t.Errorf("frame %d differs. Got %+v, wants %+v", i, decoded[i], frames[i])
+		}
+	}
+}
+
+func TestRecorder_Replay(t *testing.T) {
+	for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			server := gatewaytest.NewServer(gatewaytest.WithBotToken("token"))
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), "session.jsonl")
+			recorder, err := CreateRecording(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			options := []gateway.Option{
+				gateway.WithBotToken("token"),
+				gateway.WithCodec(codec),
+				gateway.WithGuildEvents(event.MessageCreate),
+				gateway.WithIdentifyConnectionProperties(&gateway.IdentifyConnectionProperties{OS: "linux"}),
+			}
+
+			shard, err := NewShard(append(options,
+				gateway.WithCommandRateLimiter(&unlimitedRateLimiter{}),
+				gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+			)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			shard.Recorder = recorder
+
+			if _, err = shard.Dial(ctx, func() (string, error) { return server.URL, nil }); err != nil {
+				t.Fatal(err)
+			}
+			loop := make(chan error, 1)
+			go func() {
+				loop <- shard.EventLoop(ctx)
+			}()
+
+			conn, err := server.Accept(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = conn.Dispatch(event.MessageCreate, map[string]interface{}{"content": "hello"}); err != nil {
+				t.Fatal(err)
+			}
+			if err = conn.Reconnect(); err != nil {
+				t.Fatal(err)
+			}
+			if err = <-loop; err == nil {
+				t.Fatal("expected the event loop to stop on reconnect")
+			}
+			if err = recorder.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			frames, err := OpenRecording(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var recorded []Frame
+			var directions []Direction
+			for _, frame := range frames {
+				// the first heartbeat may be sent immediately, but heartbeats are not replayed
+				var payload gateway.Payload
+				if !frame.Close && codec.Unmarshal(frame.Data, &payload) == nil &&
+					(payload.Op == opcode.Heartbeat || payload.Op == opcode.HeartbeatACK) {
+					continue
+				}
+
+				directions = append(directions, frame.Direction)
+				if frame.Direction == Outbound {
+					recorded = append(recorded, frame)
+				}
+			}
+			// hello, identify, ready, message create, reconnect, close
+			wants := []Direction{Inbound, Outbound, Inbound, Inbound, Inbound, Outbound}
+			if len(directions) != len(wants) {
+				t.Fatalf("expected frames %v, got %v", wants, directions)
+			}
+			if !frames[len(frames)-1].Close {
+				t.Error("expected the last frame to be the close frame")
+			}
+
+			var handled []event.Type
+			client, written, err := Replay(frames, append(options, gateway.WithEventHandler(func(_ gateway.ShardID, evt event.Type, _ encoding.RawMessage) {
+				handled = append(handled, evt)
+			}))...)
+
+			var discordErr *gateway.DiscordError
+			if !errors.As(err, &discordErr) || discordErr.OpCode != opcode.Reconnect {
+				t.Errorf("expected the replay to stop on reconnect, got %v", err)
+			}
+			if client.ResumeURL() != server.ResumeURL() {
+				t.Errorf("expected the replayed client to be resumable. Got resume url '%s'", client.ResumeURL())
+			}
+			if len(handled) == 0 || handled[len(handled)-1] != event.MessageCreate {
+				t.Errorf("expected message create to be handled. Got %v", handled)
+			}
+
+			if len(written) != len(recorded) {
+				t.Fatalf("expected %d written frames, got %d", len(recorded), len(written))
+			}
+			for i := range written {
+				if written[i].Close != recorded[i].Close || !bytes.Equal(written[i].Data, recorded[i].Data) {
+					t.Errorf("written frame %d differs from the recording. Got %q, wants %q", i, written[i].Data, recorded[i].Data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package gatewayutil
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/discordpkg/gateway"
+)
+
+// Replay feeds the inbound frames of a recording into a new client, such that the state transitions and handler
+// calls of a recorded session can be reproduced in tests:
+//
+//	frames, err := gatewayutil.OpenRecording("testdata/session.jsonl")
+//	client, written, err := gatewayutil.Replay(frames,
+//		gateway.WithBotToken("token"),
+//		gateway.WithGuildEvents(event.All()...),
+//		gateway.WithEventHandler(handler),
+//	)
+//
+// The client is created from the options, which must use the same codec as the recorded session. Heartbeats are not
+// replayed, and the rate limiters default to allow every command as the frames are processed without delay.
+//
+// Just like Shard.EventLoop, the replay stops at the first error returned by the client, and the client is closed
+// afterwards. The frames written by the client are returned, such that they can be compared with the outbound frames
+// of the recording.
+func Replay(frames []Frame, options ...gateway.Option) (client *gateway.Client, written []Frame, err error) {
+	options = append([]gateway.Option{
+		gateway.WithCommandRateLimiter(&unlimitedRateLimiter{}),
+		gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+	}, options...)
+	options = append(options, gateway.WithHeartbeatHandler(&replayHeartbeatHandler{}))
+
+	if client, err = gateway.NewClient(options...); err != nil {
+		return nil, nil, err
+	}
+
+	recorder := &Recorder{
+		record: func(frame *Frame) error {
+			written = append(written, *frame)
+			return nil
+		},
+	}
+	payloadWriter := recorder.Writer(io.Discard)
+	closeWriter := recorder.CloseWriter(io.Discard)
+
+	for i := range frames {
+		if frames[i].Direction != Inbound {
+			continue
+		}
+		if _, err = client.ProcessNext(bytes.NewReader(frames[i].Data), payloadWriter); err != nil {
+			break
+		}
+	}
+
+	_ = client.Close(closeWriter)
+	return client, written, err
+}
+
+// replayHeartbeatHandler never sends heartbeats, as the replayed session is not timed
+type replayHeartbeatHandler struct{}
+
+func (h *replayHeartbeatHandler) Configure(_ *gateway.StateCtx, _ time.Duration) {}
+
+func (h *replayHeartbeatHandler) Run() {}
+
+type unlimitedRateLimiter struct{}
+
+var _ gateway.RateLimiter = &unlimitedRateLimiter{}
+
+func (rl *unlimitedRateLimiter) Try(_ gateway.ShardID) (bool, time.Duration) {
+	return true, 0
+}
@@ -0,0 +1,196 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/gatewayutil/log"
+)
+
+var ErrHandlerPanicked = errors.New("event handler panicked")
+
+// Middleware wraps the handling of an event, and decides whether, and how, the next handler is called.
+type Middleware func(next gateway.HandlerV2) gateway.HandlerV2
+
+type RouterOption func(router *Router) error
+
+// WithRouterCodec sets the codec used to decode payloads for typed subscriptions, see Subscribe. The codec must match
+// the client codec. Defaults to encoding.JSONCodec.
+func WithRouterCodec(codec encoding.Codec) RouterOption {
+	return func(router *Router) error {
+		if codec == nil {
+			return errors.New("codec can not be nil")
+		}
+		router.codec = codec
+		return nil
+	}
+}
+
+// WithMiddleware adds middleware that runs once for every routed event, before the subscribers are called. The first
+// middleware is the outermost one.
+func WithMiddleware(middleware ...Middleware) RouterOption {
+	return func(router *Router) error {
+		for _, m := range middleware {
+			if m == nil {
+				return errors.New("middleware can not be nil")
+			}
+		}
+		router.middleware = append(router.middleware, middleware...)
+		return nil
+	}
+}
+
+// NewRouter creates a Router without any subscribers. Use Router.Handle with gateway.WithEventHandlerV2, or
+// Router.HandleEvent with gateway.WithEventHandler.
+func NewRouter(options ...RouterOption) (*Router, error) {
+	router := &Router{
+		codec:       encoding.JSONCodec{},
+		subscribers: map[event.Type][]*subscriber{},
+	}
+	for i := range options {
+		if err := options[i](router); err != nil {
+			return nil, err
+		}
+	}
+
+	router.handler = router.route
+	for i := len(router.middleware) - 1; i >= 0; i-- {
+		router.handler = router.middleware[i](router.handler)
+	}
+	return router, nil
+}
+
+// Router calls the subscribers of an event type, in the order they subscribed, such that handlers don't need to
+// switch on the event type. Subscribers can be added and removed while events are routed.
+type Router struct {
+	codec      encoding.Codec
+	middleware []Middleware
+	handler    gateway.HandlerV2
+
+	mu          sync.RWMutex
+	subscribers map[event.Type][]*subscriber
+}
+
+type subscriber struct {
+	handler gateway.HandlerV2
+}
+
+// On subscribes the handler to the event type, and returns a function that removes the subscription again.
+func (r *Router) On(evt event.Type, handler gateway.HandlerV2) (unsubscribe func()) {
+	sub := &subscriber{handler: handler}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers[evt] = append(r.subscribers[evt], sub)
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		subscribers := r.subscribers[evt]
+		for i := range subscribers {
+			if subscribers[i] != sub {
+				continue
+			}
+
+			// copy on write, as routed events may still iterate the previous subscribers
+			remaining := make([]*subscriber, 0, len(subscribers)-1)
+			remaining = append(remaining, subscribers[:i]...)
+			remaining = append(remaining, subscribers[i+1:]...)
+			if len(remaining) == 0 {
+				delete(r.subscribers, evt)
+			} else {
+				r.subscribers[evt] = remaining
+			}
+			return
+		}
+	}
+}
+
+// Subscribe is the same as Router.On, except the payload is decoded into T using the router codec. T should match the
+// event type, such as event.MessageCreateEvent for event.MessageCreate. Decoding errors are returned without calling
+// the handler.
+func Subscribe[T any](router *Router, evt event.Type, handler func(ctx context.Context, evt *gateway.Event, payload *T) error) (unsubscribe func()) {
+	return router.On(evt, func(ctx context.Context, e *gateway.Event) error {
+		payload := new(T)
+		if err := router.codec.Unmarshal(e.Data, payload); err != nil {
+			return fmt.Errorf("unable to decode %s payload: %w", e.Type, err)
+		}
+		return handler(ctx, e, payload)
+	})
+}
+
+// Handle routes the event through the middleware to the subscribers of the event type. Every subscriber is called,
+// even when an earlier one fails, and the first error is returned.
+func (r *Router) Handle(ctx context.Context, evt *gateway.Event) error {
+	return r.handler(ctx, evt)
+}
+
+// HandleEvent routes the event like Handle, and logs errors as it has no way to return them. It is meant to be used
+// with gateway.WithEventHandler.
+func (r *Router) HandleEvent(shardID gateway.ShardID, evt event.Type, data encoding.RawMessage) {
+	e := &gateway.Event{ShardID: shardID, Type: evt, ReceivedAt: time.Now(), Data: data}
+	if err := r.Handle(context.Background(), e); err != nil {
+		log.Error("router failed to handle %s event: %s", evt, err)
+	}
+}
+
+func (r *Router) route(ctx context.Context, evt *gateway.Event) error {
+	r.mu.RLock()
+	subscribers := r.subscribers[evt.Type]
+	r.mu.RUnlock()
+
+	var firstErr error
+	for _, sub := range subscribers {
+		if err := sub.handler(ctx, evt); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// LogEvents is middleware that logs every event at debug level, along with how long it took to handle.
+func LogEvents() Middleware {
+	return func(next gateway.HandlerV2) gateway.HandlerV2 {
+		return func(ctx context.Context, evt *gateway.Event) error {
+			start := time.Now()
+			err := next(ctx, evt)
+			log.Debug("shard %d handled %s event with sequence number %d in %s", evt.ShardID, evt.Type, evt.Seq, time.Since(start))
+			return err
+		}
+	}
+}
+
+// RecoverPanics is middleware that turns a panic in a later handler into an ErrHandlerPanicked error, which holds the
+// panic value and stack trace. Note that the remaining subscribers of the event are not called.
+func RecoverPanics() Middleware {
+	return func(next gateway.HandlerV2) gateway.HandlerV2 {
+		return func(ctx context.Context, evt *gateway.Event) (err error) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					err = fmt.Errorf("%w: %v\n%s", ErrHandlerPanicked, recovered, debug.Stack())
+				}
+			}()
+			return next(ctx, evt)
+		}
+	}
+}
+
+// FilterEvents is middleware that only passes on the events for which keep returns true.
+func FilterEvents(keep func(evt *gateway.Event) bool) Middleware {
+	return func(next gateway.HandlerV2) gateway.HandlerV2 {
+		return func(ctx context.Context, evt *gateway.Event) error {
+			if !keep(evt) {
+				return nil
+			}
+			return next(ctx, evt)
+		}
+	}
+}
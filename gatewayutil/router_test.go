@@ -0,0 +1,118 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+)
+
+func newRouter(t *testing.T, options ...RouterOption) *Router {
+	router, err := NewRouter(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+func TestRouter(t *testing.T) {
+	t.Run("subscribers", func(t *testing.T) {
+		router := newRouter(t)
+
+		var handled []string
+		subscribe := func(name string, evt event.Type, err error) func() {
+			return router.On(evt, func(_ context.Context, e *gateway.Event) error {
+				handled = append(handled, fmt.Sprintf("%s:%s", name, e.Type))
+				return err
+			})
+		}
+		subscribe("a", event.MessageCreate, nil)
+		unsubscribe := subscribe("b", event.MessageCreate, errors.New("b failed"))
+		subscribe("c", event.MessageCreate, errors.New("c failed"))
+		subscribe("d", event.GuildCreate, nil)
+
+		err := router.Handle(context.Background(), &gateway.Event{Type: event.MessageCreate})
+		if err == nil || err.Error() != "b failed" {
+			t.Errorf("expected the first error to be returned, got %v", err)
+		}
+
+		unsubscribe()
+		unsubscribe()
+		router.HandleEvent(0, event.MessageCreate, nil)
+		router.HandleEvent(0, event.TypingStart, nil)
+
+		wants := "[a:MESSAGE_CREATE b:MESSAGE_CREATE c:MESSAGE_CREATE a:MESSAGE_CREATE c:MESSAGE_CREATE]"
+		if got := fmt.Sprint(handled); got != wants {
+			t.Errorf("incorrect subscribers called. Got %s, wants %s", got, wants)
+		}
+	})
+
+	t.Run("middleware", func(t *testing.T) {
+		var order []string
+		trace := func(name string) Middleware {
+			return func(next gateway.HandlerV2) gateway.HandlerV2 {
+				return func(ctx context.Context, evt *gateway.Event) error {
+					order = append(order, name)
+					return next(ctx, evt)
+				}
+			}
+		}
+
+		router := newRouter(t, WithMiddleware(
+			trace("first"),
+			RecoverPanics(),
+			FilterEvents(func(evt *gateway.Event) bool { return evt.ShardID == 0 }),
+			LogEvents(),
+			trace("last"),
+		))
+		router.On(event.MessageCreate, func(_ context.Context, evt *gateway.Event) error {
+			panic("oops")
+		})
+
+		err := router.Handle(context.Background(), &gateway.Event{Type: event.MessageCreate})
+		if !errors.Is(err, ErrHandlerPanicked) {
+			t.Errorf("expected the panic to be recovered, got %v", err)
+		}
+		if err = router.Handle(context.Background(), &gateway.Event{ShardID: 1, Type: event.MessageCreate}); err != nil {
+			t.Errorf("expected the event to be filtered, got %v", err)
+		}
+		if got := fmt.Sprint(order); got != "[first last first]" {
+			t.Errorf("incorrect middleware order. Got %s", got)
+		}
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			router := newRouter(t, WithRouterCodec(codec))
+
+			var content string
+			Subscribe(router, event.MessageCreate, func(_ context.Context, _ *gateway.Event, msg *event.MessageCreateEvent) error {
+				content = msg.Content
+				return nil
+			})
+
+			data, err := codec.Marshal(map[string]interface{}{"content": "hello"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = router.Handle(context.Background(), &gateway.Event{Type: event.MessageCreate, Data: data}); err != nil {
+				t.Fatal(err)
+			}
+			if content != "hello" {
+				t.Errorf("expected the payload to be decoded, got content '%s'", content)
+			}
+
+			err = router.Handle(context.Background(), &gateway.Event{Type: event.MessageCreate, Data: []byte("malformed")})
+			if err == nil {
+				t.Error("expected a decoding error")
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package gatewayutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/discordpkg/gateway"
+)
+
+// SessionStore persists the sessions of shards, such that a restarted process can resume the sessions instead of
+// identifying every shard again. See Shard.Sessions.
+type SessionStore interface {
+	// Load returns the session of the shard, or nil when no session is stored.
+	Load(shardID gateway.ShardID) (*gateway.Session, error)
+
+	// Save stores the session, replacing any previous session of the same shard.
+	Save(session *gateway.Session) error
+
+	// Delete removes the session of the shard, if any.
+	Delete(shardID gateway.ShardID) error
+}
+
+// NewFileSessionStore stores every session as a json file in the directory, which is created when missing.
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create session directory: %w", err)
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// FileSessionStore is a SessionStore writing a json file per shard. Files are replaced atomically, such that a crash
+// while saving never leaves a partial session behind.
+type FileSessionStore struct {
+	dir string
+}
+
+var _ SessionStore = (*FileSessionStore)(nil)
+
+func (s *FileSessionStore) path(shardID gateway.ShardID) string {
+	return filepath.Join(s.dir, fmt.Sprintf("shard-%d.json", shardID))
+}
+
+func (s *FileSessionStore) Load(shardID gateway.ShardID) (*gateway.Session, error) {
+	data, err := os.ReadFile(s.path(shardID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var session *gateway.Session
+	if err = json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("unable to decode session of shard %d: %w", shardID, err)
+	}
+	return session, nil
+}
+
+func (s *FileSessionStore) Save(session *gateway.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(s.dir, fmt.Sprintf("shard-%d-*.tmp", session.ShardID))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), s.path(session.ShardID))
+}
+
+func (s *FileSessionStore) Delete(shardID gateway.ShardID) error {
+	if err := os.Remove(s.path(shardID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
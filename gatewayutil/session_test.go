@@ -0,0 +1,213 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/gatewaytest"
+)
+
+func TestFileSessionStore(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if session, err := store.Load(1); err != nil || session != nil {
+		t.Errorf("expected no session, got %+v (%v)", session, err)
+	}
+
+	session := &gateway.Session{ID: "session", ResumeGatewayURL: "wss://resume.discord.gg", Sequence: 42, ShardID: 1, ShardCount: 2}
+	if err = store.Save(session); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded == nil || *loaded != *session {
+		t.Errorf("incorrect session. Got %+v, wants %+v", loaded, session)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = store.Delete(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loaded, err = store.Load(1); err != nil || loaded != nil {
+		t.Errorf("expected the session to be deleted, got %+v (%v)", loaded, err)
+	}
+}
+
+func TestShard_Sessions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := gatewaytest.NewServer()
+	defer server.Close()
+
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// resumes must not count as session starts, so the url is only requested to identify
+	var urls int
+	getURL := func() (string, error) {
+		urls++
+		return server.URL, nil
+	}
+
+	// run simulates a process that is stopped once a connection is established
+	run := func() *gatewaytest.Conn {
+		handled := make(chan struct{}, 1)
+		shard, err := NewShard(
+			gateway.WithBotToken("token"),
+			gateway.WithGuildEvents(event.MessageCreate),
+			gateway.WithEventHandler(func(_ gateway.ShardID, _ event.Type, _ encoding.RawMessage) {
+				handled <- struct{}{}
+			}),
+			gateway.WithCommandRateLimiter(&unlimitedRateLimiter{}),
+			gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shard.Sessions = store
+
+		runCtx, stop := context.WithCancel(ctx)
+		result := make(chan error, 1)
+		go func() {
+			result <- shard.Run(runCtx, getURL)
+		}()
+
+		conn, err := server.Accept(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = conn.Dispatch(event.MessageCreate, map[string]interface{}{"content": "hello"}); err != nil {
+			t.Fatal(err)
+		}
+		<-handled
+
+		stop()
+		if err = <-result; !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context cancelled, got %v", err)
+		}
+		return conn
+	}
+
+	identified := run()
+	if identified.Resumed() {
+		t.Fatal("expected the first process to identify")
+	}
+	session, err := store.Load(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session == nil || session.ID != identified.SessionID() || session.ResumeGatewayURL != server.ResumeURL()+"?encoding=json&v=10" {
+		t.Fatalf("expected the session to be stored on shutdown. Got %+v", session)
+	}
+
+	resumed := run()
+	if !resumed.Resumed() || resumed.SessionID() != identified.SessionID() {
+		t.Error("expected the restarted process to resume the session")
+	}
+	if urls != 1 {
+		t.Errorf("expected the url to only be requested for the identify, got %d calls", urls)
+	}
+}
+
+func TestShard_StoredSession(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := gatewaytest.NewServer()
+	defer server.Close()
+
+	newShard := func(store SessionStore) *Shard {
+		shard, err := NewShard(
+			gateway.WithBotToken("token"),
+			gateway.WithGuildEvents(event.MessageCreate),
+			gateway.WithCommandRateLimiter(&unlimitedRateLimiter{}),
+			gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shard.Sessions = store
+		shard.Backoff = func(int) time.Duration { return 0 }
+		return shard
+	}
+	newStore := func(session *gateway.Session) SessionStore {
+		store, err := NewFileSessionStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = store.Save(session); err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+	getURL := func() (string, error) { return server.URL, nil }
+
+	t.Run("failed dial", func(t *testing.T) {
+		store := newStore(&gateway.Session{ID: "session", ResumeGatewayURL: server.ResumeURL(), Sequence: 1, ShardCount: 1})
+		shard := newShard(store)
+
+		if _, err := shard.Dial(ctx, func() (string, error) { return "", ErrGatewayBotRequest }); err == nil {
+			t.Fatal("expected the dial to fail")
+		}
+		if session, _ := store.Load(0); session == nil {
+			t.Fatal("expected the session to be kept when the dial fails")
+		}
+
+		conn, err := shard.Dial(ctx, getURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if session, _ := store.Load(0); session != nil {
+			t.Error("expected the session to be removed once the client holds it")
+		}
+	})
+
+	for name, session := range map[string]*gateway.Session{
+		"different shard": {ID: "session", ResumeGatewayURL: "ws://127.0.0.1:1", Sequence: 1, ShardCount: 2},
+		"incomplete":      {ResumeGatewayURL: "ws://127.0.0.1:1", ShardCount: 1},
+		"refused resume":  {ID: "session", ResumeGatewayURL: "ws://127.0.0.1:1", Sequence: 1, ShardCount: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(session)
+			runCtx, stop := context.WithCancel(ctx)
+			defer stop()
+
+			result := make(chan error, 1)
+			go func() {
+				result <- newShard(store).Run(runCtx, getURL)
+			}()
+
+			// the stored session is never resumed, and the shard identifies instead
+			conn, err := server.Accept(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if conn.Resumed() {
+				t.Error("expected the shard to identify")
+			}
+			if stored, _ := store.Load(0); stored != nil {
+				t.Error("expected the unusable session to be removed")
+			}
+
+			stop()
+			if err = <-result; !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context cancelled, got %v", err)
+			}
+		})
+	}
+}
@@ -1,15 +1,26 @@
 package gatewayutil
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net"
+	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/closecode"
+	"github.com/discordpkg/gateway/encoding"
 	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/event/opcode"
+	"github.com/discordpkg/gateway/gatewayutil/log"
 
 	"github.com/gobwas/ws"
 	"github.com/gobwas/ws/wsutil"
@@ -27,17 +38,60 @@ func (e *WebsocketError) Unwrap() error {
 	return e.Err
 }
 
+// ErrNotConnected is returned when a command is sent while the shard has no connection, such as before the first dial
+// or while Run reconnects.
+var ErrNotConnected = errors.New("shard is not connected")
+
+// ConfigError is returned when the shard can not dial due to invalid options or an invalid dial url. Run does not
+// reconnect on a ConfigError, as every attempt would fail the same way.
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Errorf("invalid shard configuration: %w", e.Err).Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ioWriteFlusher writes and flushes a complete websocket frame. Payloads are written by both the event loop and the
+// heartbeat process, so the writers of a connection share a mutex.
 type ioWriteFlusher struct {
 	writer *wsutil.Writer
+	mu     *sync.Mutex
 }
 
 func (i *ioWriteFlusher) Write(p []byte) (n int, err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	if n, err = i.writer.Write(p); err != nil {
 		return n, err
 	}
 	return n, i.writer.Flush()
 }
 
+// bufferedConn reads the data buffered during the websocket handshake, before reading from the connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// activeConn holds the client of a websocket connection and the writer of its payloads. They are published together,
+// such that a command is always written to the connection of the client that validated it.
+type activeConn struct {
+	client *gateway.Client
+	writer io.Writer
+
+	// closed is closed once the connection is no longer used, and a response to a command can no longer arrive
+	closed chan struct{}
+}
+
 func NewShard(options ...gateway.Option) (*Shard, error) {
 	shard := &Shard{
 		options: options,
@@ -50,83 +104,370 @@ type Shard struct {
 	options []gateway.Option
 	client  *gateway.Client
 
-	Conn        net.Conn
-	textWriter  io.Writer
-	closeWriter io.Writer
+	// current holds the client and payload writer of the open connection, such that commands and the latency can be
+	// used from other goroutines while Run replaces them. It is nil while disconnected.
+	current atomic.Pointer[activeConn]
+
+	Conn          net.Conn
+	payloadWriter io.Writer
+	closeWriter   io.Writer
+	writeMu       sync.Mutex
+
+	// codec and payloadOp are derived from the client options, etf payloads are sent as binary frames
+	codec     encoding.Codec
+	payloadOp ws.OpCode
+
+	// metrics and shardID are derived from the client options, and reported to on reconnects
+	metrics gateway.Metrics
+	shardID gateway.ShardID
+
+	// query holds the query parameters of the last dial url
+	query        string
+	decompressor decompressor
+
+	// Backoff decides how long Run waits before reconnecting. Defaults to an exponential backoff with jitter,
+	// starting at 1 second and capped at 1 minute.
+	Backoff Backoff
+
+	// Recorder records every frame read and written by the client, when set. See Recorder.
+	Recorder *Recorder
+
+	// Sessions persists the session whenever the event loop returns, and is used to resume the session on the first
+	// dial. This allows a restarted process to resume instead of identifying again. See FileSessionStore.
+	Sessions SessionStore
+
+	// resumeDialFailures counts the consecutive failed dials to the resume url, see maxResumeDialAttempts
+	resumeDialFailures int
+
+	// established is set once the current connection received its first dispatch event
+	established   bool
+	onStateChange func(state ShardState, err error)
+}
+
+// Backoff returns the delay before the next reconnect attempt. The attempt count starts at 0 and is reset once a
+// connection has been successfully established.
+type Backoff func(attempt int) time.Duration
+
+// ExponentialBackoff doubles the delay for every attempt, from min up to max. A random jitter of up to half the delay
+// is subtracted to avoid every shard reconnecting at the same time.
+func ExponentialBackoff(min, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		delay := max
+		if attempt < 32 {
+			if d := min << attempt; d > 0 && d < max {
+				delay = d
+			}
+		}
+
+		half := int64(delay / 2)
+		if half <= 0 {
+			return delay
+		}
+		return delay - time.Duration(rand.Int63n(half))
+	}
 }
 
+var defaultBackoff = ExponentialBackoff(time.Second, time.Minute)
+
+// maxResumeDialAttempts is the number of failed dials to the resume url of a session, after which the session is
+// dropped and a new session is identified using the url of GetGatewayBotURL.
+const maxResumeDialAttempts = 3
+
 type GetGatewayBotURL func() (string, error)
 
 // Dial sets up the websocket connection before identifying with the gateway.
-// The url must be complete and specify api version and encoding:
+// The url must be complete and specify api version and encoding (see DialURL):
 //
 //	"wss://gateway.discord.gg/"                      => invalid
 //	"wss://gateway.discord.gg/?v=10"                 => invalid
 //	"wss://gateway.discord.gg/?v=10&encoding=json"   => valid
+//
+// The encoding is always set to match the codec of the client (see gateway.WithCodec), which defaults to json.
+// Transport compression is enabled by adding "compress=zlib-stream" or "compress=zstd-stream" to the url.
 func (s *Shard) Dial(ctx context.Context, getURL GetGatewayBotURL) (connection net.Conn, err error) {
+	settings, err := s.settings()
+	if err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+	codec := settings.Codec
+	s.metrics, s.shardID = settings.Metrics, settings.ShardID
+
+	// a resume url that keeps failing may never accept the session again, so a new session is identified instead
+	parent := s.client
+	if s.resumeDialFailures >= maxResumeDialAttempts {
+		log.Info("shard %d failed to dial the resume url %d times, identifying a new session", s.shardID, s.resumeDialFailures)
+		s.resumeDialFailures = 0
+		parent = nil
+		if s.client == nil && s.Sessions != nil {
+			s.deleteSession()
+		}
+	}
+
+	// a new shard resumes the stored session, which is removed once the client is created
+	var session *gateway.Session
+	if s.client == nil && s.Sessions != nil {
+		session = s.loadSession(settings)
+	}
+
 	dialURL := ""
-	if s.client != nil {
-		dialURL = s.client.ResumeURL()
+	if parent != nil {
+		// the resume url does not specify version nor encoding, so the parameters of the previous url are reused
+		if dialURL = parent.ResumeURL(); dialURL != "" {
+			dialURL = withQuery(dialURL, s.query)
+		}
+	}
+	resuming := dialURL != "" || session != nil
+	if dialURL == "" && session != nil {
+		// a stored session holds the query parameters of its dial url, such that getURL is only called to identify,
+		// as a GatewayBotClient counts every url as a session start
+		if u, err := url.Parse(session.ResumeGatewayURL); err == nil && u.RawQuery != "" {
+			dialURL = session.ResumeGatewayURL
+		}
 	}
 	if dialURL == "" {
 		dialURL, err = getURL()
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("unable to get a URL for websocket dial: %w", err)
 		}
 		if dialURL == "" {
 			return nil, errors.New("unable to get a URL for websocket dial")
 		}
+		if session != nil {
+			// sessions stored by earlier versions lack the query parameters, just like the resume url of a client
+			if u, err := url.Parse(dialURL); err == nil {
+				dialURL = withQuery(session.ResumeGatewayURL, u.RawQuery)
+			}
+		}
 	}
 
-	dialURL, err = ValidateDialURL(dialURL)
+	dialURL, err = ValidateDialURL(withEncoding(dialURL, codec.Name()))
 	if err != nil {
-		return nil, err
+		return nil, &ConfigError{Err: err}
+	}
+	u, err := url.Parse(dialURL)
+	if err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+	s.query = u.RawQuery
+
+	// the compression context is bound to the connection, so a new decompressor is created once connected
+	if s.decompressor != nil {
+		_ = s.decompressor.Close()
+		s.decompressor = nil
+	}
+
+	s.codec, s.payloadOp = codec, ws.OpText
+	if codec.FrameType() == encoding.BinaryFrame {
+		s.payloadOp = ws.OpBinary
 	}
 
 	conn, reader, _, err := ws.Dial(ctx, dialURL)
 	if err != nil {
-		return nil, err
+		if resuming {
+			s.resumeDialFailures++
+		}
+		return nil, &WebsocketError{Err: err}
 	}
+	s.resumeDialFailures = 0
 
 	if reader != nil {
-		defer ws.PutReader(reader)
-		if reader.Size() > 0 {
-			_ = conn.Close()
-			return nil, fmt.Errorf("unable to handle data sent ... ")
-			// TODO: this should be handled somehow??
-		}
+		// frames sent right after the handshake, such as hello, may already be buffered by the reader
+		conn = &bufferedConn{Conn: conn, reader: reader}
+	}
+
+	compression := TransportCompression(u.Query().Get("compress"))
+	if s.decompressor, err = newDecompressor(compression); err != nil {
+		_ = conn.Close()
+		return nil, &ConfigError{Err: err}
 	}
 
 	s.Conn = conn
-	s.textWriter = s.writer(ws.OpText)
+	s.established = false
+	s.payloadWriter = s.writer(s.payloadOp)
 	s.closeWriter = s.writer(ws.OpClose)
+	if s.Recorder != nil {
+		s.payloadWriter = s.Recorder.Writer(s.payloadWriter)
+		s.closeWriter = s.Recorder.CloseWriter(s.closeWriter)
+	}
 
-	options := append(s.options, gateway.WithExistingSession(s.client))
+	options := append(s.options, gateway.WithExistingSession(parent), gateway.WithSessionSnapshot(session))
 	options = append(options, gateway.WithHeartbeatHandler(&gateway.DefaultHeartbeatHandler{
-		TextWriter:       s.textWriter,
+		TextWriter:       s.payloadWriter,
 		ConnectionCloser: s.Conn,
 	}))
 
-	if s.client, err = gateway.NewClient(options...); err != nil {
-		return nil, err
+	client, err := gateway.NewClient(options...)
+	if err != nil {
+		_ = conn.Close()
+		if s.decompressor != nil {
+			_ = s.decompressor.Close()
+			s.decompressor = nil
+		}
+		return nil, &ConfigError{Err: err}
+	}
+	s.client = client
+	s.publish(&activeConn{client: client, writer: s.payloadWriter, closed: make(chan struct{})})
+	if session != nil {
+		// the session is now held by the client, and stored again when the event loop returns
+		s.deleteSession()
 	}
 
 	return conn, nil
 }
 
+// settings returns the configuration of the shard, such as the codec which decides the encoding of the dial url and
+// the frame type of the connection. The options are evaluated without creating a client, which would report a state
+// change to the metrics.
+func (s *Shard) settings() (*gateway.Settings, error) {
+	options := s.options[:len(s.options):len(s.options)]
+	options = append(options, gateway.WithHeartbeatHandler(&gateway.DefaultHeartbeatHandler{}))
+
+	return gateway.ReadSettings(options...)
+}
+
+// loadSession returns the stored session, or nil when the shard should identify instead. A session that is invalid
+// or belongs to a different shard can never be resumed, so it is removed.
+func (s *Shard) loadSession(settings *gateway.Settings) *gateway.Session {
+	session, err := s.Sessions.Load(s.shardID)
+	if err != nil {
+		log.Error("unable to load session of shard %d, identifying instead: %s", s.shardID, err)
+		return nil
+	}
+	if session == nil {
+		return nil
+	}
+
+	if err = session.Validate(); err != nil {
+		log.Info("ignoring stored session of shard %d: %s", s.shardID, err)
+		s.deleteSession()
+		return nil
+	}
+	if session.ShardID != settings.ShardID || session.ShardCount != settings.ShardCount {
+		log.Info("ignoring stored session of shard %d out of %d shards, as the shard is %d out of %d shards",
+			session.ShardID, session.ShardCount, settings.ShardID, settings.ShardCount)
+		s.deleteSession()
+		return nil
+	}
+	return session
+}
+
+func (s *Shard) deleteSession() {
+	if err := s.Sessions.Delete(s.shardID); err != nil {
+		log.Error("unable to delete session of shard %d: %s", s.shardID, err)
+	}
+}
+
+// saveSession stores the session when it can be resumed, otherwise any stored session is removed
+func (s *Shard) saveSession() {
+	if s.Sessions == nil {
+		return
+	}
+
+	session, err := s.client.Session()
+	if err != nil {
+		err = s.Sessions.Delete(s.shardID)
+	} else {
+		// the query parameters of the dial url, such as the encoding, are kept for resuming in a new process
+		session.ResumeGatewayURL = withQuery(session.ResumeGatewayURL, s.query)
+		err = s.Sessions.Save(session)
+	}
+	if err != nil {
+		log.Error("unable to store session of shard %d: %s", s.shardID, err)
+	}
+}
+
+// Latency returns the heartbeat round trip time of the current connection, see gateway.Client.Latency. It is safe to
+// call from any goroutine, and is 0 while disconnected and after every reconnect until the first heartbeat is
+// acknowledged.
+func (s *Shard) Latency() time.Duration {
+	conn := s.current.Load()
+	if conn == nil {
+		return 0
+	}
+	return conn.client.Latency()
+}
+
+// AverageLatency returns the moving average of the heartbeat round trip times of the current connection, see
+// gateway.Client.AverageLatency. It is safe to call from any goroutine, and is 0 while disconnected and after every
+// reconnect.
+func (s *Shard) AverageLatency() time.Duration {
+	conn := s.current.Load()
+	if conn == nil {
+		return 0
+	}
+	return conn.client.AverageLatency()
+}
+
+// publish replaces the open connection, and notifies the users of the previous one that it is closed
+func (s *Shard) publish(conn *activeConn) {
+	if previous := s.current.Swap(conn); previous != nil {
+		close(previous.closed)
+	}
+}
+
+// active returns the open connection, or ErrNotConnected while the shard is disconnected
+func (s *Shard) active() (*activeConn, error) {
+	conn := s.current.Load()
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+	return conn, nil
+}
+
+// Write sends a payload on the current connection. Like the commands, it is safe to call from any goroutine and
+// returns ErrNotConnected while the shard is disconnected.
 func (s *Shard) Write(op event.Type, data []byte) error {
-	return s.client.Write(s.textWriter, op, data)
+	conn, err := s.active()
+	if err != nil {
+		return err
+	}
+	return conn.client.Write(conn.writer, op, data)
+}
+
+// RequestGuildMembers sends a request guild members command, see gateway.Client.RequestGuildMembers.
+func (s *Shard) RequestGuildMembers(request *gateway.RequestGuildMembers) error {
+	_, err := s.requestGuildMembers(request)
+	return err
+}
+
+// requestGuildMembers returns a channel that is closed once the connection the request was sent on is closed, as
+// the member chunks can no longer arrive after that. See MemberChunkAggregator.
+func (s *Shard) requestGuildMembers(request *gateway.RequestGuildMembers) (<-chan struct{}, error) {
+	conn, err := s.active()
+	if err != nil {
+		return nil, err
+	}
+	if err = conn.client.RequestGuildMembers(conn.writer, request); err != nil {
+		return nil, err
+	}
+	return conn.closed, nil
+}
+
+// UpdatePresence sends an update presence command, see gateway.Client.UpdatePresence.
+func (s *Shard) UpdatePresence(presence *gateway.UpdatePresence) error {
+	conn, err := s.active()
+	if err != nil {
+		return err
+	}
+	return conn.client.UpdatePresence(conn.writer, presence)
+}
+
+// UpdateVoiceState sends an update voice state command, see gateway.Client.UpdateVoiceState.
+func (s *Shard) UpdateVoiceState(voiceState *gateway.UpdateVoiceState) error {
+	conn, err := s.active()
+	if err != nil {
+		return err
+	}
+	return conn.client.UpdateVoiceState(conn.writer, voiceState)
 }
 
 func (s *Shard) writer(op ws.OpCode) io.Writer {
-	return &ioWriteFlusher{wsutil.NewWriter(s.Conn, ws.StateClientSide, op)}
+	return &ioWriteFlusher{writer: wsutil.NewWriter(s.Conn, ws.StateClientSide, op), mu: &s.writeMu}
 }
 
 func (s *Shard) nextFrame(rd *wsutil.Reader, ctrlFrameHandler wsutil.FrameHandlerFunc) (io.Reader, error) {
 	hdr, err := rd.NextFrame()
 	if err != nil {
-		_ = s.Conn.Close()
-
 		msg := err.Error()
 		closedConnection := strings.Contains(msg, "use of closed network connection")
 		closedConnection = closedConnection || strings.Contains(msg, "use of closed connection")
@@ -143,16 +484,34 @@ func (s *Shard) nextFrame(rd *wsutil.Reader, ctrlFrameHandler wsutil.FrameHandle
 		if err := ctrlFrameHandler(hdr, rd); err != nil {
 			var errClose wsutil.ClosedError
 			if errors.As(err, &errClose) {
-				mockedMessage := fmt.Sprintf("{\"closecode\":%d,\"d\":\"%s\"}", errClose.Code, errClose.Reason)
-				return strings.NewReader(mockedMessage), nil
+				mockedMessage, err := s.closePayload(errClose)
+				if err != nil {
+					return nil, &WebsocketError{Err: err}
+				}
+				return bytes.NewReader(mockedMessage), nil
 			} else {
 				return nil, &WebsocketError{Err: err}
 			}
 		}
 		return nil, nil
 	}
-	if hdr.OpCode&ws.OpText == 0 {
-		// discord only uses text, even for heartbeats / ping/pong frames
+	if hdr.OpCode == ws.OpBinary && s.decompressor != nil {
+		// transport compression uses binary frames, a complete payload may be spread across multiple messages
+		data, err := io.ReadAll(rd)
+		if err != nil {
+			return nil, &WebsocketError{Err: err}
+		}
+
+		inflated, err := s.decompressor.Decompress(data)
+		if err != nil {
+			return nil, &WebsocketError{Err: err}
+		} else if inflated == nil {
+			return nil, nil
+		}
+		return bytes.NewReader(inflated), nil
+	}
+	if hdr.OpCode != s.payloadOp {
+		// discord only uses text frames, or binary frames for etf, even for heartbeats / ping/pong frames
 		if err := rd.Discard(); err != nil {
 			return nil, &WebsocketError{Err: err}
 		}
@@ -162,8 +521,44 @@ func (s *Shard) nextFrame(rd *wsutil.Reader, ctrlFrameHandler wsutil.FrameHandle
 	return rd, nil
 }
 
+// closePayload mocks a payload for the close frame, such that the client can handle the close code
+func (s *Shard) closePayload(errClose wsutil.ClosedError) ([]byte, error) {
+	reason, err := s.codec.Marshal(errClose.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.codec.Marshal(&gateway.Payload{
+		CloseCode: closecode.Type(errClose.Code),
+		Data:      reason,
+	})
+}
+
+// EventLoop reads and processes incoming websocket frames until the connection is lost, the client fails or the
+// context is cancelled. On return the client is closed, which notifies Discord, and the connection is closed.
 func (s *Shard) EventLoop(ctx context.Context) error {
-	defer s.client.Close(s.closeWriter)
+	defer func() {
+		// commands sent from here on fail with ErrNotConnected, instead of being written to a closing connection
+		s.publish(nil)
+		_ = s.client.Close(s.closeWriter)
+		_ = s.Conn.Close()
+		s.saveSession()
+		if s.decompressor != nil {
+			_ = s.decompressor.Close()
+		}
+	}()
+
+	// a cancelled context must interrupt any blocking read, but the connection is kept open such that the client
+	// can still write a close frame before the event loop returns
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = s.Conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
 
 	controlHandler := wsutil.ControlFrameHandler(s.Conn, ws.StateClientSide)
 	rd := wsutil.Reader{
@@ -177,18 +572,115 @@ func (s *Shard) EventLoop(ctx context.Context) error {
 	for {
 		reader, err := s.nextFrame(&rd, controlHandler)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
 			return err
 		} else if reader == nil {
 			continue
 		}
+		if s.Recorder != nil {
+			reader = s.Recorder.Reader(reader)
+		}
 
-		_, err = s.client.ProcessNext(reader, s.textWriter)
+		payload, err := s.client.ProcessNextContext(ctx, reader, s.payloadWriter)
 		if err != nil {
 			return err
 		}
+		if payload.Op == opcode.Dispatch {
+			s.established = true
+		}
 
 		if err = ctx.Err(); err != nil {
 			return err
 		}
 	}
 }
+
+// Run owns the complete lifecycle of the shard. It dials the gateway and runs the event loop, and on recoverable
+// errors it waits in accordance with the Backoff before dialing again. The session is resumed when possible,
+// otherwise a new session is identified. A resume url that fails to dial 3 times in a row is given up on, and a new
+// session is identified using the url of getURL.
+//
+// Any other error, such as a failing getURL or a connection that was lost, is retried. Run only returns on close
+// codes that does not allow reconnecting, such as AuthenticationFailed or DisallowedIntents, on a ConfigError, or
+// when the context is cancelled.
+func (s *Shard) Run(ctx context.Context, getURL GetGatewayBotURL) error {
+	backoff := s.Backoff
+	if backoff == nil {
+		backoff = defaultBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		s.setState(ShardConnecting, nil)
+		_, err := s.Dial(ctx, getURL)
+		if err == nil {
+			s.setState(ShardRunning, nil)
+			err = s.EventLoop(ctx)
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			s.setState(ShardStopped, nil)
+			return ctxErr
+		}
+		if !canReconnect(err) {
+			s.setState(ShardFailed, err)
+			return err
+		}
+
+		if s.established {
+			attempt = 0
+		}
+		delay := backoff(attempt)
+		log.Info("shard disconnected, reconnecting in %s: %s", delay, err)
+
+		s.setState(ShardReconnecting, err)
+		if s.metrics != nil {
+			s.metrics.Reconnect(s.shardID)
+		}
+		if !sleep(ctx, delay) {
+			s.setState(ShardStopped, nil)
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Shard) setState(state ShardState, err error) {
+	if s.onStateChange != nil {
+		s.onStateChange(state, err)
+	}
+}
+
+// canReconnect reports whether the shard may dial a new connection after the given error. Only close codes that do
+// not allow reconnecting and configuration errors are final.
+func canReconnect(err error) bool {
+	var discordErr *gateway.DiscordError
+	if errors.As(err, &discordErr) {
+		// session issues signaled by operation codes, such as InvalidSession, and websocket level close codes, such as
+		// 1001 going away, are not Discord close codes. The client state decides whether to resume or identify.
+		if discordErr.CloseCode < 4000 {
+			return true
+		}
+		return discordErr.CanReconnect()
+	}
+
+	var configErr *ConfigError
+	return !errors.As(err, &configErr)
+}
+
+// sleep blocks for the given duration, and returns false if the context was cancelled before then.
+func sleep(ctx context.Context, duration time.Duration) bool {
+	if duration <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,420 @@
+package gatewayutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway"
+	"github.com/discordpkg/gateway/closecode"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/event/opcode"
+	"github.com/discordpkg/gateway/gatewaytest"
+
+	"github.com/gobwas/ws/wsutil"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 10*time.Second)
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 500 * time.Millisecond, time.Second},
+		{1, time.Second, 2 * time.Second},
+		{3, 4 * time.Second, 8 * time.Second},
+		{4, 5 * time.Second, 10 * time.Second},
+		{100, 5 * time.Second, 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 20; i++ {
+			delay := backoff(test.attempt)
+			if delay < test.min || delay > test.max {
+				t.Errorf("attempt %d: delay %s is outside [%s, %s]", test.attempt, delay, test.min, test.max)
+			}
+		}
+	}
+}
+
+func TestCanReconnect(t *testing.T) {
+	tests := []struct {
+		err       error
+		reconnect bool
+	}{
+		{&gateway.DiscordError{CloseCode: closecode.UnknownError}, true},
+		{&gateway.DiscordError{CloseCode: closecode.SessionTimedOut}, true},
+		{&gateway.DiscordError{CloseCode: closecode.AuthenticationFailed}, false},
+		{&gateway.DiscordError{CloseCode: closecode.DisallowedIntents}, false},
+		{&gateway.DiscordError{CloseCode: 1000}, true},
+		{&gateway.DiscordError{CloseCode: 1001}, true},
+		{&gateway.DiscordError{OpCode: opcode.InvalidSession}, true},
+		{&gateway.DiscordError{OpCode: opcode.Reconnect}, true},
+		{&WebsocketError{Err: errors.New("connection reset")}, true},
+		{fmt.Errorf("wrapped: %w", gateway.ErrIdentifyRateLimited), true},
+		{gateway.ErrOutOfSync, true},
+		{fmt.Errorf("unable to get a URL for websocket dial: %w", ErrGatewayBotRequest), true},
+		{errors.New("zlib: invalid header"), true},
+		{&ConfigError{Err: ErrURLScheme}, false},
+		{&ConfigError{Err: errors.New("missing bot token")}, false},
+	}
+
+	for _, test := range tests {
+		if got := canReconnect(test.err); got != test.reconnect {
+			t.Errorf("%s: expected %t, got %t", test.err, test.reconnect, got)
+		}
+	}
+}
+
+func TestShard_RunRetries(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := gatewaytest.NewServer()
+	defer server.Close()
+
+	newShard := func() *Shard {
+		shard, err := NewShard(
+			gateway.WithBotToken("token"),
+			gateway.WithGuildEvents(event.MessageCreate),
+			gateway.WithCommandRateLimiter(NewCommandRateLimiter()),
+			gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shard.Backoff = func(int) time.Duration { return 0 }
+		return shard
+	}
+
+	t.Run("get url", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		attempts := 0
+		result := make(chan error, 1)
+		go func() {
+			result <- newShard().Run(ctx, func() (string, error) {
+				if attempts++; attempts < 3 {
+					return "", ErrGatewayBotRequest
+				}
+				return server.URL, nil
+			})
+		}()
+
+		if _, err := server.Accept(ctx); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		if err := <-result; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context cancelled, got %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("expected failing gateway urls to be retried, got %d attempts", attempts)
+		}
+	})
+
+	t.Run("config error", func(t *testing.T) {
+		err := newShard().Run(ctx, func() (string, error) {
+			return "https://gateway.discord.gg?v=10&encoding=json", nil
+		})
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || !errors.Is(err, ErrURLScheme) {
+			t.Errorf("expected a config error, got %v", err)
+		}
+	})
+}
+
+func TestShard_closePayload(t *testing.T) {
+	errClose := wsutil.ClosedError{Code: 4004, Reason: `Authentication "failed"`}
+
+	for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+		t.Run(fmt.Sprintf("%T", codec), func(t *testing.T) {
+			shard := &Shard{codec: codec}
+			data, err := shard.closePayload(errClose)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var payload gateway.Payload
+			if err = codec.Unmarshal(data, &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.CloseCode != closecode.AuthenticationFailed {
+				t.Errorf("incorrect close code. Got %d", payload.CloseCode)
+			}
+
+			var reason string
+			if err = codec.Unmarshal(payload.Data, &reason); err != nil {
+				t.Fatal(err)
+			}
+			if reason != errClose.Reason {
+				t.Errorf("incorrect reason. Got '%s'", reason)
+			}
+		})
+	}
+}
+
+type stateMetrics struct {
+	gateway.Metrics
+	states []string
+}
+
+func (m *stateMetrics) StateChanged(_ gateway.ShardID, state string) {
+	m.states = append(m.states, state)
+}
+
+func TestShard_DialStateMetrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := gatewaytest.NewServer()
+	defer server.Close()
+
+	metrics := &stateMetrics{}
+	shard, err := NewShard(
+		gateway.WithBotToken("token"),
+		gateway.WithCommandRateLimiter(&unlimitedRateLimiter{}),
+		gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+		gateway.WithMetrics(metrics),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := shard.Dial(ctx, func() (string, error) { return server.URL, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if got := fmt.Sprint(metrics.states); got != "[hello]" {
+		t.Errorf("expected only the state of the dialled client to be reported, got %s", got)
+	}
+}
+
+func TestShard_Run(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := gatewaytest.NewServer(gatewaytest.WithHeartbeatInterval(20 * time.Millisecond))
+	defer server.Close()
+
+	contents := make(chan string, 10)
+	shard, err := NewShard(
+		gateway.WithBotToken("token"),
+		gateway.WithGuildEvents(event.MessageCreate),
+		gateway.WithCommandRateLimiter(NewCommandRateLimiter()),
+		gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+		gateway.WithEventHandler(func(_ gateway.ShardID, _ event.Type, data encoding.RawMessage) {
+			var message struct {
+				Content string `json:"content"`
+			}
+			_ = encoding.Unmarshal(data, &message)
+			contents <- message.Content
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shard.Backoff = func(int) time.Duration { return 0 }
+
+	result := make(chan error, 1)
+	go func() {
+		result <- shard.Run(ctx, func() (string, error) { return server.URL, nil })
+	}()
+
+	// the latency is read by other goroutines, such as a ping command, while Run replaces the client
+	go func() {
+		for ctx.Err() == nil {
+			_, _ = shard.Latency(), shard.AverageLatency()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	dispatch := func(conn *gatewaytest.Conn, content string) {
+		if err := conn.Dispatch(event.MessageCreate, map[string]interface{}{"content": content}); err != nil {
+			t.Fatal(err)
+		}
+		if got := <-contents; got != content {
+			t.Errorf("incorrect content. Got '%s', wants '%s'", got, content)
+		}
+	}
+
+	conn, err := server.Accept(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatch(conn, "first")
+	if err = conn.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := server.Accept(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resumed.Resumed() || resumed.SessionID() != conn.SessionID() {
+		t.Fatalf("expected the session to be resumed")
+	}
+	dispatch(resumed, "second")
+	if resumed.Seq() != 4 {
+		t.Errorf("expected ready, two message creates and resumed in the session. Got seq %d", resumed.Seq())
+	}
+
+	if err = resumed.InvalidSession(false); err != nil {
+		t.Fatal(err)
+	}
+	identified, err := server.Accept(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identified.Resumed() || identified.SessionID() == conn.SessionID() {
+		t.Error("expected a new session after an invalid session")
+	}
+
+	cancel()
+	if err = <-result; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context cancelled, got %v", err)
+	}
+}
+
+func TestShard_CommandsWhileReconnecting(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := gatewaytest.NewServer(gatewaytest.WithHeartbeatInterval(20 * time.Millisecond))
+	defer server.Close()
+
+	shard, err := NewShard(
+		gateway.WithBotToken("token"),
+		gateway.WithCommandRateLimiter(&unlimitedRateLimiter{}),
+		gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shard.Backoff = func(int) time.Duration { return 0 }
+
+	presence := &gateway.UpdatePresence{Status: gateway.StatusOnline}
+	if err = shard.UpdatePresence(presence); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected a command before dialing to fail, got %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- shard.Run(ctx, func() (string, error) { return server.URL, nil })
+	}()
+
+	// commands are sent by other goroutines, while Run replaces the connection
+	sending, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-sending:
+				return
+			default:
+			}
+			_ = shard.UpdatePresence(presence)
+			_ = shard.Write(event.UpdatePresence, []byte(`{"status":"online","since":null,"activities":[],"afk":false}`))
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		conn, err := server.Accept(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload, err := conn.Receive(ctx); err != nil || payload.Op != opcode.PresenceUpdate {
+			t.Fatalf("expected the commands to be sent on the new connection, got %+v (%v)", payload, err)
+		}
+		if err = conn.Reconnect(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = server.Accept(ctx); err != nil {
+		t.Fatal(err)
+	}
+	close(sending)
+	<-stopped
+
+	cancel()
+	if err = <-result; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context cancelled, got %v", err)
+	}
+	if err = shard.UpdatePresence(presence); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected a command after the event loop returned to fail, got %v", err)
+	}
+}
+
+func TestShard_RunResumeRefused(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the port of a closed listener refuses every connection
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := "ws://" + listener.Addr().String() + "/"
+	_ = listener.Close()
+
+	server := gatewaytest.NewServer(gatewaytest.WithResumeURL(refused))
+	defer server.Close()
+
+	shard, err := NewShard(
+		gateway.WithBotToken("token"),
+		gateway.WithGuildEvents(event.MessageCreate),
+		gateway.WithCommandRateLimiter(&unlimitedRateLimiter{}),
+		gateway.WithIdentifyRateLimiter(&unlimitedRateLimiter{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shard.Backoff = func(int) time.Duration { return 0 }
+
+	var urls atomic.Int32
+	result := make(chan error, 1)
+	go func() {
+		result <- shard.Run(ctx, func() (string, error) {
+			urls.Add(1)
+			return server.URL, nil
+		})
+	}()
+
+	conn, err := server.Accept(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Dispatch(event.MessageCreate, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	identified, err := server.Accept(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identified.Resumed() || identified.SessionID() == conn.SessionID() {
+		t.Error("expected a new session once the resume url kept refusing the connection")
+	}
+	if got := urls.Load(); got != 2 {
+		t.Errorf("expected a new url for the identify, got %d calls", got)
+	}
+
+	cancel()
+	if err = <-result; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context cancelled, got %v", err)
+	}
+}
@@ -19,6 +19,7 @@ var supportedAPIVersions = []string{
 }
 var supportedAPICodes = []string{
 	"json",
+	"etf",
 }
 
 var ErrURLScheme = errors.New("url scheme was not websocket (ws nor wss)")
@@ -44,6 +45,7 @@ func ValidateDialURL(URLString string) (string, error) {
 	scheme := u.Scheme
 	v := u.Query().Get("v")
 	encoding := u.Query().Get("encoding")
+	compress := u.Query().Get("compress")
 
 	if v == "" || encoding == "" || scheme == "" {
 		return "", ErrIncompleteDialURL
@@ -58,6 +60,72 @@ func ValidateDialURL(URLString string) (string, error) {
 	if encoding != "" && !in(encoding, supportedAPICodes) {
 		return "", ErrUnsupportedAPICodec
 	}
+	if compress != "" && !in(compress, supportedCompressions) {
+		return "", ErrUnsupportedCompression
+	}
 
 	return u.String(), nil
 }
+
+type DialURLOption func(query url.Values)
+
+// WithTransportCompression enables compression of the whole websocket connection.
+func WithTransportCompression(compression TransportCompression) DialURLOption {
+	return func(query url.Values) {
+		if compression == NoCompression {
+			query.Del("compress")
+		} else {
+			query.Set("compress", string(compression))
+		}
+	}
+}
+
+// DialURL completes the url given by the "Get Gateway Bot" endpoint, such that it can be used for Shard.Dial.
+// Api version 10 and json encoding are used unless the url already specifies otherwise:
+//
+//	DialURL("wss://gateway.discord.gg")                                    => "wss://gateway.discord.gg?encoding=json&v=10"
+//	DialURL("wss://gateway.discord.gg", WithTransportCompression(ZlibStream)) => "wss://gateway.discord.gg?compress=zlib-stream&encoding=json&v=10"
+func DialURL(gatewayURL string, options ...DialURLOption) (string, error) {
+	u, err := url.Parse(gatewayURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	if query.Get("v") == "" {
+		query.Set("v", "10")
+	}
+	if query.Get("encoding") == "" {
+		query.Set("encoding", "json")
+	}
+	for i := range options {
+		options[i](query)
+	}
+
+	u.RawQuery = query.Encode()
+	return ValidateDialURL(u.String())
+}
+
+// withEncoding sets the encoding query parameter of the url.
+func withEncoding(URLString, encoding string) string {
+	u, err := url.Parse(URLString)
+	if err != nil {
+		return URLString
+	}
+
+	query := u.Query()
+	query.Set("encoding", encoding)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// withQuery sets the query of the url, unless the url already specifies one.
+func withQuery(URLString, query string) string {
+	u, err := url.Parse(URLString)
+	if err != nil || u.RawQuery != "" {
+		return URLString
+	}
+
+	u.RawQuery = query
+	return u.String()
+}
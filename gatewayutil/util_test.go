@@ -58,7 +58,10 @@ func TestValidateDialURL(t *testing.T) {
 		{"incomplete url", "wss://gateway.discord.gg/?encoding=json", ErrIncompleteDialURL},
 		{"old api version", "wss://gateway.discord.gg/?v=1&encoding=json", ErrUnsupportedAPIVersion},
 		{"wrong encoding", "wss://gateway.discord.gg/?v=10&encoding=mysql", ErrUnsupportedAPICodec},
+		{"wrong compression", "wss://gateway.discord.gg/?v=10&encoding=json&compress=gzip", ErrUnsupportedCompression},
 		{"valid url", "wss://gateway.discord.gg/?v=10&encoding=json", nil},
+		{"valid compressed url", "wss://gateway.discord.gg/?v=10&encoding=json&compress=zlib-stream", nil},
+		{"valid etf url", "wss://gateway.discord.gg/?v=10&encoding=etf", nil},
 	}
 
 	for _, test := range tests {
@@ -70,3 +73,49 @@ func TestValidateDialURL(t *testing.T) {
 		})
 	}
 }
+
+func TestWithQuery(t *testing.T) {
+	query := "v=10&encoding=json"
+
+	if got := withQuery("wss://gateway-us-east1-b.discord.gg", query); got != "wss://gateway-us-east1-b.discord.gg?"+query {
+		t.Errorf("query was not added, got %s", got)
+	}
+	if got := withQuery("wss://gateway.discord.gg/?v=9&encoding=json", query); got != "wss://gateway.discord.gg/?v=9&encoding=json" {
+		t.Errorf("existing query was overwritten, got %s", got)
+	}
+}
+
+func TestWithEncoding(t *testing.T) {
+	if got := withEncoding("wss://gateway.discord.gg/?v=10", "etf"); got != "wss://gateway.discord.gg/?encoding=etf&v=10" {
+		t.Errorf("encoding was not added, got %s", got)
+	}
+	if got := withEncoding("wss://gateway.discord.gg/?encoding=json&v=10", "etf"); got != "wss://gateway.discord.gg/?encoding=etf&v=10" {
+		t.Errorf("encoding was not replaced, got %s", got)
+	}
+}
+
+func TestDialURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		gatewayURL string
+		options    []DialURLOption
+		expected   string
+	}{
+		{"defaults", "wss://gateway.discord.gg", nil, "wss://gateway.discord.gg?encoding=json&v=10"},
+		{"existing version", "wss://gateway.discord.gg/?v=9", nil, "wss://gateway.discord.gg/?encoding=json&v=9"},
+		{"compression", "wss://gateway.discord.gg", []DialURLOption{WithTransportCompression(ZlibStream)}, "wss://gateway.discord.gg?compress=zlib-stream&encoding=json&v=10"},
+		{"no compression", "wss://gateway.discord.gg/?compress=zlib-stream", []DialURLOption{WithTransportCompression(NoCompression)}, "wss://gateway.discord.gg/?encoding=json&v=10"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := DialURL(test.gatewayURL, test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.expected {
+				t.Errorf("got '%s', expected '%s'", got, test.expected)
+			}
+		})
+	}
+}
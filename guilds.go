@@ -0,0 +1,147 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/discordpkg/gateway/event"
+)
+
+// guildTracker keeps track of the guilds listed in the Ready payload, until every guild has been received through
+// GUILD_CREATE or has been reported as unavailable.
+type guildTracker struct {
+	mu          sync.Mutex
+	pending     map[event.Snowflake]struct{}
+	available   []event.Snowflake
+	unavailable []event.Snowflake
+	timer       *time.Timer
+
+	// tracking is true from Ready until every guild arrived or the timeout was reached
+	tracking bool
+}
+
+// trackGuilds starts tracking the guilds of the Ready payload. The GuildsReady event is dispatched right away when
+// the bot is not part of any guild.
+func (ctx *StateCtx) trackGuilds(guilds []event.UnavailableGuild) {
+	tracker := &ctx.guilds
+	tracker.mu.Lock()
+
+	tracker.pending = make(map[event.Snowflake]struct{}, len(guilds))
+	for _, guild := range guilds {
+		tracker.pending[guild.ID] = struct{}{}
+	}
+	tracker.available, tracker.unavailable = nil, nil
+	tracker.tracking = true
+
+	if len(tracker.pending) == 0 {
+		tracker.mu.Unlock()
+		ctx.guildsReady(false)
+		return
+	}
+
+	if timeout := ctx.client.guildsReadyTimeout; timeout > 0 {
+		tracker.timer = time.AfterFunc(timeout, func() {
+			ctx.guildsReady(true)
+		})
+	}
+	tracker.mu.Unlock()
+}
+
+// guildReceived marks the guild of a GUILD_CREATE or GUILD_DELETE event as no longer pending.
+func (ctx *StateCtx) guildReceived(payload *Payload) {
+	if payload.EventName != event.GuildCreate && payload.EventName != event.GuildDelete {
+		return
+	}
+
+	tracker := &ctx.guilds
+	tracker.mu.Lock()
+	if len(tracker.pending) == 0 {
+		tracker.mu.Unlock()
+		return
+	}
+
+	var guild event.UnavailableGuild
+	if err := ctx.client.codec.Unmarshal(payload.Data, &guild); err != nil {
+		tracker.mu.Unlock()
+		ctx.logger.Error("unable to decode the guild id of %s event: %s", payload.EventName, err)
+		return
+	}
+	if _, ok := tracker.pending[guild.ID]; !ok {
+		tracker.mu.Unlock()
+		return
+	}
+
+	delete(tracker.pending, guild.ID)
+	if guild.Unavailable {
+		tracker.unavailable = append(tracker.unavailable, guild.ID)
+	} else if payload.EventName == event.GuildCreate {
+		tracker.available = append(tracker.available, guild.ID)
+	}
+	done := len(tracker.pending) == 0
+	tracker.mu.Unlock()
+
+	if done {
+		ctx.guildsReady(false)
+	}
+}
+
+// guildsReady stops the tracking, and dispatches the GuildsReady event when enabled. Only the first call dispatches
+// the event, as the timeout may race with the last guild. On timeout the event is handled by the timer goroutine, so
+// handler panics are always recovered.
+func (ctx *StateCtx) guildsReady(timedOut bool) {
+	tracker := &ctx.guilds
+	tracker.mu.Lock()
+	if !tracker.tracking {
+		tracker.mu.Unlock()
+		return
+	}
+	tracker.tracking = false
+	if tracker.timer != nil {
+		tracker.timer.Stop()
+		tracker.timer = nil
+	}
+
+	payload := &event.GuildsReadyEvent{
+		Available:   tracker.available,
+		Unavailable: tracker.unavailable,
+	}
+	if timedOut {
+		for id := range tracker.pending {
+			payload.TimedOut = append(payload.TimedOut, id)
+		}
+		ctx.logger.Info("timed out waiting for %d guilds", len(tracker.pending))
+	}
+	tracker.pending = nil
+	tracker.mu.Unlock()
+
+	client := ctx.client
+	if !client.guildsReadyEnabled || client.eventHandler == nil || ctx.closed.Load() {
+		return
+	}
+
+	data, err := client.codec.Marshal(payload)
+	if err != nil {
+		ctx.logger.Error("unable to marshal %s payload: %s", event.GuildsReady, err)
+		return
+	}
+	ctx.handleEvent(context.Background(), &Event{
+		ShardID:    client.id,
+		Type:       event.GuildsReady,
+		ReceivedAt: time.Now(),
+		Data:       data,
+	}, client.recoverPanics || timedOut)
+}
+
+// PendingGuilds returns the guilds of the Ready payload that have not been received yet.
+func (c *Client) PendingGuilds() []event.Snowflake {
+	tracker := &c.ctx.guilds
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	pending := make([]event.Snowflake, 0, len(tracker.pending))
+	for id := range tracker.pending {
+		pending = append(pending, id)
+	}
+	return pending
+}
@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
+	"github.com/discordpkg/gateway/event"
+	"github.com/discordpkg/gateway/event/opcode"
+)
+
+func processDispatch(t *testing.T, client *Client, seq int64, evt event.Type, data interface{}) {
+	raw, err := client.codec.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err := client.codec.Marshal(&Payload{Op: opcode.Dispatch, Seq: seq, EventName: evt, Data: raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.ProcessNext(bytes.NewReader(frame), &bytes.Buffer{}); err != nil {
+		t.Fatalf("%s: %v", evt, err)
+	}
+}
+
+type handlerFailedMetrics struct {
+	nopMetrics
+	failed chan event.Type
+}
+
+func (m *handlerFailedMetrics) HandlerFailed(_ ShardID, evt event.Type) {
+	m.failed <- evt
+}
+
+func guilds(ids ...string) []interface{} {
+	list := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, map[string]interface{}{"id": id, "unavailable": true})
+	}
+	return list
+}
+
+func newGuildsReadyClient(t *testing.T, codec encoding.Codec, timeout time.Duration) (*Client, <-chan *event.GuildsReadyEvent) {
+	ready := make(chan *event.GuildsReadyEvent, 1)
+	client := NewClientMust(t, append(commonOptions,
+		WithCodec(codec),
+		WithGuildEvents(event.MessageCreate),
+		WithGuildsReady(timeout),
+		WithEventHandlerV2(func(_ context.Context, evt *Event) error {
+			if evt.Type != event.GuildsReady {
+				return fmt.Errorf("unexpected event %s", evt.Type)
+			}
+			var payload *event.GuildsReadyEvent
+			if err := codec.Unmarshal(evt.Data, &payload); err != nil {
+				return err
+			}
+			ready <- payload
+			return nil
+		}),
+	)...)
+	client.ctx.SetState(&ReadyState{ctx: client.ctx})
+	return client, ready
+}
+
+func sorted(ids []event.Snowflake) string {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return fmt.Sprint(ids)
+}
+
+func TestClient_GuildsReady(t *testing.T) {
+	for _, codec := range []encoding.Codec{encoding.JSONCodec{}, etf.Codec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			client, ready := newGuildsReadyClient(t, codec, time.Minute)
+
+			processDispatch(t, client, 1, event.Ready, map[string]interface{}{
+				"session_id":         "session",
+				"resume_gateway_url": "wss://resume.discord.gg",
+				"guilds":             guilds("1", "2", "3"),
+			})
+			processDispatch(t, client, 2, event.GuildCreate, map[string]interface{}{"id": "1", "name": "guild"})
+			processDispatch(t, client, 3, event.GuildDelete, map[string]interface{}{"id": "2", "unavailable": true})
+			if pending := client.PendingGuilds(); sorted(pending) != "[3]" {
+				t.Errorf("incorrect pending guilds. Got %v", pending)
+			}
+			if len(ready) > 0 {
+				t.Fatal("guilds ready was dispatched before every guild was received")
+			}
+
+			processDispatch(t, client, 4, event.GuildCreate, map[string]interface{}{"id": "3", "name": "guild"})
+			select {
+			case payload := <-ready:
+				if sorted(payload.Available) != "[1 3]" || sorted(payload.Unavailable) != "[2]" || len(payload.TimedOut) != 0 {
+					t.Errorf("incorrect guilds ready payload. Got %+v", payload)
+				}
+			default:
+				t.Fatal("expected guilds ready once every guild was received")
+			}
+
+			// a guild joined later on does not trigger it again
+			processDispatch(t, client, 5, event.GuildCreate, map[string]interface{}{"id": "4", "name": "guild"})
+			if len(ready) > 0 {
+				t.Error("guilds ready must only be dispatched once")
+			}
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		client, ready := newGuildsReadyClient(t, encoding.JSONCodec{}, 10*time.Millisecond)
+		processDispatch(t, client, 1, event.Ready, map[string]interface{}{"guilds": guilds("1", "2")})
+		processDispatch(t, client, 2, event.GuildCreate, map[string]interface{}{"id": "1"})
+
+		select {
+		case payload := <-ready:
+			if sorted(payload.Available) != "[1]" || sorted(payload.TimedOut) != "[2]" {
+				t.Errorf("incorrect guilds ready payload. Got %+v", payload)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected guilds ready once the timeout was reached")
+		}
+		if len(client.PendingGuilds()) != 0 {
+			t.Error("expected no pending guilds after the timeout")
+		}
+	})
+
+	t.Run("timeout panic", func(t *testing.T) {
+		metrics := &handlerFailedMetrics{failed: make(chan event.Type, 1)}
+		client := NewClientMust(t, append(commonOptions,
+			WithMetrics(metrics),
+			WithGuildsReady(10*time.Millisecond),
+			WithEventHandlerV2(func(_ context.Context, evt *Event) error {
+				if evt.Type == event.GuildsReady {
+					panic("handler failed")
+				}
+				return nil
+			}),
+		)...)
+		client.ctx.SetState(&ReadyState{ctx: client.ctx})
+		processDispatch(t, client, 1, event.Ready, map[string]interface{}{"guilds": guilds("1")})
+
+		// the timer goroutine must not close the client, as that races with the event loop
+		select {
+		case evt := <-metrics.failed:
+			if evt != event.GuildsReady {
+				t.Errorf("unexpected failed event %s", evt)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the panic to be recovered")
+		}
+	})
+
+	t.Run("no guilds", func(t *testing.T) {
+		client, ready := newGuildsReadyClient(t, encoding.JSONCodec{}, 0)
+		processDispatch(t, client, 1, event.Ready, map[string]interface{}{"guilds": guilds()})
+		if len(ready) != 1 {
+			t.Error("expected guilds ready right after ready")
+		}
+	})
+}
@@ -3,10 +3,7 @@ package gateway
 import (
 	"io"
 	"math/rand"
-	"strconv"
 	"time"
-
-	"github.com/discordpkg/gateway/event"
 )
 
 type HeartbeatHandler interface {
@@ -55,12 +52,12 @@ func (p *DefaultHeartbeatHandler) Run() {
 	for {
 		if !p.ctx.heartbeatACK.CompareAndSwap(true, false) {
 			p.ctx.logger.Info("did not receive heart beat ack since last heartbeat")
+			p.ctx.client.metrics.HeartbeatMissed(p.ctx.client.id)
 			break
 		}
 
 		seq := p.ctx.sequenceNumber.Load()
-		seqStr := strconv.FormatInt(seq, 10)
-		if err := p.ctx.Write(p.TextWriter, event.Heartbeat, []byte(seqStr)); err != nil {
+		if err := p.ctx.WriteHeartbeat(p.TextWriter, seq); err != nil {
 			p.ctx.logger.Info("unable to send heartbeat: %s", err.Error())
 			break
 		}
@@ -11,6 +11,15 @@ import (
 type EventCodeData struct {
 	Name string
 	Code string
+
+	// Command is the name of the send event for the operation, which uses a verb first naming. Such as
+	// "UpdatePresence" for the "Presence Update" operation.
+	Command string
+}
+
+type EventOpCodeData struct {
+	Event  string
+	OpCode string
 }
 
 type EventData struct {
@@ -22,6 +31,9 @@ func main() {
 	receiveEvents := parseEvents("internal/discord-api-docs/docs/topics/Gateway_Events.md", "receive events")
 	generate.Generate(receiveEvents, "internal/generate/events/events_gen.go.tmpl", "event/receive_gen.go")
 
+	types := parseTypes("internal/discord-api-docs/docs/topics/Gateway_Events.md", objectSources, receiveEvents)
+	generate.Generate(types, "internal/generate/events/types_gen.go.tmpl", "event/types_gen.go")
+
 	sendEvents := parseEvents("internal/discord-api-docs/docs/topics/Gateway_Events.md", "send events")
 	generate.Generate(sendEvents, "internal/generate/events/events_gen.go.tmpl", "event/send_gen.go")
 
@@ -32,24 +44,31 @@ func main() {
 	eventCodes := parseEventCodes("internal/discord-api-docs/docs/topics/Opcodes_and_Status_Codes.md", "gateway opcodes")
 	generate.Generate(eventCodes, "internal/generate/events/opcodes_gen.go.tmpl", "event/opcode/codes_gen.go")
 
-	// generate method for converting event to opcode
-	// remove opcodes which can't be mapped by name to an event
-	for i := len(eventCodes) - 1; i >= 0; i-- {
-		exists := false
-		for j := range events {
-			if eventCodes[i].Name == events[j].Name {
-				exists = true
-				break
+	// generate method for converting event to opcode. Only send events and receive events with their own operation
+	// code are mapped, as dispatch events such as "Presence Update" share the name of an operation
+	var opcodeEvents []*EventData
+	for _, evt := range receiveEvents {
+		if in(evt.Name, nonDispatchEvents) {
+			opcodeEvents = append(opcodeEvents, evt)
+		}
+	}
+	opcodeEvents = append(opcodeEvents, sendEvents...)
+
+	var eventOpCodes []*EventOpCodeData
+	for _, code := range eventCodes {
+		mapped := false
+		for _, evt := range opcodeEvents {
+			if evt.Name == code.Name || evt.Name == code.Command {
+				eventOpCodes = append(eventOpCodes, &EventOpCodeData{Event: evt.Name, OpCode: code.Name})
+				mapped = true
 			}
 		}
 
-		if !exists {
-			fmt.Println("WARN: removed opcode from event method: " + eventCodes[i].Name)
-			eventCodes[i] = eventCodes[len(eventCodes)-1]
-			eventCodes = eventCodes[:len(eventCodes)-1]
+		if !mapped {
+			fmt.Println("WARN: removed opcode from event method: " + code.Name)
 		}
 	}
-	generate.Generate(eventCodes, "internal/generate/events/event_codes_gen.go.tmpl", "event/codes_gen.go")
+	generate.Generate(eventOpCodes, "internal/generate/events/event_codes_gen.go.tmpl", "event/codes_gen.go")
 }
 
 func parseEventCodes(filePath string, tableName string) []*EventCodeData {
@@ -63,10 +82,12 @@ func parseEventCodes(filePath string, tableName string) []*EventCodeData {
 		for i := range table.Rows {
 			row := table.Rows[i]
 
-			name := strings.Join(strings.Split(row[1], " "), "")
+			words := strings.Split(row[1], " ")
+			name := strings.Join(words, "")
+			command := words[len(words)-1] + strings.Join(words[:len(words)-1], "")
 			code := row[0]
 
-			events = append(events, &EventCodeData{Name: name, Code: code})
+			events = append(events, &EventCodeData{Name: name, Code: code, Command: command})
 		}
 	}
 
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/discordpkg/gateway/internal/generate"
+)
+
+// objectSources are the markdown files, besides the gateway events, holding the object structures referenced by
+// the dispatch events
+var objectSources = []string{
+	"internal/discord-api-docs/docs/interactions/Application_Commands.md",
+	"internal/discord-api-docs/docs/interactions/Receiving_and_Responding.md",
+	"internal/discord-api-docs/docs/resources/Application.md",
+	"internal/discord-api-docs/docs/resources/Audit_Log.md",
+	"internal/discord-api-docs/docs/resources/Auto_Moderation.md",
+	"internal/discord-api-docs/docs/resources/Channel.md",
+	"internal/discord-api-docs/docs/resources/Emoji.md",
+	"internal/discord-api-docs/docs/resources/Guild.md",
+	"internal/discord-api-docs/docs/resources/Guild_Scheduled_Event.md",
+	"internal/discord-api-docs/docs/resources/Stage_Instance.md",
+	"internal/discord-api-docs/docs/resources/Sticker.md",
+	"internal/discord-api-docs/docs/resources/User.md",
+	"internal/discord-api-docs/docs/resources/Voice.md",
+	"internal/discord-api-docs/docs/topics/Permissions.md",
+}
+
+// eventObjects maps the dispatch events whose inner payload is an object to the name used by the docs. The docs only
+// describe these in prose, such as "The inner payload is a channel object".
+var eventObjects = map[string]string{
+	"ApplicationCommandPermissionsUpdate": "guild application command permissions",
+	"AutoModerationRuleCreate":            "auto moderation rule",
+	"AutoModerationRuleDelete":            "auto moderation rule",
+	"AutoModerationRuleUpdate":            "auto moderation rule",
+	"ChannelCreate":                       "channel",
+	"ChannelDelete":                       "channel",
+	"ChannelUpdate":                       "channel",
+	"GuildAuditLogEntryCreate":            "audit log entry",
+	"GuildCreate":                         "guild",
+	"GuildDelete":                         "unavailable guild",
+	"GuildMemberAdd":                      "guild member",
+	"GuildScheduledEventCreate":           "guild scheduled event",
+	"GuildScheduledEventDelete":           "guild scheduled event",
+	"GuildScheduledEventUpdate":           "guild scheduled event",
+	"GuildUpdate":                         "guild",
+	"IntegrationCreate":                   "integration",
+	"IntegrationUpdate":                   "integration",
+	"InteractionCreate":                   "interaction",
+	"MessageCreate":                       "message",
+	"MessageUpdate":                       "message",
+	"StageInstanceCreate":                 "stage instance",
+	"StageInstanceDelete":                 "stage instance",
+	"StageInstanceUpdate":                 "stage instance",
+	"ThreadCreate":                        "channel",
+	"ThreadDelete":                        "channel",
+	"ThreadMemberUpdate":                  "thread member",
+	"ThreadUpdate":                        "channel",
+	"UserUpdate":                          "user",
+	"VoiceStateUpdate":                    "voice state",
+}
+
+// handwrittenTypes are defined in event/types.go, as the docs do not describe them using a table
+var handwrittenTypes = []string{"Snowflake", "UnavailableGuild"}
+
+// nonDispatchEvents are receive events with their own operation code, they are handled by the client
+var nonDispatchEvents = []string{"Hello", "InvalidSession", "Reconnect"}
+
+type StructField struct {
+	Name      string
+	Type      string
+	JSON      string
+	OmitEmpty bool
+}
+
+type StructData struct {
+	Name   string
+	Event  string
+	Title  string
+	Fields []*StructField
+}
+
+type TypesData struct {
+	Objects []*StructData
+	Events  []*StructData
+}
+
+var (
+	markdownLink = regexp.MustCompile(`\[([^\]]+)\]\(([^)]*)\)`)
+	typeComment  = regexp.MustCompile(` \(.*\)$`)
+)
+
+type typeParser struct {
+	tables   map[string]*generate.MarkdownTable
+	events   map[string]bool
+	objects  map[string]*StructData
+	resolved []string
+}
+
+func parseTypes(gatewayEventsPath string, objectPaths []string, receiveEvents []*EventData) *TypesData {
+	p := &typeParser{
+		tables:  map[string]*generate.MarkdownTable{},
+		events:  map[string]bool{},
+		objects: map[string]*StructData{},
+	}
+
+	isFieldTable := func(name string) bool {
+		return strings.HasSuffix(strings.ToLower(name), "structure") ||
+			strings.HasSuffix(strings.ToLower(name), "fields") ||
+			strings.HasSuffix(strings.ToLower(name), "object") ||
+			strings.HasSuffix(strings.ToLower(name), "metadata") ||
+			strings.HasPrefix(strings.ToLower(name), "activity ") ||
+			strings.HasPrefix(strings.ToLower(name), "optional ")
+	}
+	for _, source := range append([]string{gatewayEventsPath}, objectPaths...) {
+		for _, table := range generate.ExtractMarkdownTables(source, isFieldTable) {
+			if len(table.Header) < 2 || !strings.EqualFold(table.Header[1], "type") {
+				continue
+			}
+			p.tables[table.Title] = table
+		}
+	}
+
+	data := &TypesData{}
+	for _, evt := range receiveEvents {
+		if in(evt.Name, nonDispatchEvents) {
+			continue
+		}
+		if _, ok := p.table(title(evt.Value), " Event Fields"); ok {
+			p.events[evt.Name] = true
+		}
+	}
+
+	for _, evt := range receiveEvents {
+		if in(evt.Name, nonDispatchEvents) {
+			continue
+		}
+
+		eventTitle := title(evt.Value)
+		s := &StructData{Name: evt.Name + "Event", Event: evt.Name, Title: eventTitle}
+		if table, ok := p.table(eventTitle, " Event Fields"); ok {
+			s.Fields = p.fields(table)
+		} else if object, ok := eventObjects[evt.Name]; ok {
+			// an unresolved object would render an empty struct, which silently decodes the payload into nothing
+			typ := p.object(object)
+			if typ == "" {
+				panic("missing the structure table of the " + object + " object, used by " + evt.Name)
+			}
+			s.Fields = append(s.Fields, &StructField{Type: typ})
+			if table, ok := p.table(eventTitle, " Extra Fields", " Event Extra Fields", " Event Additional Fields"); ok {
+				s.Fields = append(s.Fields, p.fields(table)...)
+			}
+		} else {
+			fmt.Println("WARN: event without payload fields: " + evt.Name)
+		}
+		data.Events = append(data.Events, s)
+	}
+
+	// objects are resolved while parsing the fields, and may reference other objects
+	for i := 0; i < len(p.resolved); i++ {
+		s := p.objects[p.resolved[i]]
+		s.Fields = p.fields(p.tables[s.Title])
+	}
+	for _, s := range p.objects {
+		data.Objects = append(data.Objects, s)
+	}
+
+	sort.Slice(data.Objects, func(i, j int) bool {
+		return data.Objects[i].Name < data.Objects[j].Name
+	})
+	sort.Slice(data.Events, func(i, j int) bool {
+		return data.Events[i].Name < data.Events[j].Name
+	})
+	return data
+}
+
+func (p *typeParser) table(name string, suffixes ...string) (*generate.MarkdownTable, bool) {
+	for _, suffix := range suffixes {
+		if table, ok := p.tables[name+suffix]; ok {
+			return table, true
+		}
+	}
+	return nil, false
+}
+
+// object returns the go type of the named object, which is generated when a structure table exists
+func (p *typeParser) object(name string) string {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "partial ")
+	goName := strings.ReplaceAll(strings.Title(name), " ", "")
+	if in(goName, handwrittenTypes) {
+		return goName
+	}
+	if p.events[goName] {
+		return goName + "Event"
+	}
+
+	table, ok := p.table(strings.Title(name), " Structure", " Object Structure", " Object", "")
+	if !ok {
+		return ""
+	}
+
+	goName = strings.TrimSuffix(table.Title, " Structure")
+	goName = strings.TrimSuffix(goName, " Object")
+	goName = strings.ReplaceAll(goName, " ", "")
+	if _, ok = p.objects[goName]; !ok {
+		p.objects[goName] = &StructData{Name: goName, Title: table.Title}
+		p.resolved = append(p.resolved, goName)
+	}
+	return goName
+}
+
+// anchorName extracts the object name from a link anchor, such as "Activity Timestamps" from
+// "#DOCS_TOPICS_GATEWAY_EVENTS/activity-object-activity-timestamps"
+func anchorName(anchor string) string {
+	slug := anchor[strings.LastIndex(anchor, "/")+1:]
+	if i := strings.LastIndex(slug, "-object-"); i >= 0 {
+		slug = slug[i+len("-object-"):]
+	}
+	slug = strings.TrimSuffix(slug, "-structure")
+	slug = strings.TrimSuffix(slug, "-object")
+	return strings.ReplaceAll(slug, "-", " ")
+}
+
+// enumSuffixes are the endings of link anchors that refer to a table of values, rather than an object
+var enumSuffixes = map[string]string{
+	"types":     "int",
+	"type":      "int",
+	"levels":    "int",
+	"level":     "int",
+	"status":    "int",
+	"behaviors": "int",
+	"events":    "int",
+	"flags":     "int",
+	"scopes":    "string",
+}
+
+// link converts a markdown link to the go type it refers to
+func (p *typeParser) link(text, anchor, remainder string) string {
+	remainder = strings.ToLower(remainder)
+	switch {
+	case strings.Contains(remainder, " id"):
+		return "Snowflake"
+	case strings.Contains(remainder, "string"):
+		return "string"
+	}
+
+	if typ := p.object(anchorName(anchor)); typ != "" {
+		return typ
+	}
+	if typ := p.object(strings.ReplaceAll(text, "_", " ")); typ != "" {
+		return typ
+	}
+	for suffix, typ := range enumSuffixes {
+		if strings.HasSuffix(strings.ToLower(anchor), suffix) {
+			return typ
+		}
+	}
+
+	fmt.Println("WARN: unknown object, using raw message: " + text)
+	return "encoding.RawMessage"
+}
+
+func (p *typeParser) fields(table *generate.MarkdownTable) []*StructField {
+	var fields []*StructField
+	for _, row := range table.Rows {
+		name := strings.NewReplacer("\\*", "", "*", "", "\\", "").Replace(row[0])
+		name = strings.TrimSpace(name)
+		optional := strings.HasSuffix(name, "?")
+		name = strings.TrimSuffix(name, "?")
+
+		fields = append(fields, &StructField{
+			Name:      fieldName(name),
+			Type:      p.goType(row[1], optional),
+			JSON:      name,
+			OmitEmpty: optional,
+		})
+	}
+	return fields
+}
+
+var primitiveTypes = map[string]string{
+	"snowflake":         "Snowflake",
+	"string":            "string",
+	"integer":           "int",
+	"int":               "int",
+	"boolean":           "bool",
+	"float":             "float64",
+	"double":            "float64",
+	"iso8601 timestamp": "time.Time",
+	"mixed":             "encoding.RawMessage",
+	"integer or string": "encoding.RawMessage",
+	"null":              "encoding.RawMessage",
+
+	"array of two integers": "[2]int",
+}
+
+// goType converts a markdown type such as "?array of [user](#DOCS_RESOURCES_USER/user-object) objects" to go
+func (p *typeParser) goType(mdType string, optional bool) string {
+	mdType = strings.TrimSpace(strings.NewReplacer("\\*", "", "*", "").Replace(mdType))
+	nullable := strings.HasPrefix(mdType, "?")
+	mdType = strings.TrimPrefix(mdType, "?")
+	mdType = typeComment.ReplaceAllString(mdType, "")
+
+	lower := strings.ToLower(mdType)
+	typ, primitive := primitiveTypes[lower]
+	if !primitive {
+		if strings.HasPrefix(lower, "array of ") {
+			return "[]" + strings.TrimPrefix(p.goType(mdType[len("array of "):], false), "*")
+		}
+
+		if link := markdownLink.FindStringSubmatchIndex(mdType); link != nil {
+			typ = p.link(mdType[link[2]:link[3]], mdType[link[4]:link[5]], mdType[link[1]:])
+		} else if typ, primitive = primitiveTypes[strings.TrimSuffix(lower, "s")]; !primitive {
+			fmt.Println("WARN: unknown type, using raw message: " + mdType)
+			typ = "encoding.RawMessage"
+		}
+	}
+
+	isStruct := typ == "time.Time" || p.objects[typ] != nil || p.events[strings.TrimSuffix(typ, "Event")]
+	if typ != "encoding.RawMessage" && (nullable || (optional && isStruct)) {
+		return "*" + typ
+	}
+	return typ
+}
+
+var initialisms = map[string]string{
+	"id":   "ID",
+	"ids":  "IDs",
+	"url":  "URL",
+	"tts":  "TTS",
+	"nsfw": "NSFW",
+	"mfa":  "MFA",
+	"rtc":  "RTC",
+}
+
+// fieldName converts snake case field names to go names, such as "guild_id" to "GuildID"
+func fieldName(name string) string {
+	words := strings.Split(name, "_")
+	for i, word := range words {
+		if initialism, ok := initialisms[word]; ok {
+			words[i] = initialism
+		} else {
+			words[i] = strings.Title(word)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// title converts an event value such as "MESSAGE_CREATE" to the title used by the docs, "Message Create"
+func title(value string) string {
+	return strings.Title(strings.ToLower(strings.ReplaceAll(value, "_", " ")))
+}
+
+func in(name string, names []string) bool {
+	for i := range names {
+		if names[i] == name {
+			return true
+		}
+	}
+	return false
+}
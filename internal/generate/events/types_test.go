@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/discordpkg/gateway/internal/generate"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestParseTypes(t *testing.T) {
+	events := parseEvents("testdata/Gateway_Events.md", "receive events")
+	types := parseTypes("testdata/Gateway_Events.md", []string{"testdata/Emoji.md", "testdata/Stage_Instance.md"}, events)
+
+	target := filepath.Join(t.TempDir(), "types_gen.go")
+	generate.Generate(types, "types_gen.go.tmpl", target)
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := "testdata/types_gen.golden"
+	if *update {
+		if err = os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wants, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(wants) {
+		t.Errorf("generated code differs from %s, run the test with -update when the change is expected. Got\n%s", golden, got)
+	}
+}
+
+func TestParseTypes_MissingObject(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected an event with an unresolved object to fail, instead of generating an empty struct")
+		}
+	}()
+
+	events := parseEvents("testdata/Gateway_Events.md", "receive events")
+	parseTypes("testdata/Gateway_Events.md", []string{"testdata/Emoji.md"}, events)
+}
@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/discordpkg/gateway/closecode"
+	"github.com/discordpkg/gateway/event"
+)
+
+// Metrics receives measurements of a client, see WithMetrics. Implementations must be safe for concurrent use, as
+// the heartbeat process reports from its own goroutine, and should not block.
+type Metrics interface {
+	// EventReceived is called for every dispatch event, before the events are filtered by the allowlist.
+	EventReceived(shardID ShardID, evt event.Type)
+
+	// BytesRead is called with the size of every payload read, after any transport decompression.
+	BytesRead(shardID ShardID, n int)
+
+	// HeartbeatLatency is called with the duration between sending a heartbeat and receiving the acknowledgement.
+	HeartbeatLatency(shardID ShardID, latency time.Duration)
+
+	// HeartbeatMissed is called when no acknowledgement was received since the last heartbeat, which causes the
+	// connection to be closed.
+	HeartbeatMissed(shardID ShardID)
+
+	// HandlerFailed is called when a HandlerV2 returns an error.
+	HandlerFailed(shardID ShardID, evt event.Type)
+
+	// StateChanged is called on every state transition, with the name of the new state.
+	StateChanged(shardID ShardID, state string)
+
+	// CloseCodeReceived is called for every close code sent by Discord.
+	CloseCodeReceived(shardID ShardID, code closecode.Type)
+
+	// CommandRateLimited is called when a command is rejected by the command or identify rate limiter.
+	CommandRateLimited(shardID ShardID, evt event.Type)
+
+	// RateLimitWait is called when a heartbeat has to wait for the command rate limiter.
+	RateLimitWait(shardID ShardID, evt event.Type, wait time.Duration)
+
+	// Reconnect is called when a shard reconnects after losing the connection.
+	Reconnect(shardID ShardID)
+
+	// SequenceDuplicate is called for every dispatch event dropped, as its sequence number was already processed.
+	SequenceDuplicate(shardID ShardID)
+
+	// SequenceGap is called when a dispatch event skipped one or more sequence numbers, see WithSequencePolicy.
+	SequenceGap(shardID ShardID, expected, got int64)
+}
+
+type nopMetrics struct{}
+
+func (n *nopMetrics) EventReceived(_ ShardID, _ event.Type)                  {}
+func (n *nopMetrics) BytesRead(_ ShardID, _ int)                             {}
+func (n *nopMetrics) HeartbeatLatency(_ ShardID, _ time.Duration)            {}
+func (n *nopMetrics) HeartbeatMissed(_ ShardID)                              {}
+func (n *nopMetrics) HandlerFailed(_ ShardID, _ event.Type)                  {}
+func (n *nopMetrics) StateChanged(_ ShardID, _ string)                       {}
+func (n *nopMetrics) CloseCodeReceived(_ ShardID, _ closecode.Type)          {}
+func (n *nopMetrics) CommandRateLimited(_ ShardID, _ event.Type)             {}
+func (n *nopMetrics) RateLimitWait(_ ShardID, _ event.Type, _ time.Duration) {}
+func (n *nopMetrics) Reconnect(_ ShardID)                                    {}
+func (n *nopMetrics) SequenceDuplicate(_ ShardID)                            {}
+func (n *nopMetrics) SequenceGap(_ ShardID, _, _ int64)                      {}
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/discordpkg/gateway/closecode"
+	"github.com/discordpkg/gateway/event"
+)
+
+type recordedMetrics struct {
+	nopMetrics
+	mu          sync.Mutex
+	events      []event.Type
+	bytes       int
+	latencies   []time.Duration
+	states      []string
+	closeCodes  []closecode.Type
+	rateLimited []event.Type
+}
+
+func (m *recordedMetrics) EventReceived(_ ShardID, evt event.Type) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, evt)
+}
+
+func (m *recordedMetrics) BytesRead(_ ShardID, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bytes += n
+}
+
+func (m *recordedMetrics) HeartbeatLatency(_ ShardID, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latencies = append(m.latencies, latency)
+}
+
+func (m *recordedMetrics) StateChanged(_ ShardID, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states = append(m.states, state)
+}
+
+func (m *recordedMetrics) CloseCodeReceived(_ ShardID, code closecode.Type) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeCodes = append(m.closeCodes, code)
+}
+
+func (m *recordedMetrics) CommandRateLimited(_ ShardID, evt event.Type) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rateLimited = append(m.rateLimited, evt)
+}
+
+func TestWithMetrics(t *testing.T) {
+	metrics := &recordedMetrics{}
+	client := NewClientMust(t, append(commonOptions, WithMetrics(metrics), WithGuildEvents(event.MessageCreate))...)
+
+	payloads := []string{
+		`{"op":10,"d":{"heartbeat_interval":45000}}`,
+		`{"op":0,"s":1,"t":"READY","d":{"session_id":"id","resume_gateway_url":"wss://localhost"}}`,
+		`{"op":0,"s":2,"t":"MESSAGE_CREATE","d":{}}`,
+	}
+
+	size := 0
+	buffer := &bytes.Buffer{}
+	for _, payload := range payloads {
+		size += len(payload)
+		if _, err := client.ProcessNext(strings.NewReader(payload), buffer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := client.ctx.WriteHeartbeat(buffer, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ProcessNext(strings.NewReader(`{"op":11}`), buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.UpdateVoiceState(buffer, &UpdateVoiceState{GuildID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	client.commandRateLimiter = &exhaustedRateLimiter{}
+	if err := client.UpdateVoiceState(buffer, &UpdateVoiceState{GuildID: "1"}); !errors.Is(err, ErrCommandRateLimited) {
+		t.Fatalf("expected rate limited error, got %v", err)
+	}
+
+	closeFrame := fmt.Sprintf(`{"closecode":%d,"d":"session timed out"}`, closecode.SessionTimedOut)
+	if _, err := client.ProcessNext(strings.NewReader(closeFrame), buffer); err == nil {
+		t.Fatal("expected close code error")
+	}
+
+	if fmt.Sprint(metrics.events) != fmt.Sprint([]event.Type{event.Ready, event.MessageCreate}) {
+		t.Errorf("incorrect events received. Got %v", metrics.events)
+	}
+	if wants := size + len(`{"op":11}`) + len(closeFrame); metrics.bytes != wants {
+		t.Errorf("incorrect bytes read. Got %d, wants %d", metrics.bytes, wants)
+	}
+	if len(metrics.latencies) != 1 {
+		t.Errorf("expected one heartbeat latency, got %v", metrics.latencies)
+	}
+	if wants := "[hello ready connected closed-resumable]"; fmt.Sprint(metrics.states) != wants {
+		t.Errorf("incorrect state transitions. Got %v, wants %s", metrics.states, wants)
+	}
+	if len(metrics.closeCodes) != 1 || metrics.closeCodes[0] != closecode.SessionTimedOut {
+		t.Errorf("incorrect close codes. Got %v", metrics.closeCodes)
+	}
+	if len(metrics.rateLimited) != 1 || metrics.rateLimited[0] != event.UpdateVoiceState {
+		t.Errorf("incorrect rate limited commands. Got %v", metrics.rateLimited)
+	}
+}
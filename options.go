@@ -1,7 +1,12 @@
 package gateway
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
+
+	"github.com/discordpkg/gateway/encoding"
 
 	"github.com/discordpkg/gateway/event"
 	"github.com/discordpkg/gateway/intent"
@@ -95,12 +100,30 @@ func WithExistingSession(deadClient *Client) Option {
 		client.ctx.SessionID = st.ctx.SessionID
 		client.ctx.ResumeGatewayURL = st.ctx.ResumeGatewayURL
 		client.ctx.sequenceNumber.Store(st.ctx.sequenceNumber.Load())
+		client.ctx.ready.Store(st.ctx.ready.Load())
 
 		client.ctx.SetState(&ResumeState{&ConnectedState{ctx: client.ctx}})
 		return nil
 	}
 }
 
+// WithSessionSnapshot resumes the session of a snapshot, see Client.Session, such that a restarted process does not
+// need to identify again. The snapshot is ignored when it belongs to a different shard, or when WithExistingSession
+// already resumes a session.
+func WithSessionSnapshot(session *Session) Option {
+	if session == nil {
+		return noopOption
+	}
+
+	return func(client *Client) error {
+		if err := session.Validate(); err != nil {
+			return err
+		}
+		client.snapshot = session
+		return nil
+	}
+}
+
 func WithIdentifyConnectionProperties(properties *IdentifyConnectionProperties) Option {
 	return func(client *Client) error {
 		client.connectionProperties = properties
@@ -143,12 +166,123 @@ func WithHeartbeatHandler(handler HeartbeatHandler) Option {
 // Warning: this function call is blocking. You should not run heavy logic in the handler, preferably just forward it
 // to a processing component. An example usage would be to send it to a buffered worker channel.
 func WithEventHandler(handler Handler) Option {
+	var handlerV2 HandlerV2
+	if handler != nil {
+		handlerV2 = func(_ context.Context, evt *Event) error {
+			handler(evt.ShardID, evt.Type, evt.Data)
+			return nil
+		}
+	}
+
+	return func(client *Client) error {
+		client.eventHandler = handlerV2
+		return nil
+	}
+}
+
+// WithEventHandlerV2 is the same as WithEventHandler, except the handler receives a context and the complete event,
+// and may return an error. Errors are logged and reported to the metrics. Only one event handler can be used, the
+// last one specified wins.
+func WithEventHandlerV2(handler HandlerV2) Option {
 	return func(client *Client) error {
 		client.eventHandler = handler
 		return nil
 	}
 }
 
+// WithPanicRecovery recovers panics in the event handler, such that a faulty handler does not take down the shard.
+// Recovered panics are logged as errors along with the event type and sequence number, reported to the metrics as a
+// handler failure, and passed on to the optional handler for custom reporting. The client stays connected.
+//
+// Without panic recovery, the client is closed before the panic continues, such that the heartbeat process stops.
+func WithPanicRecovery(handler PanicHandler) Option {
+	return func(client *Client) error {
+		client.recoverPanics = true
+		client.panicHandler = handler
+		return nil
+	}
+}
+
+// WithGuildsReady dispatches the synthetic event.GuildsReady event to the event handler, once every guild of the
+// Ready payload has been received through GUILD_CREATE or has been reported as unavailable. Guilds that are not
+// received within the timeout are listed as timed out, a timeout of 0 waits indefinitely. Note that the event may be
+// dispatched by a timer, but never concurrently with other events.
+func WithGuildsReady(timeout time.Duration) Option {
+	return func(client *Client) error {
+		if timeout < 0 {
+			return errors.New("guilds ready timeout can not be negative")
+		}
+		client.guildsReadyEnabled = true
+		client.guildsReadyTimeout = timeout
+		return nil
+	}
+}
+
+// WithSequencePolicy decides how dispatch events that skip one or more sequence numbers are handled. Defaults to
+// SequenceStrict.
+func WithSequencePolicy(policy SequencePolicy) Option {
+	return func(client *Client) error {
+		switch policy {
+		case SequenceStrict, SequenceTolerate, SequenceReorder:
+		default:
+			return fmt.Errorf("unknown sequence policy %d", policy)
+		}
+		client.sequencePolicy = policy
+		return nil
+	}
+}
+
+// WithSequenceReorderWindow sets the maximum number of events buffered while waiting for a missing sequence number,
+// and enables SequenceReorder. Defaults to 10.
+func WithSequenceReorderWindow(size int) Option {
+	return func(client *Client) error {
+		if size < 1 {
+			return errors.New("sequence reorder window must be 1 or higher")
+		}
+		client.sequencePolicy = SequenceReorder
+		client.reorderWindow = size
+		return nil
+	}
+}
+
+// WithTracer starts a span for every event passed to the event handler, with attributes such as the shard id, event
+// type, sequence number and payload size. Use WithEventHandlerV2 to continue the trace in the handler. See
+// gatewayutil/otel for OpenTelemetry.
+func WithTracer(tracer Tracer) Option {
+	return func(client *Client) error {
+		if tracer == nil {
+			return errors.New("tracer can not be nil")
+		}
+		client.tracer = tracer
+		return nil
+	}
+}
+
+// WithCodec sets the codec used to encode and decode payloads, such that clients in the same process can use different
+// encodings. The codec must match the "encoding" query parameter of the dial url, and its frame type decides whether
+// payloads are written as text or binary websocket frames. Defaults to encoding.JSONCodec.
+func WithCodec(codec encoding.Codec) Option {
+	return func(client *Client) error {
+		if codec == nil {
+			return errors.New("codec can not be nil")
+		}
+		client.codec = codec
+		return nil
+	}
+}
+
+// WithMetrics reports measurements of the client, such as received events and heartbeat latency, to the given
+// Metrics implementation. See gatewayutil/prometheus for Prometheus collectors.
+func WithMetrics(metrics Metrics) Option {
+	return func(client *Client) error {
+		if metrics == nil {
+			return errors.New("metrics can not be nil")
+		}
+		client.metrics = metrics
+		return nil
+	}
+}
+
 func WithLogger(logger Logger) Option {
 	return func(client *Client) error {
 		client.logger = logger
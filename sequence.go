@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"fmt"
+	"io"
+)
+
+// SequencePolicy decides how the client handles dispatch events that skip one or more sequence numbers. Events
+// with a sequence number that was already processed are always dropped as duplicates.
+type SequencePolicy int
+
+const (
+	// SequenceStrict closes the client on a gap, such that the session is resumed and Discord replays the missed
+	// events. This is the default.
+	SequenceStrict SequencePolicy = iota
+	// SequenceTolerate logs a warning on a gap, and processes the event anyway. The missed events are lost.
+	SequenceTolerate
+	// SequenceReorder buffers events that arrive ahead of the expected sequence number, and processes them once the
+	// gap is filled. The gap is reported when the first event is buffered. When the buffer exceeds the reorder
+	// window, the buffered events are dropped and the client is closed as with SequenceStrict.
+	SequenceReorder
+)
+
+const defaultReorderWindow = 10
+
+// SequenceError is returned when a dispatch event skipped one or more sequence numbers. It matches both
+// ErrSequenceNumberSkipped and ErrOutOfSync using errors.Is.
+type SequenceError struct {
+	Expected int64
+	Got      int64
+}
+
+func (e *SequenceError) Error() string {
+	return fmt.Sprintf("%s: expected sequence number %d, got %d", ErrSequenceNumberSkipped, e.Expected, e.Got)
+}
+
+func (e *SequenceError) Is(target error) bool {
+	return target == ErrSequenceNumberSkipped || target == ErrOutOfSync
+}
+
+func (c *Client) process(payload *Payload, pipe io.Writer) (err error) {
+	// only dispatch events have sequence numbers, other messages such as hello and heartbeat ack are always processed.
+	// A stored sequence number of 0 means no dispatch event has been processed yet.
+	if payload.Seq == 0 {
+		return c.ctx.Process(payload, pipe)
+	}
+
+	seq := &c.ctx.sequenceNumber
+	if seq.CompareAndSwap(0, payload.Seq) || seq.CompareAndSwap(payload.Seq-1, payload.Seq) {
+		if err = c.ctx.Process(payload, pipe); err != nil {
+			return err
+		}
+		return c.processReordered(pipe)
+	}
+
+	current := seq.Load()
+	if payload.Seq <= current {
+		c.logger.Debug("dropping %s event with sequence number %d, as it was already processed", payload.EventName, payload.Seq)
+		c.metrics.SequenceDuplicate(c.id)
+		return nil
+	}
+
+	expected := current + 1
+	if c.sequencePolicy == SequenceReorder {
+		if len(c.reordered) == 0 {
+			c.logger.Debug("sequence number skipped, expected %d but got %d: buffering events until the gap is filled", expected, payload.Seq)
+			c.metrics.SequenceGap(c.id, expected, payload.Seq)
+		}
+		if c.reordered == nil {
+			c.reordered = map[int64]*Payload{}
+		}
+		c.reordered[payload.Seq] = payload
+		if len(c.reordered) <= c.reorderWindow {
+			return nil
+		}
+
+		// the gap was reported when the first event was buffered, and the session is resumed to replay the events
+		c.logger.Warn("sequence number %d is still missing after buffering %d events", expected, len(c.reordered))
+		c.reordered = nil
+		c.ctx.closeResumable()
+		return &SequenceError{Expected: expected, Got: payload.Seq}
+	}
+
+	c.metrics.SequenceGap(c.id, expected, payload.Seq)
+	if c.sequencePolicy == SequenceTolerate {
+		c.logger.Warn("sequence number skipped, expected %d but got %d: events were lost", expected, payload.Seq)
+		seq.Store(payload.Seq)
+		return c.ctx.Process(payload, pipe)
+	}
+
+	c.ctx.closeResumable()
+	return &SequenceError{Expected: expected, Got: payload.Seq}
+}
+
+// processReordered processes the buffered events that directly follow the stored sequence number
+func (c *Client) processReordered(pipe io.Writer) error {
+	seq := &c.ctx.sequenceNumber
+	for len(c.reordered) > 0 {
+		next := seq.Load() + 1
+		payload, ok := c.reordered[next]
+		if !ok {
+			return nil
+		}
+
+		delete(c.reordered, next)
+		seq.Store(next)
+		if err := c.ctx.Process(payload, pipe); err != nil {
+			return err
+		}
+	}
+	return nil
+}
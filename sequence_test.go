@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/event"
+)
+
+type sequenceMetrics struct {
+	nopMetrics
+	duplicates int
+	gaps       []string
+}
+
+func (m *sequenceMetrics) SequenceDuplicate(_ ShardID) {
+	m.duplicates++
+}
+
+func (m *sequenceMetrics) SequenceGap(_ ShardID, expected, got int64) {
+	m.gaps = append(m.gaps, fmt.Sprintf("%d-%d", expected, got))
+}
+
+func TestClient_SequencePolicy(t *testing.T) {
+	newClient := func(options ...Option) (*Client, *[]int64, *sequenceMetrics) {
+		var handled []int64
+		metrics := &sequenceMetrics{}
+		client := NewClientMust(t, append(append(commonOptions,
+			WithMetrics(metrics),
+			WithGuildEvents(event.MessageCreate),
+			WithEventHandler(func(_ ShardID, _ event.Type, data encoding.RawMessage) {
+				var seq int64
+				_ = encoding.Unmarshal(data, &seq)
+				handled = append(handled, seq)
+			}),
+		), options...)...)
+
+		client.ctx.SessionID, client.ctx.ResumeGatewayURL = "session", "wss://resume.discord.gg"
+		client.ctx.SetState(&ConnectedState{client.ctx})
+		return client, &handled, metrics
+	}
+	process := func(client *Client, seqs ...int64) error {
+		for _, seq := range seqs {
+			data := fmt.Sprintf(`{"op":0,"s":%d,"t":"MESSAGE_CREATE","d":%d}`, seq, seq)
+			if _, err := client.ProcessNext(strings.NewReader(data), &bytes.Buffer{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		client, handled, metrics := newClient()
+
+		err := process(client, 1, 2, 2, 5)
+		var sequenceErr *SequenceError
+		if !errors.As(err, &sequenceErr) || sequenceErr.Expected != 3 || sequenceErr.Got != 5 {
+			t.Fatalf("expected a sequence error, got %v", err)
+		}
+		if !errors.Is(err, ErrSequenceNumberSkipped) || !errors.Is(err, ErrOutOfSync) {
+			t.Errorf("expected the sequence error to match the sentinel errors")
+		}
+		if client.ResumeURL() == "" {
+			t.Error("expected the client to be resumable")
+		}
+		if got := fmt.Sprint(*handled); got != "[1 2]" {
+			t.Errorf("incorrect events handled. Got %s", got)
+		}
+		if metrics.duplicates != 1 || fmt.Sprint(metrics.gaps) != "[3-5]" {
+			t.Errorf("incorrect metrics. Got %d duplicates and gaps %v", metrics.duplicates, metrics.gaps)
+		}
+	})
+
+	t.Run("tolerate", func(t *testing.T) {
+		client, handled, metrics := newClient(WithSequencePolicy(SequenceTolerate))
+
+		if err := process(client, 1, 4, 3, 5); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(*handled); got != "[1 4 5]" {
+			t.Errorf("incorrect events handled. Got %s", got)
+		}
+		if metrics.duplicates != 1 || fmt.Sprint(metrics.gaps) != "[2-4]" {
+			t.Errorf("incorrect metrics. Got %d duplicates and gaps %v", metrics.duplicates, metrics.gaps)
+		}
+	})
+
+	t.Run("reorder", func(t *testing.T) {
+		client, handled, metrics := newClient(WithSequenceReorderWindow(2))
+
+		if err := process(client, 1, 3, 4, 2, 5); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(*handled); got != "[1 2 3 4 5]" {
+			t.Errorf("expected the events to be reordered. Got %s", got)
+		}
+
+		err := process(client, 7, 8, 9)
+		var sequenceErr *SequenceError
+		if !errors.As(err, &sequenceErr) || sequenceErr.Expected != 6 || sequenceErr.Got != 9 {
+			t.Fatalf("expected a sequence error once the window is exceeded, got %v", err)
+		}
+		if got := fmt.Sprint(metrics.gaps); got != "[2-3 6-7]" {
+			t.Errorf("expected the gaps to be reported once buffered, got %s", got)
+		}
+		if len(client.reordered) != 0 {
+			t.Errorf("expected the buffer to be cleared on overflow, got %d events", len(client.reordered))
+		}
+		if client.ResumeURL() == "" {
+			t.Error("expected the client to be resumable")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := NewClient(append(commonOptions, WithSequencePolicy(SequencePolicy(10)))...); err == nil {
+			t.Error("expected an unknown policy to be rejected")
+		}
+		if _, err := NewClient(append(commonOptions, WithSequenceReorderWindow(0))...); err == nil {
+			t.Error("expected an empty reorder window to be rejected")
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package gateway
+
+import "errors"
+
+var ErrSessionNotResumable = errors.New("client does not hold a resumable session")
+var ErrIncompleteSession = errors.New("session snapshot is missing the session id, resume gateway url or sequence number")
+
+// Session is a serializable snapshot of a resumable session, such that a new process can resume the session instead
+// of identifying again. See Client.Session and WithSessionSnapshot.
+type Session struct {
+	ID               string  `json:"session_id"`
+	ResumeGatewayURL string  `json:"resume_gateway_url"`
+	Sequence         int64   `json:"seq"`
+	ShardID          ShardID `json:"shard_id"`
+	ShardCount       int     `json:"shard_count"`
+}
+
+// Validate returns ErrIncompleteSession when the snapshot can not be resumed.
+func (s *Session) Validate() error {
+	if s.ID == "" || s.ResumeGatewayURL == "" || s.Sequence <= 0 {
+		return ErrIncompleteSession
+	}
+	return nil
+}
+
+// Session returns a snapshot of the session, which requires the client to be closed while the session can still be
+// resumed. Otherwise ErrSessionNotResumable is returned.
+func (c *Client) Session() (*Session, error) {
+	if _, ok := c.ctx.state.(*ResumableClosedState); !ok {
+		return nil, ErrSessionNotResumable
+	}
+
+	return &Session{
+		ID:               c.ctx.SessionID,
+		ResumeGatewayURL: c.ctx.ResumeGatewayURL,
+		Sequence:         c.ctx.sequenceNumber.Load(),
+		ShardID:          c.id,
+		ShardCount:       c.totalNumberOfShards,
+	}, nil
+}
+
+// resumeSnapshot resumes the session of the snapshot, unless it belongs to a different shard. Sessions are bound to
+// the shard information given on identify, so resharding requires new sessions.
+func (c *Client) resumeSnapshot(session *Session) {
+	if session.ShardID != c.id || session.ShardCount != c.totalNumberOfShards {
+		c.logger.Info("ignoring session snapshot of shard %d out of %d shards, as the client is shard %d out of %d shards",
+			session.ShardID, session.ShardCount, c.id, c.totalNumberOfShards)
+		return
+	}
+
+	c.ctx.SessionID = session.ID
+	c.ctx.ResumeGatewayURL = session.ResumeGatewayURL
+	c.ctx.sequenceNumber.Store(session.Sequence)
+	c.ctx.SetState(&ResumeState{&ConnectedState{ctx: c.ctx}})
+}
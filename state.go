@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"io"
 	"net"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -18,6 +20,10 @@ import (
 
 var ErrRateLimited = errors.New("unable to send message to Discord due to hitting rate limited")
 var ErrIdentifyRateLimited = fmt.Errorf("can't send identify command: %w", ErrRateLimited)
+var ErrCommandRateLimited = fmt.Errorf("can't send command: %w", ErrRateLimited)
+
+// latencySmoothing decides the weight of new latency samples in the moving average, 1/latencySmoothing
+const latencySmoothing = 5
 
 type State interface {
 	fmt.Stringer
@@ -34,14 +40,33 @@ type StateCtx struct {
 	heartbeatACK   atomic.Bool
 	sequenceNumber atomic.Int64
 
+	// heartbeatSent is the unix nano time of the last heartbeat without an acknowledgement, 0 if none
+	heartbeatSent  atomic.Int64
+	latency        atomic.Int64
+	averageLatency atomic.Int64
+
 	closed atomic.Bool
 	client *Client
 
+	// connected mirrors whether the state is ConnectedState, such that commands can be sent from other goroutines
+	connected atomic.Bool
+
 	SessionID        string
 	ResumeGatewayURL string
 
+	// ready is the Ready payload of the session, see Client.Ready
+	ready atomic.Pointer[Ready]
+
 	state  State
 	logger Logger
+
+	// dispatchCtx and receivedAt describe the payload being processed, see Client.ProcessNextContext
+	dispatchCtx context.Context
+	receivedAt  time.Time
+
+	// handlerMu ensures the event handler is never called concurrently, as synthetic events are dispatched by timers
+	handlerMu sync.Mutex
+	guilds    guildTracker
 }
 
 func (ctx *StateCtx) String() string {
@@ -50,6 +75,7 @@ func (ctx *StateCtx) String() string {
 
 func (ctx *StateCtx) SetState(state State) {
 	ctx.logger.Debug("state update: %s", state)
+	ctx.client.metrics.StateChanged(ctx.client.id, state.String())
 
 	switch state.(type) {
 	case *ClosedState:
@@ -58,6 +84,8 @@ func (ctx *StateCtx) SetState(state State) {
 		ctx.logger.Panic("StateCtx can not be an internal state")
 	}
 
+	_, connected := state.(*ConnectedState)
+	ctx.connected.Store(connected)
 	ctx.state = state
 }
 
@@ -67,15 +95,21 @@ func (ctx *StateCtx) CloseCodeHandler(payload *Payload) error {
 	}
 
 	ctx.logger.Debug("handling close code")
+	ctx.client.metrics.CloseCodeReceived(ctx.client.id, payload.CloseCode)
 	if closecode.CanReconnectAfter(payload.CloseCode) {
 		ctx.SetState(&ResumableClosedState{ctx})
 	} else {
 		ctx.SetState(&ClosedState{})
 	}
 
+	var reason string
+	if err := ctx.client.codec.Unmarshal(payload.Data, &reason); err != nil {
+		reason = strings.Trim(string(payload.Data), "\"")
+	}
+
 	return &DiscordError{
 		CloseCode: payload.CloseCode,
-		Reason:    strings.Trim(string(payload.Data), "\""),
+		Reason:    reason,
 	}
 }
 
@@ -83,7 +117,7 @@ func (ctx *StateCtx) SessionIssueHandler(payload *Payload) error {
 	switch payload.Op {
 	case opcode.InvalidSession:
 		var d bool
-		if err := encoding.Unmarshal(payload.Data, &d); err != nil || !d {
+		if err := ctx.client.codec.Unmarshal(payload.Data, &d); err != nil || !d {
 			ctx.SetState(&ClosedState{})
 		} else {
 			ctx.SetState(&ResumableClosedState{ctx})
@@ -111,6 +145,15 @@ func (ctx *StateCtx) Process(payload *Payload, pipe io.Writer) error {
 	return ctx.state.Process(payload, pipe)
 }
 
+// closeResumable marks the client as closed, while allowing the session to be resumed when possible
+func (ctx *StateCtx) closeResumable() {
+	if ctx.SessionID != "" && ctx.ResumeGatewayURL != "" && ctx.sequenceNumber.Load() > 0 {
+		ctx.SetState(&ResumableClosedState{ctx})
+	} else {
+		ctx.SetState(&ClosedState{})
+	}
+}
+
 func (ctx *StateCtx) Close(closeWriter io.Writer) error {
 	if ctx.closed.Load() {
 		return net.ErrClosed
@@ -139,12 +182,19 @@ func (ctx *StateCtx) Write(pipe io.Writer, evt event.Type, payload encoding.RawM
 		return errors.New("can not send event type to Discord, it's receive only")
 	case opcode.Heartbeat:
 		if ok, timeout := ctx.client.commandRateLimiter.Try(ctx.client.id); !ok {
+			ctx.client.metrics.RateLimitWait(ctx.client.id, evt, timeout)
 			<-time.After(timeout)
 		}
 	case opcode.Identify:
 		if available, _ := ctx.client.identifyRateLimiter.Try(ctx.client.id); !available {
+			ctx.client.metrics.CommandRateLimited(ctx.client.id, evt)
 			return ErrIdentifyRateLimited
 		}
+	case opcode.RequestGuildMembers, opcode.PresenceUpdate, opcode.VoiceStateUpdate:
+		if available, _ := ctx.client.commandRateLimiter.Try(ctx.client.id); !available {
+			ctx.client.metrics.CommandRateLimited(ctx.client.id, evt)
+			return ErrCommandRateLimited
+		}
 	}
 
 	packet := Payload{
@@ -152,7 +202,7 @@ func (ctx *StateCtx) Write(pipe io.Writer, evt event.Type, payload encoding.RawM
 		Data: payload,
 	}
 
-	data, err := encoding.Marshal(&packet)
+	data, err := ctx.client.codec.Marshal(&packet)
 	if err != nil {
 		return fmt.Errorf("unable to marshal packet; %w", err)
 	}
@@ -161,6 +211,47 @@ func (ctx *StateCtx) Write(pipe io.Writer, evt event.Type, payload encoding.RawM
 	return err
 }
 
+// WriteHeartbeat sends a heartbeat with the given sequence number, encoded by the codec of the client.
+func (ctx *StateCtx) WriteHeartbeat(pipe io.Writer, seq int64) error {
+	data, err := ctx.client.codec.Marshal(seq)
+	if err != nil {
+		return fmt.Errorf("unable to marshal heartbeat; %w", err)
+	}
+
+	if err = ctx.Write(pipe, event.Heartbeat, data); err != nil {
+		return err
+	}
+
+	// only the first unacknowledged heartbeat is timed, as acknowledgements do not reference a heartbeat
+	ctx.heartbeatSent.CompareAndSwap(0, time.Now().UnixNano())
+	return nil
+}
+
+// heartbeatAcknowledged records the latency of the last heartbeat
+func (ctx *StateCtx) heartbeatAcknowledged() {
+	ctx.heartbeatACK.CompareAndSwap(false, true)
+
+	sent := ctx.heartbeatSent.Swap(0)
+	if sent == 0 {
+		return
+	}
+
+	latency := time.Since(time.Unix(0, sent))
+	ctx.latency.Store(int64(latency))
+
+	// exponential moving average, where the first sample is used as is
+	average := ctx.averageLatency.Load()
+	if average == 0 {
+		average = int64(latency)
+	} else {
+		average += (int64(latency) - average) / latencySmoothing
+	}
+	ctx.averageLatency.Store(average)
+
+	ctx.logger.Debug("heartbeat acknowledged after %s, average latency %s", latency, time.Duration(average))
+	ctx.client.metrics.HeartbeatLatency(ctx.client.id, latency)
+}
+
 func (ctx *StateCtx) WriteNormalClose(pipe io.Writer) error {
 	ctx.SetState(&ClosedState{})
 	return ctx.writeClose(pipe, closecode.Normal)
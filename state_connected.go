@@ -1,11 +1,11 @@
 package gateway
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"strconv"
+	"runtime/debug"
 
-	"github.com/discordpkg/gateway/event"
 	"github.com/discordpkg/gateway/event/opcode"
 )
 
@@ -27,24 +27,84 @@ func (st *ConnectedState) String() string {
 func (st *ConnectedState) Process(payload *Payload, pipe io.Writer) error {
 	switch payload.Op {
 	case opcode.Heartbeat:
-		seqStr := strconv.FormatInt(payload.Seq, 10)
-		if err := st.ctx.Write(pipe, event.Heartbeat, []byte(seqStr)); err != nil {
+		if err := st.ctx.WriteHeartbeat(pipe, payload.Seq); err != nil {
 			st.ctx.SetState(&ClosedState{})
 			return fmt.Errorf("discord requested heartbeat, but was unable to send one. %w", err)
 		}
 	case opcode.HeartbeatACK:
-		st.ctx.heartbeatACK.CompareAndSwap(false, true)
+		st.ctx.heartbeatAcknowledged()
 	case opcode.Dispatch:
-		if st.ctx.client.eventHandler == nil {
-			return nil
-		}
+		st.ctx.dispatch(payload)
+		st.ctx.guildReceived(payload)
+	}
 
-		if _, ok := st.ctx.client.allowlist[payload.EventName]; !ok {
-			return nil
-		}
+	return nil
+}
 
-		st.ctx.client.eventHandler(st.ctx.client.id, payload.EventName, payload.Data)
+// dispatch passes the payload to the event handler, unless the event was not requested
+func (ctx *StateCtx) dispatch(payload *Payload) {
+	if _, ok := ctx.client.allowlist[payload.EventName]; !ok || ctx.client.eventHandler == nil {
+		return
 	}
 
-	return nil
+	handlerCtx := ctx.dispatchCtx
+	if handlerCtx == nil {
+		handlerCtx = context.Background()
+	}
+
+	ctx.handleEvent(handlerCtx, &Event{
+		ShardID:    ctx.client.id,
+		Type:       payload.EventName,
+		Seq:        payload.Seq,
+		ReceivedAt: ctx.receivedAt,
+		Data:       payload.Data,
+	}, ctx.client.recoverPanics)
+}
+
+// handleEvent calls the event handler, within a span when a tracer is configured. Events handled outside the event
+// loop must recover panics, as closing the client would race with the event loop.
+func (ctx *StateCtx) handleEvent(handlerCtx context.Context, evt *Event, recoverPanics bool) {
+	client := ctx.client
+
+	ctx.handlerMu.Lock()
+	defer ctx.handlerMu.Unlock()
+
+	if client.tracer != nil {
+		var end func()
+		handlerCtx, end = client.tracer.StartDispatch(handlerCtx, DispatchInfo{
+			ShardID: client.id,
+			Event:   evt.Type,
+			Seq:     evt.Seq,
+			Size:    len(evt.Data),
+		})
+		defer end()
+	}
+
+	defer ctx.handlePanic(evt, recoverPanics)
+	if err := client.eventHandler(handlerCtx, evt); err != nil {
+		ctx.logger.Error("event handler failed to handle %s event with sequence number %d: %s", evt.Type, evt.Seq, err)
+		client.metrics.HandlerFailed(client.id, evt.Type)
+	}
+}
+
+// handlePanic recovers a panic of the event handler when panic recovery is enabled. Otherwise, the client is closed
+// before the panic continues, such that the heartbeat process stops writing to a connection that is no longer read.
+func (ctx *StateCtx) handlePanic(evt *Event, recoverPanics bool) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	client := ctx.client
+	if !recoverPanics {
+		ctx.SetState(&ClosedState{})
+		panic(recovered)
+	}
+
+	stack := debug.Stack()
+	ctx.logger.Error("event handler panicked on %s event with sequence number %d: %v\n%s", evt.Type, evt.Seq, recovered, stack)
+	client.metrics.HandlerFailed(client.id, evt.Type)
+	if client.panicHandler != nil {
+		client.panicHandler(evt, recovered, stack)
+	}
 }
@@ -3,7 +3,6 @@ package gateway
 import (
 	"errors"
 	"fmt"
-	"github.com/discordpkg/gateway/encoding"
 	"io"
 	"time"
 
@@ -42,20 +41,12 @@ func (st *HelloState) Process(payload *Payload, pipe io.Writer) error {
 		return errors.New(fmt.Sprintf("incorrect opcode: %d", int(payload.Op)))
 	}
 
-	var hello Hello
-	if err := encoding.Unmarshal(payload.Data, &hello); err != nil {
+	if err := st.ctx.startHeartbeat(payload); err != nil {
 		st.ctx.SetState(&ClosedState{})
 		return err
 	}
 
-	st.ctx.logger.Debug("starting heartbeat process")
-	var handler HeartbeatHandler
-	handler, st.ctx.client.heartbeatHandler = st.ctx.client.heartbeatHandler, nil
-	handler.Configure(st.ctx, time.Duration(hello.HeartbeatIntervalMilli)*time.Millisecond)
-	st.ctx.heartbeatACK.Store(true)
-	go handler.Run()
-
-	data, err := encoding.Marshal(st.Identity)
+	data, err := st.ctx.client.codec.Marshal(st.Identity)
 	if err != nil {
 		st.ctx.SetState(&ClosedState{})
 		return fmt.Errorf("unable to marshal identify payload. %w", err)
@@ -69,3 +60,19 @@ func (st *HelloState) Process(payload *Payload, pipe io.Writer) error {
 	st.ctx.SetState(&ReadyState{ctx: st.ctx})
 	return nil
 }
+
+// startHeartbeat configures and runs the heartbeat handler, using the interval of the Hello payload
+func (ctx *StateCtx) startHeartbeat(payload *Payload) error {
+	var hello Hello
+	if err := ctx.client.codec.Unmarshal(payload.Data, &hello); err != nil {
+		return err
+	}
+
+	ctx.logger.Debug("starting heartbeat process")
+	var handler HeartbeatHandler
+	handler, ctx.client.heartbeatHandler = ctx.client.heartbeatHandler, nil
+	handler.Configure(ctx, time.Duration(hello.HeartbeatIntervalMilli)*time.Millisecond)
+	ctx.heartbeatACK.Store(true)
+	go handler.Run()
+	return nil
+}
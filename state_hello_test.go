@@ -3,6 +3,7 @@ package gateway
 import (
 	"bytes"
 	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/encoding/etf"
 	"github.com/discordpkg/gateway/event/opcode"
 	"strings"
 	"testing"
@@ -85,4 +86,37 @@ func TestHelloState(t *testing.T) {
 			t.Error("didn't write identify op code")
 		}
 	})
+
+	t.Run("etf", func(t *testing.T) {
+		client := NewClientMust(t, append(options, WithCodec(etf.Codec{}))...)
+
+		hello, err := etf.Marshal(map[string]interface{}{
+			"op": opcode.Hello,
+			"d":  map[string]interface{}{"heartbeat_interval": 45},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buffer := &bytes.Buffer{}
+		if _, err = client.ProcessNext(bytes.NewReader(hello), buffer); err != nil {
+			t.Fatal(err)
+		}
+
+		var payload *Payload
+		if err = etf.Unmarshal(buffer.Bytes(), &payload); err != nil {
+			t.Fatal("didn't write valid etf to discord")
+		}
+		if payload.Op != opcode.Identify {
+			t.Error("didn't write identify op code")
+		}
+
+		var identify Identify
+		if err = etf.Unmarshal(payload.Data, &identify); err != nil {
+			t.Fatal(err)
+		}
+		if identify.BotToken != "token" {
+			t.Errorf("incorrect token. Got '%s'", identify.BotToken)
+		}
+	})
 }
@@ -3,21 +3,31 @@ package gateway
 import (
 	"errors"
 	"fmt"
-	"github.com/discordpkg/gateway/encoding"
 	"io"
 
+	"github.com/discordpkg/gateway/event"
 	"github.com/discordpkg/gateway/event/opcode"
 )
 
+// Ready is the payload of the Ready event. The guilds are unavailable until they are received through GUILD_CREATE,
+// see WithGuildsReady. Shard holds the shard id and the total number of shards, and is zero when the session was
+// identified without a shard.
 type Ready struct {
-	SessionID        string `json:"session_id"`
-	ResumeGatewayURL string `json:"resume_gateway_url"`
+	V                int                      `json:"v"`
+	User             event.User               `json:"user"`
+	Guilds           []event.UnavailableGuild `json:"guilds"`
+	SessionID        string                   `json:"session_id"`
+	ResumeGatewayURL string                   `json:"resume_gateway_url"`
+	Shard            [2]int                   `json:"shard"`
+	Application      event.Application        `json:"application"`
 }
 
 // ReadyState is responsibile for the Ready phase of the gateway connection. It's responsibilities are:
 //  1. Process incoming Ready event
 //  2. Cache relevant Discord session data
+//  3. Start tracking the guilds that are yet to be received
 //  4. Transition to the ConnectedState
+//  5. Forward the Ready event to the event handler, when requested
 //
 // See the Discord documentation for more information:
 //   - https://discord.com/developers/docs/topics/gateway#ready-event
@@ -36,14 +46,17 @@ func (st *ReadyState) Process(payload *Payload, _ io.Writer) error {
 	}
 
 	var ready Ready
-	if err := encoding.Unmarshal(payload.Data, &ready); err != nil {
+	if err := st.ctx.client.codec.Unmarshal(payload.Data, &ready); err != nil {
 		st.ctx.SetState(&ClosedState{})
 		return err
 	}
 
 	st.ctx.SessionID = ready.SessionID
 	st.ctx.ResumeGatewayURL = ready.ResumeGatewayURL
+	st.ctx.ready.Store(&ready)
 
 	st.ctx.SetState(&ConnectedState{ctx: st.ctx})
+	st.ctx.dispatch(payload)
+	st.ctx.trackGuilds(ready.Guilds)
 	return nil
 }
@@ -2,7 +2,10 @@ package gateway
 
 import (
 	"bytes"
+	"github.com/discordpkg/gateway/encoding"
+	"github.com/discordpkg/gateway/event"
 	"github.com/discordpkg/gateway/event/opcode"
+	"strings"
 	"testing"
 )
 
@@ -77,4 +80,63 @@ func TestReadyState_Process(t *testing.T) {
 			t.Error("forgot to save resume url")
 		}
 	})
+
+	t.Run("guilds", func(t *testing.T) {
+		state := NewReadyState(t, options...)
+
+		payload := &Payload{Op: 0, Data: []byte(`{"v":10,"session_id":"test","resume_gateway_url":"test.com","guilds":[{"id":"1","unavailable":true}]}`)}
+		if err := state.Process(payload, &bytes.Buffer{}); err != nil {
+			t.Fatal(err)
+		}
+
+		pending := state.ctx.client.PendingGuilds()
+		if len(pending) != 1 || pending[0] != "1" {
+			t.Errorf("expected the guilds of the ready payload to be pending. Got %v", pending)
+		}
+	})
+}
+
+func TestReadyState_Forward(t *testing.T) {
+	var handled []event.Type
+	options := append(commonOptions,
+		WithShardInfo(1, 2),
+		WithGuildEvents(event.Ready, event.Resumed),
+		WithEventHandler(func(_ ShardID, evt event.Type, _ encoding.RawMessage) {
+			handled = append(handled, evt)
+		}),
+	)
+	client := NewClientMust(t, options...)
+	client.ctx.SetState(&ReadyState{ctx: client.ctx})
+
+	ready := `{"op":0,"s":1,"t":"READY","d":{"v":10,"session_id":"session","resume_gateway_url":"wss://resume.discord.gg",` +
+		`"user":{"id":"10","username":"bot"},"application":{"id":"20","flags":1},"shard":[1,2],"guilds":[{"id":"30","unavailable":true}]}}`
+	if _, err := client.ProcessNext(strings.NewReader(ready), &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	data := client.Ready()
+	if data == nil {
+		t.Fatal("expected the ready payload to be stored")
+	}
+	if data.User.ID != "10" || data.Application.ID != "20" || data.Shard != [2]int{1, 2} || len(data.Guilds) != 1 {
+		t.Errorf("incorrect ready payload. Got %+v", data)
+	}
+
+	if err := client.Close(&bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	resumed := NewClientMust(t, append(options, WithExistingSession(client))...)
+	if resumed.Ready() != data {
+		t.Error("expected the resumed client to keep the ready payload of the session")
+	}
+
+	hello := `{"op":10,"d":{"heartbeat_interval":45000}}`
+	for _, frame := range []string{hello, `{"op":0,"s":2,"t":"RESUMED","d":{}}`} {
+		if _, err := resumed.ProcessNext(strings.NewReader(frame), &bytes.Buffer{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(handled) != 2 || handled[0] != event.Ready || handled[1] != event.Resumed {
+		t.Errorf("expected ready and resumed to be forwarded. Got %v", handled)
+	}
 }
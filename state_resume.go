@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/discordpkg/gateway/event"
@@ -13,7 +14,10 @@ type Resume struct {
 	SequenceNumber int64  `json:"seq"`
 }
 
-// ResumeState wraps a ConnectedState until a Resumed event is received from Discord...
+// ResumeState wraps a ConnectedState until a Resumed event is received from Discord. On Hello it starts the heartbeat
+// process and sends a Resume command, instead of identifying.
+//
+// See https://discord.com/developers/docs/topics/gateway#resuming
 type ResumeState struct {
 	parentState *ConnectedState
 }
@@ -23,6 +27,10 @@ func (st *ResumeState) String() string {
 }
 
 func (st *ResumeState) Process(payload *Payload, pipe io.Writer) error {
+	if payload.Op == opcode.Hello {
+		return st.resume(payload, pipe)
+	}
+
 	if err := st.parentState.Process(payload, pipe); err != nil {
 		return err
 	}
@@ -34,3 +42,27 @@ func (st *ResumeState) Process(payload *Payload, pipe io.Writer) error {
 
 	return nil
 }
+
+func (st *ResumeState) resume(payload *Payload, pipe io.Writer) error {
+	ctx := st.parentState.ctx
+	if err := ctx.startHeartbeat(payload); err != nil {
+		ctx.SetState(&ClosedState{})
+		return err
+	}
+
+	data, err := ctx.client.codec.Marshal(&Resume{
+		BotToken:       ctx.client.botToken,
+		SessionID:      ctx.SessionID,
+		SequenceNumber: ctx.sequenceNumber.Load(),
+	})
+	if err != nil {
+		ctx.SetState(&ClosedState{})
+		return fmt.Errorf("unable to marshal resume payload. %w", err)
+	}
+
+	if err = ctx.Write(pipe, event.Resume, data); err != nil {
+		ctx.SetState(&ClosedState{})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/discordpkg/gateway/event"
+)
+
+// DispatchInfo describes a dispatched payload, as given to the Tracer.
+type DispatchInfo struct {
+	ShardID ShardID
+	Event   event.Type
+	Seq     int64
+
+	// Size is the number of bytes of the event data
+	Size int
+}
+
+// Tracer starts a span for every dispatch event passed to the event handler, see WithTracer and
+// gatewayutil/otel for OpenTelemetry.
+type Tracer interface {
+	// StartDispatch starts a span for the dispatch event. The returned context holds the span and is passed to the
+	// HandlerV2, and end is called once the handler returned.
+	StartDispatch(ctx context.Context, info DispatchInfo) (spanCtx context.Context, end func())
+}